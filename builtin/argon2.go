@@ -4,15 +4,29 @@
 package builtin
 
 import (
-	"golang.org/x/crypto/argon2"
-
 	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/dbms"
 )
 
-var _ = builtin(Argon2id, "(password :string, salt :string) :string")
+var _ = builtin(Argon2id,
+	"(password :string, salt :string, iterations :number = 1) :string")
 
-func Argon2id(a, b Value) Value {
+func Argon2id(a, b, c Value) Value {
 	password := ToStr(a)
 	salt := ToStr(b)
-	return SuStr(argon2.IDKey([]byte(password), []byte(salt), 1, 64*1024, 1, 32))
+	iterations := ToInt(c)
+	if iterations < 1 {
+		panic("Argon2id: iterations must be at least 1")
+	}
+	return SuStr(dbms.Argon2id(password, salt, iterations))
+}
+
+var _ = builtin(ScramPassHash, "(password :string, iterations :number = 3) :string")
+
+func ScramPassHash(a, b Value) Value {
+	iterations := ToInt(b)
+	if iterations < 1 {
+		panic("ScramPassHash: iterations must be at least 1")
+	}
+	return SuStr(dbms.ScramPassHash(ToStr(a), iterations))
 }
@@ -5,6 +5,7 @@ package builtin
 
 import (
 	"strings"
+	"time"
 
 	. "github.com/apmckinlay/gsuneido/core"
 	"github.com/apmckinlay/gsuneido/db19/meta/schema"
@@ -30,12 +31,54 @@ func Database(th *Thread, args []Value) Value {
 
 var databaseMethods = methods("db")
 
+var _ = staticMethod(db_Analyze, "(table :string) :object")
+
+func db_Analyze(th *Thread, args []Value) Value {
+	if dbms := dbms.Local(th.Dbms(), "admin"); dbms != nil {
+		return dbms.Analyze(ToStr(args[0]))
+	}
+	return th.Dbms().Exec(th, SuObjectOf(SuStr("Database.Analyze"), args[0]))
+}
+
 var _ = staticMethod(db_Auth, "(data :string) :boolean")
 
 func db_Auth(th *Thread, args []Value) Value {
 	return SuBool(th.Dbms().Auth(th, ToStr(args[0])))
 }
 
+var _ = staticMethod(db_AuthUser, "(user :string, password :string) :boolean")
+
+func db_AuthUser(th *Thread, args []Value) Value {
+	return SuBool(dbms.AuthPassword(th, th.Dbms(), ToStr(args[0]), ToStr(args[1])))
+}
+
+var _ = staticMethod(db_Changes,
+	"(tables = #(), since = false, limit :number = 1000, wait :number = 0) :object")
+
+// db_Changes returns captured changes (see db19/changes.go).
+// tables may be a single table name or a list.
+// wait is in milliseconds.
+func db_Changes(th *Thread, args []Value) Value {
+	var tables []string
+	if s, ok := args[0].ToStr(); ok {
+		tables = []string{s}
+	} else {
+		ob := ToContainer(args[0])
+		for i := range ob.ListSize() {
+			tables = append(tables, ToStr(ob.ListGet(i)))
+		}
+	}
+	since := NilDate
+	if args[1] != False {
+		var ok bool
+		if since, ok = AsDate(args[1]); !ok {
+			panic("Database.Changes: since must be a date or false")
+		}
+	}
+	wait := time.Duration(ToInt(args[3])) * time.Millisecond
+	return th.Dbms().Changes(th, tables, since, ToInt(args[2]), wait)
+}
+
 var _ = staticMethod(db_Check, "() :string")
 
 func db_Check(th *Thread, args []Value) Value {
@@ -66,11 +109,19 @@ func db_Cursors(th *Thread, args []Value) Value {
 	return IntVal(th.Dbms().Cursors())
 }
 
-var _ = staticMethod(db_Dump, "(table :string = '', to :string = '', publicKey :string = '') :string")
+var _ = staticMethod(db_Dump, "(table :string = '', to :string = '', publicKey :string = '', since = false) :string")
 
 func db_Dump(th *Thread, args []Value) Value {
-	if dbms, ok := th.Dbms().(*dbms.DbmsLocal); ok {
-		err := dbms.Dump(ToStr(args[0]), ToStr(args[1]), ToStr(args[2]))
+	if dbms := dbms.Local(th.Dbms(), "admin"); dbms != nil {
+		since := int64(0)
+		if args[3] != False {
+			d, ok := args[3].(SuDate)
+			if !ok {
+				panic("Database.Dump: since must be a date")
+			}
+			since = d.UnixMilli()
+		}
+		err := dbms.Dump(ToStr(args[0]), ToStr(args[1]), ToStr(args[2]), since)
 		if err != "" {
 			th.ReturnThrow = true
 			return SuStr(strings.Replace(err, "dump", "Database.Dump", 1))
@@ -78,7 +129,40 @@ func db_Dump(th *Thread, args []Value) Value {
 		return EmptyStr
 	}
 	return th.Dbms().Exec(th,
-		SuObjectOf(SuStr("Database.Dump"), args[0], args[1], args[2]))
+		SuObjectOf(SuStr("Database.Dump"), args[0], args[1], args[2], args[3]))
+}
+
+var _ = staticMethod(db_Backup, "(to :string = '', publicKey :string = '', rate :number = 0) :string")
+
+func db_Backup(th *Thread, args []Value) Value {
+	if dbms := dbms.Local(th.Dbms(), "admin"); dbms != nil {
+		err := dbms.Backup(ToStr(args[0]), ToStr(args[1]), ToInt(args[2]))
+		if err != "" {
+			th.ReturnThrow = true
+			return SuStr("Database.Backup: " + err)
+		}
+		return EmptyStr
+	}
+	return th.Dbms().Exec(th,
+		SuObjectOf(SuStr("Database.Backup"), args[0], args[1], args[2]))
+}
+
+var _ = staticMethod(db_BackupStatus, "() :object")
+
+func db_BackupStatus(th *Thread, args []Value) Value {
+	if dbms := dbms.Local(th.Dbms(), "admin"); dbms != nil {
+		return dbms.BackupStatus()
+	}
+	return th.Dbms().Exec(th, SuObjectOf(SuStr("Database.BackupStatus")))
+}
+
+var _ = staticMethod(db_BackupCancel, "() :boolean")
+
+func db_BackupCancel(th *Thread, args []Value) Value {
+	if dbms := dbms.Local(th.Dbms(), "admin"); dbms != nil {
+		return SuBool(dbms.BackupCancel())
+	}
+	return th.Dbms().Exec(th, SuObjectOf(SuStr("Database.BackupCancel")))
 }
 
 var _ = staticMethod(db_Final, "() :number")
@@ -99,14 +183,20 @@ func db_Kill(th *Thread, args []Value) Value {
 	return IntVal(th.Dbms().Kill(ToStr(args[0])))
 }
 
-var _ = staticMethod(db_Load, "(table :string, from :string = '', privateKey :string = '', passphrase :string = '') :number")
+var _ = staticMethod(db_Load, "(table :string, from :string = '', privateKey :string = '', passphrase :string = '', incremental :object = #()) :number")
 
 func db_Load(th *Thread, args []Value) Value {
-	if dbms, ok := th.Dbms().(*dbms.DbmsLocal); ok {
-		return IntVal(dbms.Load(ToStr(args[0]), ToStr(args[1]), ToStr(args[2]), ToStr(args[3])))
+	if dbms := dbms.Local(th.Dbms(), "admin"); dbms != nil {
+		ob := ToContainer(args[4])
+		incremental := make([]string, ob.ListSize())
+		for i := range incremental {
+			incremental[i] = ToStr(ob.ListGet(i))
+		}
+		return IntVal(dbms.Load(ToStr(args[0]), ToStr(args[1]), ToStr(args[2]),
+			ToStr(args[3]), incremental))
 	}
-	return th.Dbms().Exec(th,
-		SuObjectOf(SuStr("Database.Load"), args[0], args[1], args[2], args[3]))
+	return th.Dbms().Exec(th, SuObjectOf(SuStr("Database.Load"),
+		args[0], args[1], args[2], args[3], args[4]))
 }
 
 var _ = staticMethod(db_Nonce, "() :string")
@@ -115,6 +205,12 @@ func db_Nonce(th *Thread, args []Value) Value {
 	return SuStr(th.Dbms().Nonce(th))
 }
 
+var _ = staticMethod(db_Salt, "(user :string) :string")
+
+func db_Salt(th *Thread, args []Value) Value {
+	return SuStr(th.Dbms().Salt(th, ToStr(args[0])))
+}
+
 var _ = staticMethod(db_Schema, "(table :string) :string")
 
 func db_Schema(th *Thread, args []Value) Value {
@@ -148,7 +244,7 @@ func db_Transactions(th *Thread, args []Value) Value {
 var _ = staticMethod(db_CorruptedQ, "() :boolean")
 
 func db_CorruptedQ(th *Thread, args []Value) Value {
-	if dbms, ok := th.Dbms().(*dbms.DbmsLocal); ok {
+	if dbms := dbms.Local(th.Dbms(), ""); dbms != nil {
 		return SuBool(dbms.Corrupted())
 	}
 	return th.Dbms().Exec(th, SuObjectOf(SuStr("Database.Corrupted?")))
@@ -164,7 +260,7 @@ func db_Top10(th *Thread, args []Value) Value {
 	defer tran.Complete()
 
 	sk := ss.New[string](128)
-	q := tran.Query(table, nil)
+	q := tran.Query(table, nil, nil)
 	hdr := q.Header()
 	for row, _ := q.Get(th, Next); row != nil; row, _ = q.Get(th, Next) {
 		sk.Add(row.GetRawVal(hdr, column, nil, nil))
@@ -195,7 +291,7 @@ func db_Distinct(th *Thread, args []Value) Value {
 	for i := range cols {
 		sketches[i] = hll.New()
 	}
-	q := t.Query(table, nil)
+	q := t.Query(table, nil, nil)
 	for row, _ := q.Get(th, Next); row != nil; row, _ = q.Get(th, Next) {
 		for i, col := range cols {
 			sketches[i].Add(row.GetRawVal(hdr, col, nil, nil))
@@ -20,7 +20,8 @@ type suDateGlobal struct {
 func init() {
 	ps := params(`(string=nil, pattern=nil,
 		year=nil, month=nil, day=nil,
-		hour=nil, minute=nil, second=nil, millisecond=nil) :false|date`)
+		hour=nil, minute=nil, second=nil, millisecond=nil,
+		zone=false) :false|date`)
 	Global.Builtin("Date", &suDateGlobal{SuBuiltin{Fn: Date,
 		BuiltinParams: BuiltinParams{ParamSpec: ps}}})
 }
@@ -32,6 +33,7 @@ func Date(_ *Thread, args []Value) Value {
 		panic("usage: Date() or Date(string [, pattern]) or " +
 			"Date(year:, month:, day:, hour:, minute:, second:)")
 	}
+	loc := toZone(args[9])
 	if args[0] != nil {
 		if d, ok := args[0].(SuDate); ok {
 			return d
@@ -44,9 +46,9 @@ func Date(_ *Thread, args []Value) Value {
 		if strings.HasPrefix(s, "#") || tsPat.Matches(s) {
 			d = DateFromLiteral(s)
 		} else if args[1] == nil {
-			d = ParseDate(s, "yMd")
+			d = ParseDateIn(s, "yMd", loc)
 		} else {
-			d = ParseDate(s, AsStr(args[1]))
+			d = ParseDateIn(s, AsStr(args[1]), loc)
 		}
 		if d == NilDate {
 			return False
@@ -55,6 +57,9 @@ func Date(_ *Thread, args []Value) Value {
 	} else if hasFields(args) {
 		return named(args)
 	}
+	if args[9] != False {
+		return FromGoTime(time.Now().In(loc))
+	}
 	return Now()
 }
 
@@ -65,6 +70,14 @@ func toDate(v Value) SuDate {
 	return v.(SuTimestamp).SuDate
 }
 
+// toZone converts a zone argument to a location, false means local
+func toZone(v Value) *time.Location {
+	if v == False {
+		return time.Local
+	}
+	return LoadZone(ToStr(v))
+}
+
 func hasFields(args []Value) bool {
 	for i := 2; i <= 8; i++ {
 		if args[i] != nil {
@@ -171,10 +184,10 @@ func date_MinusSeconds(this Value, val Value) Value {
 	panic("date.MinusSeconds requires date")
 }
 
-var _ = method(date_FormatEn, "(format :string) :string")
+var _ = method(date_FormatEn, "(format :string, zone=false) :string")
 
-func date_FormatEn(this, arg Value) Value {
-	return SuStr(toDate(this).Format(ToStr(arg)))
+func date_FormatEn(this, arg, zone Value) Value {
+	return SuStr(toDate(this).FormatIn(ToStr(arg), toZone(zone)))
 }
 
 var _ = method(date_GetLocalGMTBias, "() :number")
@@ -185,12 +198,19 @@ func date_GetLocalGMTBias(this Value) Value {
 }
 
 var _ = method(date_Plus, "(years=0, months=0, days=0, "+
-	"hours=0, minutes=0, seconds=0, milliseconds=0) :date")
+	"hours=0, minutes=0, seconds=0, milliseconds=0, zone=false) :date")
 
 func date_Plus(th *Thread, this Value, args []Value) Value {
-	d := toDate(this).Plus(ToInt(args[0]), ToInt(args[1]),
-		ToInt(args[2]), ToInt(args[3]), ToInt(args[4]),
-		ToInt(args[5]), ToInt(args[6]))
+	var d SuDate
+	if args[7] == False {
+		d = toDate(this).Plus(ToInt(args[0]), ToInt(args[1]),
+			ToInt(args[2]), ToInt(args[3]), ToInt(args[4]),
+			ToInt(args[5]), ToInt(args[6]))
+	} else {
+		d = toDate(this).PlusIn(toZone(args[7]), ToInt(args[0]),
+			ToInt(args[1]), ToInt(args[2]), ToInt(args[3]), ToInt(args[4]),
+			ToInt(args[5]), ToInt(args[6]))
+	}
 	if ts, ok := this.(SuTimestamp); ok {
 		ts.SuDate = d
 		return ts
@@ -198,6 +218,12 @@ func date_Plus(th *Thread, this Value, args []Value) Value {
 	return d
 }
 
+var _ = method(date_ToZone, "(zone :string, from=false) :date")
+
+func date_ToZone(this, zone, from Value) Value {
+	return toDate(this).ToZone(toZone(from), toZone(zone))
+}
+
 var _ = method(date_WeekDay, "(firstDay='Sun') :number")
 
 func date_WeekDay(this, arg Value) Value {
@@ -0,0 +1,227 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package builtin
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	. "github.com/apmckinlay/gsuneido/core"
+)
+
+// Database.Export and Database.Import convert query results
+// to and from portable text formats (see jsonval.go for the value mapping)
+//
+//   - jsonl is one JSON object per record, one per line (lossless)
+//   - csv is a header line of the column names, then one line per record
+//
+// The file is read or written where the code is running,
+// i.e. on the client if client-server.
+
+var _ = staticMethod(db_Export, "(query :string, to :string, format :string = '') :number")
+
+func db_Export(th *Thread, args []Value) Value {
+	query := ToStr(args[0])
+	to := ToStr(args[1])
+	format := exportFormat(to, ToStr(args[2]), "Database.Export")
+	f, err := os.Create(to)
+	if err != nil {
+		panic("Database.Export: " + err.Error())
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	tran := th.Dbms().Transaction(false)
+	defer tran.Complete()
+	q := tran.Query(query, nil, th.Sviews())
+	hdr := q.Header()
+	cols := exportColumns(hdr)
+	var cw *csv.Writer
+	var fields []string
+	if format == "csv" {
+		cw = csv.NewWriter(w)
+		cw.Write(cols)
+		fields = make([]string, len(cols))
+	}
+	var sb strings.Builder
+	n := 0
+	for row, _ := q.Get(th, Next); row != nil; row, _ = q.Get(th, Next) {
+		if format == "csv" {
+			for i, col := range cols {
+				fields[i] = csvEncode(row.GetVal(hdr, col, th, nil))
+			}
+			cw.Write(fields)
+		} else {
+			sb.Reset()
+			sb.WriteByte('{')
+			for i, col := range cols {
+				if i > 0 {
+					sb.WriteByte(',')
+				}
+				jsonQuote(&sb, col)
+				sb.WriteByte(':')
+				jsonEncode(&sb, row.GetVal(hdr, col, th, nil))
+			}
+			sb.WriteString("}\n")
+			w.WriteString(sb.String())
+		}
+		n++
+	}
+	if cw != nil {
+		cw.Flush()
+		err = cw.Error()
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		panic("Database.Export: " + err.Error())
+	}
+	return IntVal(n)
+}
+
+// exportColumns returns the columns of the query,
+// excluding rules (capitalized) and derived _lower! columns
+func exportColumns(hdr *Header) []string {
+	cols := make([]string, 0, len(hdr.Columns))
+	for _, col := range hdr.Columns {
+		if hdr.HasField(col) && !strings.HasSuffix(col, "_lower!") {
+			cols = append(cols, col)
+		}
+	}
+	return cols
+}
+
+// importBatch is the number of records per transaction,
+// to stay well below the limit on writes per transaction
+const importBatch = 1000
+
+var _ = staticMethod(db_Import, "(table :string, from :string, format :string = '') :number")
+
+// db_Import outputs the records from the file to an existing table.
+// It commits every importBatch records, so it is not atomic.
+func db_Import(th *Thread, args []Value) Value {
+	table := ToStr(args[0])
+	from := ToStr(args[1])
+	format := exportFormat(from, ToStr(args[2]), "Database.Import")
+	f, err := os.Open(from)
+	if err != nil {
+		panic("Database.Import: " + err.Error())
+	}
+	defer f.Close()
+	var next func() *SuObject
+	if format == "csv" {
+		next = csvReader(f)
+	} else {
+		next = jsonlReader(f)
+	}
+	n := 0
+	for {
+		tran := th.Dbms().Transaction(true)
+		q := tran.Query(table, nil, th.Sviews())
+		hdr := q.Header()
+		nb := 0
+		func() {
+			defer func() {
+				if e := recover(); e != nil {
+					tran.Abort()
+					panic(importError(e, n+nb+1))
+				}
+			}()
+			for ; nb < importBatch; nb++ {
+				ob := next()
+				if ob == nil {
+					break
+				}
+				q.Output(th, ob.ToRecord(th, hdr))
+			}
+		}()
+		if conflict := tran.Complete(); conflict != "" {
+			panic("Database.Import: " + conflict)
+		}
+		n += nb
+		if nb < importBatch {
+			return IntVal(n)
+		}
+	}
+}
+
+func importError(e any, recnum int) string {
+	s := fmt.Sprint(e)
+	if v, ok := e.(Value); ok {
+		s = ToStrOrString(v)
+	}
+	s = strings.TrimPrefix(s, "Database.Import: ")
+	return fmt.Sprint("Database.Import: record ", recnum, ": ", s)
+}
+
+func exportFormat(file, format, fn string) string {
+	if format == "" {
+		if strings.HasSuffix(strings.ToLower(file), ".csv") {
+			return "csv"
+		}
+		return "jsonl"
+	}
+	if format != "csv" && format != "jsonl" {
+		panic(fn + ": format must be \"csv\" or \"jsonl\"")
+	}
+	return format
+}
+
+func jsonlReader(f io.Reader) func() *SuObject {
+	dec := json.NewDecoder(bufio.NewReader(f))
+	dec.UseNumber()
+	return func() *SuObject {
+		var x any
+		if err := dec.Decode(&x); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			panic("Database.Import: " + err.Error())
+		}
+		m, ok := x.(map[string]any)
+		if !ok {
+			panic("Database.Import: expected a JSON object")
+		}
+		ob := &SuObject{}
+		for k, v := range m {
+			ob.Set(SuStr(k), jsonDecode(v))
+		}
+		return ob
+	}
+}
+
+func csvReader(f io.Reader) func() *SuObject {
+	r := csv.NewReader(bufio.NewReader(f))
+	cols, err := r.Read()
+	if err != nil {
+		panic("Database.Import: " + err.Error())
+	}
+	cols = append([]string(nil), cols...)
+	r.ReuseRecord = true
+	return func() *SuObject {
+		fields, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			panic("Database.Import: " + err.Error())
+		}
+		ob := &SuObject{}
+		for i, fld := range fields {
+			if fld != "" {
+				ob.Set(SuStr(cols[i]), csvDecode(fld))
+			}
+		}
+		return ob
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package builtin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	"github.com/apmckinlay/gsuneido/dbms"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestJsonValue(t *testing.T) {
+	test := func(v Value, expected string) {
+		t.Helper()
+		var sb strings.Builder
+		jsonEncode(&sb, v)
+		assert.T(t).This(sb.String()).Is(expected)
+		r := jsonlReader(strings.NewReader(`{"x":` + expected + `}`))()
+		assert.T(t).This(r.Get(nil, SuStr("x"))).Is(v)
+	}
+	test(SuStr("hello"), `"hello"`)
+	test(SuStr("a\"b\\c\nd<>&\x01"), `"a\"b\\c\nd<>&\u0001"`)
+	test(SuStr("\xff\xfe"), `{"$bytes":"//4="}`)
+	test(IntVal(123), `123`)
+	test(num(".5"), `0.5`)
+	test(num("-.5"), `-0.5`)
+	test(num("1e30"), `1e30`)
+	test(num("inf"), `{"$number":"inf"}`)
+	test(True, `true`)
+	test(False, `false`)
+	test(NewDate(2025, 12, 31, 23, 59, 58, 123),
+		`{"$date":"2025-12-31T23:59:58.123"}`)
+	test(SuObjectOf(IntVal(1), SuStr("a")), `[1,"a"]`)
+	ob := SuObjectOf(IntVal(1))
+	ob.Set(SuStr("a"), SuObjectOf())
+	test(ob, `{"$list":[1],"a":[]}`)
+
+	csv := func(v Value, expected string) {
+		t.Helper()
+		assert.T(t).This(csvEncode(v)).Is(expected)
+		assert.T(t).This(csvDecode(expected)).Is(v)
+	}
+	csv(SuStr("hello"), "hello")
+	csv(SuStr("007"), "007")
+	csv(IntVal(-12), "-12")
+	csv(num(".25"), "0.25")
+	csv(num("-inf"), "-inf")
+	csv(True, "true")
+	csv(NewDate(2025, 1, 2, 3, 4, 5, 6), "2025-01-02T03:04:05.006")
+	csv(SuObjectOf(IntVal(1), SuStr("a")), `[1,"a"]`)
+}
+
+func num(s string) Value {
+	return NumFromString(s)
+}
+
+func TestDatabase_ExportImport(t *testing.T) {
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	defer db.Close()
+	db19.MakeSuTran = func(ut *db19.UpdateTran) *SuTran {
+		return NewSuTran(nil, true)
+	}
+	qry.DoAdmin(db, "create src (a, b, c, d) key(a)", nil)
+	ut := db.NewUpdateTran()
+	qry.DoAction(nil, ut, "insert { a: 1, b: 'x,y', c: #20250102.0304, "+
+		"d: #(1, 'two', n: true) } into src")
+	qry.DoAction(nil, ut, "insert { a: 2, b: 'line1\nline2', c: .5 } into src")
+	qry.DoAction(nil, ut, "insert { a: 3, b: 'ignored' } into src")
+	ut.Commit()
+	th := &Thread{}
+	th.SetDbms(dbms.NewDbmsLocal(db))
+	dir := t.TempDir()
+
+	for _, format := range []string{"jsonl", "csv"} {
+		file := filepath.Join(dir, "src."+format)
+		n := db_Export(th, []Value{SuStr("src where a < 3 sort a"),
+			SuStr(file), EmptyStr})
+		assert.T(t).This(n).Is(IntVal(2))
+		if format == "jsonl" {
+			buf, _ := os.ReadFile(file)
+			assert.T(t).This(strings.Split(string(buf), "\n")[1]).
+				Is(`{"a":2,"b":"line1\nline2","c":0.5,"d":""}`)
+		}
+		dst := "dst_" + format
+		qry.DoAdmin(db, "create "+dst+" (a, b, c, d) key(a)", nil)
+		n = db_Import(th, []Value{SuStr(dst), SuStr(file), SuStr(format)})
+		assert.T(t).This(n).Is(IntVal(2))
+		tran := th.Dbms().Transaction(false)
+		q1 := tran.Query("src where a < 3 sort a", nil, nil)
+		q2 := tran.Query(dst+" sort a", nil, nil)
+		h1, h2 := q1.Header(), q2.Header()
+		for range 2 {
+			r1, _ := q1.Get(th, Next)
+			r2, _ := q2.Get(th, Next)
+			for _, col := range []string{"a", "b", "c", "d"} {
+				assert.T(t).This(r2.GetVal(h2, col, th, nil)).
+					Is(r1.GetVal(h1, col, th, nil))
+			}
+		}
+		tran.Complete()
+	}
+	assert.T(t).This(func() {
+		db_Import(th, []Value{SuStr("dst_csv"),
+			SuStr(filepath.Join(dir, "src.csv")), EmptyStr})
+	}).Panics("Database.Import: record 1: ")
+}
@@ -0,0 +1,560 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package builtin
+
+import (
+	"encoding/base64"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/core/types"
+	"github.com/apmckinlay/gsuneido/util/ascii"
+)
+
+// JsonEncode and JsonDecode convert between Suneido values and
+// standard JSON for interchange (e.g. REST APIs).
+// They are not lossless, unlike the lossless mode used by Export.
+// The stdlib Json class can delegate to them.
+//
+//   - string => string (invalid UTF-8 bytes are treated as Latin-1)
+//   - number => number, without going through float
+//   - true, false => true, false
+//   - date => string e.g. "2006-01-02T15:04:05.000"
+//   - object with no named members => array
+//   - other objects and records => object,
+//     list members have their index as the name
+//
+// Other values (e.g. functions, classes, instances, infinite numbers)
+// are handled according to the other: option.
+//
+// The lossless mode (see jsonval.go) differs:
+//
+//   - string => {"$bytes": "<base64>"} if not valid UTF-8
+//   - infinite number => {"$number": "inf" | "-inf"}
+//   - date => {"$date": "2006-01-02T15:04:05.000"}
+//   - object or record with no named members => array
+//   - other objects and records => object,
+//     with any list members as an array in "$list"
+//   - other values throw an error
+
+var _ = builtin(JsonEncode,
+	"(value, pretty = false, other = 'throw') :string")
+
+func JsonEncode(th *Thread, args []Value) Value {
+	var sb strings.Builder
+	je := jsonEncoder{th: th, sb: &sb}
+	if args[1] == True {
+		je.indent = "  "
+	} else if s, ok := args[1].ToStr(); ok {
+		je.indent = s
+	}
+	switch ToStr(args[2]) {
+	case "throw":
+		je.other = jsonThrow
+	case "null":
+		je.other = jsonNull
+	case "skip":
+		je.other = jsonSkip
+	case "string":
+		je.other = jsonString
+	default:
+		panic("JsonEncode: other must be 'throw', 'null', 'skip', or 'string'")
+	}
+	if je.skip(args[0]) {
+		return SuStr("null")
+	}
+	je.encode(args[0], 0)
+	return SuStr(sb.String())
+}
+
+type jsonOther byte
+
+const (
+	jsonThrow jsonOther = iota
+	jsonNull
+	jsonSkip
+	jsonString
+)
+
+const jsonMaxDepth = 1000
+
+type jsonEncoder struct {
+	th     *Thread
+	sb     *strings.Builder
+	indent string
+	other  jsonOther
+	// lossless is used by Export
+	lossless bool
+}
+
+func (je *jsonEncoder) encode(v Value, depth int) {
+	sb := je.sb
+	switch v.Type() {
+	case types.String:
+		s := ToStr(v)
+		if utf8.ValidString(s) {
+			jsonQuote(sb, s)
+		} else if je.lossless {
+			jsonTagged(sb, "$bytes",
+				base64.StdEncoding.EncodeToString([]byte(s)))
+		} else {
+			jsonQuoteLatin1(sb, s)
+		}
+	case types.Number:
+		dn := ToDnum(v)
+		if !dn.IsInf() {
+			sb.WriteString(jsonNumber(dn))
+		} else if je.lossless {
+			jsonTagged(sb, "$number", dn.String())
+		} else {
+			je.nonJson(v)
+		}
+	case types.Boolean:
+		if v == True {
+			sb.WriteString("true")
+		} else {
+			sb.WriteString("false")
+		}
+	case types.Date:
+		if je.lossless {
+			jsonTagged(sb, "$date", formatDate(v.(SuDate)))
+		} else {
+			jsonQuote(sb, formatDate(v.(SuDate)))
+		}
+	case types.Object, types.Record:
+		if depth >= jsonMaxDepth {
+			panic("JSON nesting too deep")
+		}
+		ob := ToContainer(v)
+		if ob.NamedSize() == 0 && (v.Type() == types.Object || je.lossless) {
+			je.encodeList(ob, depth)
+		} else {
+			je.encodeObject(ob, depth)
+		}
+	default:
+		je.nonJson(v)
+	}
+}
+
+// skip returns whether v should be omitted because of other: 'skip'
+func (je *jsonEncoder) skip(v Value) bool {
+	if je.other != jsonSkip {
+		return false
+	}
+	switch v.Type() {
+	case types.String, types.Boolean, types.Date, types.Object, types.Record:
+		return false
+	case types.Number:
+		return ToDnum(v).IsInf()
+	}
+	return true
+}
+
+func (je *jsonEncoder) nonJson(v Value) {
+	switch je.other {
+	case jsonNull:
+		je.sb.WriteString("null")
+	case jsonString:
+		jsonQuote(je.sb, Display(je.th, v))
+	default:
+		panic("can't convert " + ErrType(v) + " to JSON")
+	}
+}
+
+func (je *jsonEncoder) encodeList(ob Container, depth int) {
+	je.sb.WriteByte('[')
+	sep := false
+	for i := range ob.ListSize() {
+		sep = je.member(sep, "", ob.ListGet(i), depth)
+	}
+	je.end(sep, ']', depth)
+}
+
+func (je *jsonEncoder) encodeObject(ob Container, depth int) {
+	je.sb.WriteByte('{')
+	sep := false
+	if je.lossless {
+		if ob.ListSize() > 0 {
+			je.newline(depth + 1)
+			je.sb.WriteString(`"$list":`)
+			je.encodeList(ob, depth+1)
+			sep = true
+		}
+	} else {
+		for i := range ob.ListSize() {
+			sep = je.member(sep, strconv.Itoa(i), ob.ListGet(i), depth)
+		}
+	}
+	// sort the names so the output is consistent
+	type member struct {
+		name string
+		val  Value
+	}
+	mems := make([]member, 0, ob.NamedSize())
+	iter := ob.Iter2(false, true)
+	for k, x := iter(); k != nil; k, x = iter() {
+		mems = append(mems, member{name: ToStrOrString(k), val: x})
+	}
+	slices.SortFunc(mems, func(x, y member) int {
+		return strings.Compare(x.name, y.name)
+	})
+	for _, m := range mems {
+		sep = je.member(sep, m.name, m.val, depth)
+	}
+	je.end(sep, '}', depth)
+}
+
+// member writes a list element (if name is "") or a named member.
+// It returns whether a separator is required before the next member.
+func (je *jsonEncoder) member(sep bool, name string, v Value, depth int) bool {
+	if je.skip(v) {
+		return sep
+	}
+	sb := je.sb
+	if sep {
+		sb.WriteByte(',')
+	}
+	je.newline(depth + 1)
+	if name != "" {
+		jsonQuote(sb, name)
+		sb.WriteByte(':')
+		if je.indent != "" {
+			sb.WriteByte(' ')
+		}
+	}
+	je.encode(v, depth+1)
+	return true
+}
+
+func (je *jsonEncoder) end(sep bool, c byte, depth int) {
+	if sep {
+		je.newline(depth)
+	}
+	je.sb.WriteByte(c)
+}
+
+func (je *jsonEncoder) newline(depth int) {
+	if je.indent == "" {
+		return
+	}
+	je.sb.WriteByte('\n')
+	for range depth {
+		je.sb.WriteString(je.indent)
+	}
+}
+
+// jsonQuoteLatin1 is used for strings that are not valid UTF-8.
+// It treats each byte as a Latin-1 character.
+func jsonQuoteLatin1(sb *strings.Builder, s string) {
+	var b strings.Builder
+	b.Grow(len(s) + len(s)/2)
+	for i := 0; i < len(s); i++ {
+		b.WriteRune(rune(s[i]))
+	}
+	jsonQuote(sb, b.String())
+}
+
+//-------------------------------------------------------------------
+
+var _ = builtin(JsonDecode,
+	"(string :string, null = '', dates = false) :unknown")
+
+func JsonDecode(s, null, dates Value) Value {
+	jd := jsonDecoder{s: ToStr(s), null: null, dates: ToBool(dates)}
+	jd.skipSpace()
+	v := jd.value(0)
+	jd.skipSpace()
+	if jd.i < len(jd.s) {
+		jd.error()
+	}
+	return v
+}
+
+type jsonDecoder struct {
+	null  Value
+	s     string
+	i     int
+	dates bool
+}
+
+func (jd *jsonDecoder) value(depth int) Value {
+	if jd.i >= len(jd.s) {
+		panic("Invalid Json format: unexpected end of input")
+	}
+	switch c := jd.s[jd.i]; {
+	case c == '{':
+		return jd.object(depth)
+	case c == '[':
+		return jd.array(depth)
+	case c == '"':
+		s := jd.string()
+		if jd.dates {
+			if d, ok := jsonDate(s); ok {
+				return d
+			}
+		}
+		return SuStr(s)
+	case c == '-' || ('0' <= c && c <= '9'):
+		return jd.number()
+	case jd.match("true"):
+		return True
+	case jd.match("false"):
+		return False
+	case jd.match("null"):
+		return jd.null
+	}
+	jd.error()
+	return nil
+}
+
+func (jd *jsonDecoder) object(depth int) Value {
+	if depth >= jsonMaxDepth {
+		panic("Invalid Json format: nesting too deep")
+	}
+	ob := &SuObject{}
+	jd.i++ // skip {
+	jd.skipSpace()
+	if jd.peek() == '}' {
+		jd.i++
+		return ob
+	}
+	for {
+		if jd.peek() != '"' {
+			jd.error()
+		}
+		name := jd.string()
+		jd.skipSpace()
+		jd.expect(':')
+		jd.skipSpace()
+		ob.Set(SuStr(name), jd.value(depth+1))
+		jd.skipSpace()
+		if jd.peek() == '}' {
+			jd.i++
+			return ob
+		}
+		jd.expect(',')
+		jd.skipSpace()
+	}
+}
+
+func (jd *jsonDecoder) array(depth int) Value {
+	if depth >= jsonMaxDepth {
+		panic("Invalid Json format: nesting too deep")
+	}
+	ob := &SuObject{}
+	jd.i++ // skip [
+	jd.skipSpace()
+	if jd.peek() == ']' {
+		jd.i++
+		return ob
+	}
+	for {
+		ob.Add(jd.value(depth + 1))
+		jd.skipSpace()
+		if jd.peek() == ']' {
+			jd.i++
+			return ob
+		}
+		jd.expect(',')
+		jd.skipSpace()
+	}
+}
+
+// number scans a JSON number and converts it directly from the digits
+// so there is no loss of precision from going through float
+func (jd *jsonDecoder) number() Value {
+	s := jd.s
+	start := jd.i
+	i := start
+	if s[i] == '-' {
+		i++
+	}
+	switch {
+	case i < len(s) && s[i] == '0':
+		i++
+	case i < len(s) && '1' <= s[i] && s[i] <= '9':
+		i = jd.digits(i)
+	default:
+		jd.i = i
+		jd.error()
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		j := jd.digits(i)
+		if j == i {
+			jd.i = i
+			jd.error()
+		}
+		i = j
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		j := jd.digits(i)
+		if j == i {
+			jd.i = i
+			jd.error()
+		}
+		i = j
+	}
+	jd.i = i
+	return NumFromString(s[start:i])
+}
+
+func (jd *jsonDecoder) digits(i int) int {
+	for i < len(jd.s) && '0' <= jd.s[i] && jd.s[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// string scans a JSON string, the fast path is when there are no escapes
+func (jd *jsonDecoder) string() string {
+	s := jd.s
+	jd.i++ // skip opening quote
+	start := jd.i
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			jd.i = i + 1
+			return s[start:i]
+		case c == '\\':
+			return jd.unescape(start, i)
+		case c < 0x20:
+			jd.i = i
+			jd.error()
+		}
+	}
+	panic("Invalid Json format: unterminated string")
+}
+
+func (jd *jsonDecoder) unescape(start, i int) string {
+	s := jd.s
+	var sb strings.Builder
+	sb.WriteString(s[start:i])
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '"':
+			jd.i = i + 1
+			return sb.String()
+		case c < 0x20:
+			jd.i = i
+			jd.error()
+		case c != '\\':
+			sb.WriteByte(c)
+			i++
+			continue
+		}
+		i++ // skip backslash
+		if i >= len(s) {
+			break
+		}
+		switch s[i] {
+		case '"', '\\', '/':
+			sb.WriteByte(s[i])
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'u':
+			r := jd.hex4(i + 1)
+			i += 4
+			if utf16.IsSurrogate(r) {
+				if i+6 < len(s) && s[i+1] == '\\' && s[i+2] == 'u' {
+					r = utf16.DecodeRune(r, jd.hex4(i+3))
+					i += 6
+				} else {
+					r = utf8.RuneError
+				}
+			}
+			sb.WriteRune(r)
+		default:
+			jd.i = i
+			jd.error()
+		}
+		i++
+	}
+	panic("Invalid Json format: unterminated string")
+}
+
+func (jd *jsonDecoder) hex4(i int) rune {
+	if i+4 > len(jd.s) {
+		panic("Invalid Json format: unterminated string")
+	}
+	n, err := strconv.ParseUint(jd.s[i:i+4], 16, 16)
+	if err != nil {
+		jd.i = i
+		jd.error()
+	}
+	return rune(n)
+}
+
+func (jd *jsonDecoder) skipSpace() {
+	for jd.i < len(jd.s) {
+		switch jd.s[jd.i] {
+		case ' ', '\t', '\n', '\r':
+			jd.i++
+		default:
+			return
+		}
+	}
+}
+
+func (jd *jsonDecoder) peek() byte {
+	if jd.i >= len(jd.s) {
+		panic("Invalid Json format: unexpected end of input")
+	}
+	return jd.s[jd.i]
+}
+
+func (jd *jsonDecoder) expect(c byte) {
+	if jd.peek() != c {
+		jd.error()
+	}
+	jd.i++
+}
+
+func (jd *jsonDecoder) match(word string) bool {
+	if strings.HasPrefix(jd.s[jd.i:], word) {
+		jd.i += len(word)
+		return true
+	}
+	return false
+}
+
+func (jd *jsonDecoder) error() {
+	panic("Invalid Json format at position " + strconv.Itoa(jd.i))
+}
+
+// jsonDate handles date literals (e.g. from the stdlib Json.Encode)
+// and ISO 8601 date times e.g. 2006-01-02T15:04:05.000
+// If the ISO date time has a zone offset it is converted to local time.
+func jsonDate(s string) (Value, bool) {
+	if len(s) >= 9 && s[0] == '#' && ascii.IsDigit(s[1]) {
+		if d := DateFromLiteral(s); d != NilDate {
+			return d, true
+		}
+	} else if len(s) >= 16 && s[4] == '-' && s[10] == 'T' {
+		if d, ok := parseDate(s); ok {
+			return d, true
+		}
+		if d := ParseDate(s, "yMd"); d != NilDate {
+			return d, true
+		}
+	}
+	return nil, false
+}
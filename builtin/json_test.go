@@ -0,0 +1,129 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package builtin
+
+import (
+	"testing"
+
+	"github.com/apmckinlay/gsuneido/compile"
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/util/assert"
+	"github.com/apmckinlay/gsuneido/util/dnum"
+)
+
+func TestJsonEncode(t *testing.T) {
+	th := &Thread{}
+	encode := func(src string, pretty Value, other string) string {
+		return ToStr(JsonEncode(th,
+			[]Value{compile.Constant(src), pretty, SuStr(other)}))
+	}
+	test := func(src string, expected string) {
+		t.Helper()
+		assert.T(t).This(encode(src, False, "throw")).Is(expected)
+	}
+	test("123", "123")
+	test("-.5", "-0.5")
+	test("1234567890.123456", "1234567890.123456")
+	test("true", "true")
+	test(`"a\"b\\c\n\x01"`, `"a\"b\\c\n\u0001"`)
+	test(`"\xe9"`, `"`+"é"+`"`)
+	test("#20240115.123456789", `"2024-01-15T12:34:56.789"`)
+	test("#()", "[]")
+	test("#{}", "{}")
+	test(`#(1, "a", (2))`, `[1,"a",[2]]`)
+	test("#(b: 2, a: 1)", `{"a":1,"b":2}`)
+	test("#{x: #(y: false)}", `{"x":{"y":false}}`)
+	test("#(1, a: 2)", `{"0":1,"a":2}`)
+	test("[]", "{}")
+	test("[1, a: 2]", `{"0":1,"a":2}`)
+	assert.T(t).This(encode("#(1, (a: 2))", True, "throw")).
+		Is("[\n  1,\n  {\n    \"a\": 2\n  }\n]")
+	assert.T(t).This(encode("#(a: 1)", SuStr("\t"), "throw")).
+		Is("{\n\t\"a\": 1\n}")
+
+	other := func(v Value, other string) string {
+		return ToStr(JsonEncode(th, []Value{v, False, SuStr(other)}))
+	}
+	fn := compile.Constant("function () { }")
+	inf := SuDnum{Dnum: dnum.PosInf}
+	assert.T(t).This(func() { other(SuObjectOf(fn), "throw") }).
+		Panics("can't convert")
+	assert.T(t).This(func() { other(inf, "throw") }).
+		Panics("can't convert")
+	assert.T(t).This(other(SuObjectOf(One, fn, inf, Zero), "skip")).
+		Is("[1,0]")
+	ob := &SuObject{}
+	ob.Set(SuStr("a"), One)
+	ob.Set(SuStr("b"), fn)
+	assert.T(t).This(other(ob, "skip")).Is(`{"a":1}`)
+	assert.T(t).This(other(SuObjectOf(inf, fn), "null")).Is("[null,null]")
+	assert.T(t).This(other(SuObjectOf(inf), "string")).Is(`["inf"]`)
+	assert.T(t).This(func() { other(One, "x") }).Panics("other must be")
+}
+
+func TestJsonDecode(t *testing.T) {
+	decode := func(s string) Value {
+		return JsonDecode(SuStr(s), EmptyStr, False)
+	}
+	test := func(s string, expected string) {
+		t.Helper()
+		assert.T(t).This(decode(s)).Is(compile.Constant(expected))
+	}
+	test("123", "123")
+	test("-0.5", "-.5")
+	test("1.5e3", "1500")
+	test("1E-2", ".01")
+	test("12345678901234567", "12345678901234567")
+	test("0.1", ".1")
+	test(" true ", "true")
+	test("false", "false")
+	test("null", `""`)
+	test(`"aé\n\"\/"`, `"a`+"é"+`\n\"/"`)
+	test(`"😀"`, `"`+"\U0001F600"+`"`)
+	test(`"x\ud83dx"`, `"x`+"�"+`x"`)
+	test("[]", "#()")
+	test(`[1, "a", [2], {}]`, `#(1, "a", (2), ())`)
+	test(`{"a": 1, "b": {"c": [true]}}`, "#(a: 1, b: (c: (true)))")
+	test(`"2024-01-15T12:34:56.789"`, `"2024-01-15T12:34:56.789"`)
+	assert.T(t).This(JsonDecode(SuStr("[null]"), False, False)).
+		Is(SuObjectOf(False))
+	dates := func(s string) Value {
+		return JsonDecode(SuStr(s), EmptyStr, True)
+	}
+	assert.T(t).This(dates(`["2024-01-15T12:34:56.789", "x", "#2024"]`)).
+		Is(compile.Constant(`#(#20240115.123456789, "x", "#2024")`))
+	assert.T(t).This(dates(`"#20240115.1234"`)).
+		Is(compile.Constant("#20240115.1234"))
+	assert.T(t).This(dates(`"2024-01-15T12:34:56Z"`)).
+		Is(DateFromLiteral("20240115.123456").(SuDate).
+			ToZone(LoadZone("UTC"), LoadZone("")))
+
+	bad := func(s string, err string) {
+		t.Helper()
+		assert.T(t).This(func() { decode(s) }).Panics(err)
+	}
+	bad("", "unexpected end")
+	bad("[1,", "unexpected end")
+	bad("[1,]", "Invalid Json format at position 3")
+	bad(`{"a" 1}`, "Invalid Json format at position 5")
+	bad("{1: 2}", "Invalid Json format at position 1")
+	bad("[1] x", "Invalid Json format at position 4")
+	bad("01", "Invalid Json format at position 1")
+	bad("1.", "Invalid Json format at position 2")
+	bad("1e", "Invalid Json format at position 2")
+	bad("-", "Invalid Json format at position 1")
+	bad(`"abc`, "unterminated")
+	bad("\"a\tb\"", "Invalid Json format at position 2")
+	bad(`"\x"`, "Invalid Json format at position 2")
+	bad(`"\u12g4"`, "Invalid Json format")
+	bad("tru", "Invalid Json format at position 0")
+}
+
+func TestJsonRoundTrip(t *testing.T) {
+	th := &Thread{}
+	x := compile.Constant(
+		`#(a: "a\nb", b: (1, -1.25, true), c: #20240115.1234, d: ())`)
+	s := JsonEncode(th, []Value{x, True, SuStr("throw")})
+	assert.T(t).This(JsonDecode(s, EmptyStr, True)).Is(x)
+}
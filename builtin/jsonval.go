@@ -0,0 +1,206 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package builtin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/core/types"
+	"github.com/apmckinlay/gsuneido/util/dnum"
+)
+
+// jsonEncode and jsonDecode map Suneido values to and from JSON
+// for Export and Import.
+// The mapping is lossless except that member names become strings.
+// jsonEncode uses the lossless mode of jsonEncoder (see json.go).
+//
+// csvEncode and csvDecode are used for CSV fields.
+// Strings are as is, numbers and booleans are as JSON,
+// dates are 2006-01-02T15:04:05.000 and objects are as JSON.
+// This is not lossless, on decode fields that look like
+// numbers, booleans, dates, or JSON arrays or objects are converted.
+
+const jsonDateFormat = "2006-01-02T15:04:05.000"
+
+func jsonEncode(sb *strings.Builder, v Value) {
+	je := jsonEncoder{sb: sb, lossless: true}
+	je.encode(v, 0)
+}
+
+func jsonTagged(sb *strings.Builder, tag, val string) {
+	sb.WriteString(`{"`)
+	sb.WriteString(tag)
+	sb.WriteString(`":`)
+	jsonQuote(sb, val)
+	sb.WriteByte('}')
+}
+
+// jsonNumber converts a (finite) Dnum to a valid JSON number
+// i.e. with a leading zero before a decimal point e.g. 0.5 instead of .5
+func jsonNumber(dn dnum.Dnum) string {
+	s := dn.String()
+	if strings.HasPrefix(s, ".") {
+		return "0" + s
+	} else if strings.HasPrefix(s, "-.") {
+		return "-0" + s[1:]
+	}
+	return s
+}
+
+// jsonQuote writes a JSON string.
+// Unlike encoding/json it does not escape <, >, and &
+func jsonQuote(sb *strings.Builder, s string) {
+	const hex = "0123456789abcdef"
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c == '\n':
+			sb.WriteString(`\n`)
+		case c == '\r':
+			sb.WriteString(`\r`)
+		case c == '\t':
+			sb.WriteString(`\t`)
+		case c < 0x20:
+			sb.WriteString(`\u00`)
+			sb.WriteByte(hex[c>>4])
+			sb.WriteByte(hex[c&0xf])
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+}
+
+func formatDate(d SuDate) string {
+	return d.ToGoTime().Format(jsonDateFormat)
+}
+
+func parseDate(s string) (SuDate, bool) {
+	t, err := time.ParseInLocation(jsonDateFormat, s, time.Local)
+	if err != nil {
+		return NilDate, false
+	}
+	return FromGoTime(t), true
+}
+
+// jsonDecode converts the result of encoding/json Decode (with UseNumber)
+func jsonDecode(x any) Value {
+	switch x := x.(type) {
+	case nil:
+		return EmptyStr
+	case bool:
+		return SuBool(x)
+	case string:
+		return SuStr(x)
+	case json.Number:
+		return NumFromString(string(x))
+	case []any:
+		ob := &SuObject{}
+		for _, y := range x {
+			ob.Add(jsonDecode(y))
+		}
+		return ob
+	case map[string]any:
+		if len(x) == 1 {
+			if v := jsonDecodeTagged(x); v != nil {
+				return v
+			}
+		}
+		ob := &SuObject{}
+		if list, ok := x["$list"].([]any); ok {
+			for _, y := range list {
+				ob.Add(jsonDecode(y))
+			}
+		}
+		for k, y := range x {
+			if k != "$list" {
+				ob.Set(SuStr(k), jsonDecode(y))
+			}
+		}
+		return ob
+	}
+	panic("invalid JSON value")
+}
+
+func jsonDecodeTagged(x map[string]any) Value {
+	for tag, y := range x {
+		s, ok := y.(string)
+		if !ok {
+			return nil
+		}
+		switch tag {
+		case "$date":
+			if d, ok := parseDate(s); ok {
+				return d
+			}
+			panic("invalid JSON date: " + s)
+		case "$number":
+			return SuDnum{Dnum: dnum.FromStr(s)}
+		case "$bytes":
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				panic("invalid JSON bytes: " + err.Error())
+			}
+			return SuStr(string(b))
+		}
+	}
+	return nil
+}
+
+func csvEncode(v Value) string {
+	switch v.Type() {
+	case types.String:
+		return ToStr(v)
+	case types.Date:
+		return formatDate(v.(SuDate))
+	}
+	var sb strings.Builder
+	if v.Type() == types.Number && ToDnum(v).IsInf() {
+		return ToDnum(v).String()
+	}
+	jsonEncode(&sb, v)
+	return sb.String()
+}
+
+func csvDecode(s string) Value {
+	switch {
+	case s == "true":
+		return True
+	case s == "false":
+		return False
+	case s == "inf" || s == "-inf":
+		return SuDnum{Dnum: dnum.FromStr(s)}
+	case s[0] == '{' || s[0] == '[':
+		var x any
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+		if dec.Decode(&x) == nil && !dec.More() {
+			return jsonDecode(x)
+		}
+	case isJsonNumber(s):
+		return NumFromString(s)
+	case len(s) == len(jsonDateFormat):
+		if d, ok := parseDate(s); ok {
+			return d
+		}
+	}
+	return SuStr(s)
+}
+
+func isJsonNumber(s string) bool {
+	if !json.Valid([]byte(s)) {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
@@ -59,8 +59,18 @@ func extractQuery(
 	return query, args
 }
 
+// paramsArg returns the params argument with the values
+// for :name parameters, or nil.
+// A non-object params is a where field (see queryWhere)
+func paramsArg(params Value) Value {
+	if !isContainer(params) {
+		return nil
+	}
+	return params
+}
+
 // queryWhere builds a where for the named arguments
-// (except for 'block')
+// (except for 'block' and 'params')
 // It should be equivalent to dbms getWhere.
 func queryWhere(as *ArgSpec, args []Value) string {
 	var sb strings.Builder
@@ -71,7 +81,8 @@ func queryWhere(as *ArgSpec, args []Value) string {
 			continue
 		}
 		field := ToStr(k)
-		if field == "query" || (field == "block" && !stringable(v)) {
+		if field == "query" || (field == "block" && !stringable(v)) ||
+			(field == "params" && isContainer(v)) {
 			continue
 		}
 		sb.WriteString(sep)
@@ -83,6 +94,11 @@ func queryWhere(as *ArgSpec, args []Value) string {
 	return sb.String()
 }
 
+func isContainer(v Value) bool {
+	_, ok := v.ToContainer()
+	return ok
+}
+
 func stringable(v Value) bool {
 	_, ok := v.AsStr()
 	return ok
@@ -166,7 +182,7 @@ func query_Tree(this Value) Value {
 var _ = builtin(formatQuery, "(query :string) :string")
 
 func formatQuery(th *Thread, args []Value) Value {
-	if dbms, ok := th.Dbms().(*dbms.DbmsLocal); ok {
+	if dbms := dbms.Local(th.Dbms(), ""); dbms != nil {
 		return SuStr(dbms.FormatQuery(ToStr(args[0])))
 	}
 	return th.Dbms().Exec(th, SuObjectOf(SuStr("FormatQuery"), args[0]))
@@ -215,8 +231,8 @@ func sqs_GetSort(arg Value) Value {
 var _ = staticMethod(sqs_Parse, "(parse :string) :unknown")
 
 func sqs_Parse(th *Thread, args []Value) Value {
-	dbms, ok := th.Dbms().(*dbms.DbmsLocal)
-	if !ok {
+	dbms := dbms.Local(th.Dbms(), "")
+	if dbms == nil {
 		panic("Query.Parse requires a local database")
 	}
 	t := dbms.Transaction(false)
@@ -226,6 +242,15 @@ func sqs_Parse(th *Thread, args []Value) Value {
 	return qry.NewSuQueryNode(q)
 }
 
+var _ = staticMethod(sqs_Analyze, "(query :string) :string")
+
+func sqs_Analyze(th *Thread, args []Value) Value {
+	if dbms := dbms.Local(th.Dbms(), "admin"); dbms != nil {
+		return SuStr(dbms.AnalyzeQuery(th, ToStr(args[0])))
+	}
+	return th.Dbms().Exec(th, SuObjectOf(SuStr("Query.Analyze"), args[0]))
+}
+
 var _ = staticMethod(sqs_Strategy1, "(@args) :string")
 
 func sqs_Strategy1(th *Thread, args []Value) Value {
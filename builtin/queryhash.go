@@ -18,7 +18,7 @@ func QueryHash(th *Thread, args []Value) Value {
 	details := ToBool(args[1])
 	tran := th.Dbms().Transaction(false)
 	defer tran.Complete()
-	q := tran.Query(query, nil)
+	q := tran.Query(query, nil, nil)
 	// fmt.Println(q.Strategy(true))
 	qh := qry.NewQueryHasher(q.Header()).CheckDups()
 
@@ -8,6 +8,7 @@ import (
 	"math"
 	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/apmckinlay/gsuneido/compile/lexer"
 	tok "github.com/apmckinlay/gsuneido/compile/tokens"
@@ -16,6 +17,7 @@ import (
 	"github.com/apmckinlay/gsuneido/util/regex"
 	"github.com/apmckinlay/gsuneido/util/str"
 	"github.com/apmckinlay/gsuneido/util/tabs"
+	"github.com/apmckinlay/gsuneido/util/unistr"
 
 	"github.com/apmckinlay/gsuneido/util/ascii"
 	"github.com/apmckinlay/gsuneido/util/tr"
@@ -135,6 +137,8 @@ func string_Eval2(th *Thread, this Value, args []Value) Value {
 
 var _ = method(string_Extract, "(pattern, part=false) :false|string")
 
+// string_Extract part may be a group number or a group name
+
 func string_Extract(th *Thread, this Value, args []Value) Value {
 	s := ToStr(this)
 	pat := th.Regex(args[0])
@@ -148,6 +152,12 @@ func string_Extract(th *Thread, this Value, args []Value) Value {
 		if pos == -1 {
 			pos, end = cap[0], cap[1]
 		}
+	} else if name, ok := args[1].ToStr(); ok {
+		g := pat.Group(name)
+		if g == -1 {
+			panic("string.Extract: nonexistent group: " + name)
+		}
+		pos, end = cap[2*g], cap[2*g+1]
 	} else {
 		part := ToInt(args[1]) * 2
 		pos, end = cap[part], cap[part+1]
@@ -306,10 +316,15 @@ func string_Match(th *Thread, this Value, args []Value) Value {
 		return False
 	}
 	ob := &SuObject{}
+	names := pat.Names()
 	for i := 0; i < len(cap); i += 2 {
 		org, end := int(cap[i]), int(cap[i+1])
 		if org >= 0 {
-			ob.Set(SuInt(i/2), SuObjectOf(IntVal(org), IntVal(end-org)))
+			m := SuObjectOf(IntVal(org), IntVal(end-org))
+			ob.Set(SuInt(i/2), m)
+			if i/2 < len(names) && names[i/2] != "" {
+				ob.Set(SuStr(names[i/2]), m)
+			}
 		}
 	}
 	return ob
@@ -526,6 +541,73 @@ func string_UpperQ(this Value) Value {
 	return SuBool(result)
 }
 
+// The Utf8 methods are Unicode aware versions for UTF-8 strings,
+// the other methods are byte oriented (see util/unistr)
+
+var _ = method(string_Utf8Compare, "(string :string, lang :string = '') :number")
+
+func string_Utf8Compare(this, s, lang Value) Value {
+	return IntVal(unistr.Compare(ToStr(this), ToStr(s), ToStr(lang)))
+}
+
+var _ = method(string_Utf8Lower, "() :string")
+
+func string_Utf8Lower(this Value) Value {
+	return sameStr(this, unistr.Lower(ToStr(this)))
+}
+
+var _ = method(string_Utf8Normalize, "(form :string = 'NFC') :string")
+
+func string_Utf8Normalize(this, form Value) Value {
+	return sameStr(this, unistr.Normalize(ToStr(this), ToStr(form)))
+}
+
+var _ = method(string_Utf8Reverse, "() :string")
+
+func string_Utf8Reverse(this Value) Value {
+	return SuStr(unistr.Reverse(ToStr(this)))
+}
+
+var _ = method(string_Utf8Size, "() :number")
+
+func string_Utf8Size(this Value) Value {
+	return IntVal(unistr.Size(ToStr(this)))
+}
+
+var _ = method(string_Utf8SortKey, "(lang :string = '') :string")
+
+func string_Utf8SortKey(this, lang Value) Value {
+	return SuStr(unistr.SortKey(ToStr(this), ToStr(lang)))
+}
+
+var _ = method(string_Utf8Substr, "(i :number, n = false) :string")
+
+// string_Utf8Substr is like [i :: n] but in characters
+func string_Utf8Substr(this, arg1, arg2 Value) Value {
+	s := ToStr(this)
+	i := ToInt(arg1)
+	if i < 0 {
+		i = max(0, i+unistr.Size(s))
+	}
+	n := math.MaxInt
+	if arg2 != False {
+		n = ToInt(arg2)
+	}
+	return sameStr(this, unistr.Substr(s, i, n))
+}
+
+var _ = method(string_Utf8Upper, "() :string")
+
+func string_Utf8Upper(this Value) Value {
+	return sameStr(this, unistr.Upper(ToStr(this)))
+}
+
+var _ = method(string_Utf8ValidQ, "() :boolean")
+
+func string_Utf8ValidQ(this Value) Value {
+	return SuBool(utf8.ValidString(ToStr(this)))
+}
+
 func replace(th *Thread, s string, patarg Value, reparg Value, count int) string {
 	if count <= 0 || (patarg == EmptyStr && reparg == EmptyStr) {
 		return s
@@ -555,7 +637,7 @@ func replace(th *Thread, s string, patarg Value, reparg Value, count int) string
 		// Get the replacement text
 		var r string
 		if reparg == nil {
-			r = regex.Replacement(s, rep, cap)
+			r = pat.Replacement(s, rep, cap)
 		} else {
 			r = s[pos:end]
 			v := th.Call(reparg, SuStr1(r))
@@ -48,6 +48,9 @@ func TestReplace(t *testing.T) {
 	// zero width match
 	test("world", "^x?", "hello", 1, "helloworld")
 
+	// named groups
+	test("hello world", `(?<a>\w+) (?<b>\w+)`, `\k<b> \k<a>`, 1, "world hello")
+
 	// Test with a callable replacement that returns the same string
 	t.Run("callable replacement - no change", func(t *testing.T) {
 		input := "hello world"
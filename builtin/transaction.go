@@ -42,7 +42,7 @@ func Transaction(th *Thread, args []Value) Value {
 	return th.Call(args[2], st)
 }
 
-var queryBlockParams = params("(query, block = false)")
+var queryBlockParams = params("(query, block = false, params = false)")
 
 var _ = exportMethods(&TranMethods, "tran")
 
@@ -70,7 +70,7 @@ var _ = method(tran_Query, "(@args) :unknown")
 func tran_Query(th *Thread, as *ArgSpec, this Value, args []Value) Value {
 	query, args := extractQuery(th, &queryBlockParams, as, args)
 	mustNotBeAction(query)
-	q := this.(*SuTran).Query(th, query)
+	q := this.(*SuTran).Query(th, query, paramsArg(args[2]))
 	if args[1] == False {
 		return q
 	}
@@ -265,6 +265,11 @@ func (p *Parser) atom() (result ast.Expr) {
 			p.final[id] = disqualified // modified
 		}
 		return p.Unary(token, e)
+	case tok.Colon:
+		if p.Param != nil { // query parameter e.g. :name
+			p.Next()
+			return p.Param(p.MatchIdent())
+		}
 	case tok.Dot: // unary, i.e. implicit "this"
 		// does not absorb Dot
 		p.newline = false
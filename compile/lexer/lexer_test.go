@@ -237,4 +237,5 @@ var queryKeywords = map[string]tok.Token{
 	"update":    tok.Update,
 	"view":      tok.View,
 	"where":     tok.Where,
+	"window":    tok.Window,
 }
@@ -135,6 +135,9 @@ func queryKeyword(s string) (tok.Token, string) {
 		if s == "update" {
 			return tok.Update, "update"
 		}
+		if s == "window" {
+			return tok.Window, "window"
+		}
 	case 7:
 		if s == "average" {
 			return tok.Average, "average"
@@ -79,6 +79,9 @@ type ParserBase struct {
 
 	// EqToIs treats Eq as Is for queries
 	EqToIs bool
+
+	// Param is used by queries to get the expression for a :name parameter
+	Param func(name string) Expr
 }
 
 type Parser struct {
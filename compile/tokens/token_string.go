@@ -149,12 +149,13 @@ func _() {
 	_ = x[Update-138]
 	_ = x[View-139]
 	_ = x[Where-140]
-	_ = x[Ntokens-141]
+	_ = x[Window-141]
+	_ = x[Ntokens-142]
 }
 
-const _Token_name = "NilEofErrorIdentifierNumberStringSymbolWhitespaceCommentNewlineHashCommaSemicolonAtLParenRParenLBracketRBracketLCurlyRCurlyRangeToRangeLenOpsStartNotBitNotNewDotCompareStartIsIsntMatchMatchNotLtLteGtGteCompareEndQMarkColonAssocStartAndOrBitOrBitAndBitXorAddSubCatMulDivAssocEndModLShiftRShiftPipeIncDecStartIncPostIncDecPostDecIncDecEndAssignStartEqAddEqSubEqCatEqMulEqDivEqModEqLShiftEqRShiftEqBitOrEqBitAndEqBitXorEqAssignEndInBreakCaseCatchClassContinueDefaultDoElseFalseForForeverFunctionIfReturnSwitchSuperThisThrowTrueTryWhileQueryStartSummarizeStartAverageCountListMaxMinTotalSummarizeEndAlterByCascadeCreateDeleteDropEnsureExtendHistoryIndexInsertIntersectIntoJoinKeyLeftjoinSemijoinLowerMinusProjectRemoveRenameReverseSetSortSummarizeSviewTempIndexTimesToUnionUniqueUpdateViewWhereNtokens"
+const _Token_name = "NilEofErrorIdentifierNumberStringSymbolWhitespaceCommentNewlineHashCommaSemicolonAtLParenRParenLBracketRBracketLCurlyRCurlyRangeToRangeLenOpsStartNotBitNotNewDotCompareStartIsIsntMatchMatchNotLtLteGtGteCompareEndQMarkColonAssocStartAndOrBitOrBitAndBitXorAddSubCatMulDivAssocEndModLShiftRShiftPipeIncDecStartIncPostIncDecPostDecIncDecEndAssignStartEqAddEqSubEqCatEqMulEqDivEqModEqLShiftEqRShiftEqBitOrEqBitAndEqBitXorEqAssignEndInBreakCaseCatchClassContinueDefaultDoElseFalseForForeverFunctionIfReturnSwitchSuperThisThrowTrueTryWhileQueryStartSummarizeStartAverageCountListMaxMinTotalSummarizeEndAlterByCascadeCreateDeleteDropEnsureExtendHistoryIndexInsertIntersectIntoJoinKeyLeftjoinSemijoinLowerMinusProjectRemoveRenameReverseSetSortSummarizeSviewTempIndexTimesToUnionUniqueUpdateViewWhereWindowNtokens"
 
-var _Token_index = [...]uint16{0, 3, 6, 11, 21, 27, 33, 39, 49, 56, 63, 67, 72, 81, 83, 89, 95, 103, 111, 117, 123, 130, 138, 146, 149, 155, 158, 161, 173, 175, 179, 184, 192, 194, 197, 199, 202, 212, 217, 222, 232, 235, 237, 242, 248, 254, 257, 260, 263, 266, 269, 277, 280, 286, 292, 296, 307, 310, 317, 320, 327, 336, 347, 349, 354, 359, 364, 369, 374, 379, 387, 395, 402, 410, 418, 427, 429, 434, 438, 443, 448, 456, 463, 465, 469, 474, 477, 484, 492, 494, 500, 506, 511, 515, 520, 524, 527, 532, 542, 556, 563, 568, 572, 575, 578, 583, 595, 600, 602, 609, 615, 621, 625, 631, 637, 644, 649, 655, 664, 668, 672, 675, 683, 691, 696, 701, 708, 714, 720, 727, 730, 734, 743, 748, 757, 762, 764, 769, 775, 781, 785, 790, 797}
+var _Token_index = [...]uint16{0, 3, 6, 11, 21, 27, 33, 39, 49, 56, 63, 67, 72, 81, 83, 89, 95, 103, 111, 117, 123, 130, 138, 146, 149, 155, 158, 161, 173, 175, 179, 184, 192, 194, 197, 199, 202, 212, 217, 222, 232, 235, 237, 242, 248, 254, 257, 260, 263, 266, 269, 277, 280, 286, 292, 296, 307, 310, 317, 320, 327, 336, 347, 349, 354, 359, 364, 369, 374, 379, 387, 395, 402, 410, 418, 427, 429, 434, 438, 443, 448, 456, 463, 465, 469, 474, 477, 484, 492, 494, 500, 506, 511, 515, 520, 524, 527, 532, 542, 556, 563, 568, 572, 575, 578, 583, 595, 600, 602, 609, 615, 621, 625, 631, 637, 644, 649, 655, 664, 668, 672, 675, 683, 691, 696, 701, 708, 714, 720, 727, 730, 734, 743, 748, 757, 762, 764, 769, 775, 781, 785, 790, 796, 803}
 
 func (i Token) String() string {
 	idx := int(i) - 0
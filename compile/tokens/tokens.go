@@ -155,6 +155,7 @@ const (
 	Update
 	View
 	Where
+	Window
 	Ntokens
 )
 
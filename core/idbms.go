@@ -3,6 +3,8 @@
 
 package core
 
+import "time"
+
 // IDbms is the interface to the dbms package.
 // The two implementations, DbmsLocal and DbmsClient, are in the dbms package
 type IDbms interface {
@@ -12,6 +14,13 @@ type IDbms interface {
 	// Auth authorizes the connection with the server
 	Auth(*Thread, string) bool
 
+	// Changes returns up to limit captured changes (see db19/changes.go)
+	// to the tables (or to any table if tables is empty)
+	// with timestamps after since (or all if since is NilDate), oldest first.
+	// If there are none, it waits up to wait for new changes.
+	Changes(th *Thread, tables []string, since SuDate, limit int,
+		wait time.Duration) *SuObject
+
 	// Check checks the database like -check
 	// It returns "" or an error message.
 	Check(full bool) string
@@ -22,8 +31,9 @@ type IDbms interface {
 	// Connections returns a list of the current server connections
 	Connections() Value
 
-	// Cursor is like a query but independent of any one transaction
-	Cursor(query string, sv *Sviews) ICursor
+	// Cursor is like a query but independent of any one transaction.
+	// params are the values for :name parameters, or nil
+	Cursor(query string, params Value, sv *Sviews) ICursor
 
 	// Cursors returns the current number of cursors
 	Cursors() int
@@ -62,6 +72,10 @@ type IDbms interface {
 	// Nonce returns a random string from the server
 	Nonce(*Thread) string
 
+	// Salt returns the password salt and iterations for a user.
+	// Nonexistent and legacy users get a consistent fake salt.
+	Salt(th *Thread, user string) string
+
 	// Run is used by the old style string.ServerEval()
 	Run(th *Thread, code string) Value
 
@@ -113,8 +127,9 @@ type ITran interface {
 	// QueryFirst (dir = Next), or QueryLast (dir = Prev)
 	Get(th *Thread, query Value, dir Dir) (Row, *Header, string)
 
-	// Query starts a query
-	Query(query string, sv *Sviews) IQuery
+	// Query starts a query.
+	// params are the values for :name parameters, or nil
+	Query(query string, params Value, sv *Sviews) IQuery
 
 	// Action executes an insert, update, or delete
 	// and returns the number of records processed
@@ -254,8 +254,14 @@ func FromGoTime(t time.Time) SuDate {
 		t.Hour(), t.Minute(), t.Second(), t.Nanosecond()/1000000)
 }
 
-// Format converts the date to a string in the specified format
+// Format converts the date to a string in the specified format.
+// Zone offsets are for the local time zone.
 func (d SuDate) Format(fmt string) string {
+	return d.FormatIn(fmt, time.Local)
+}
+
+// FormatIn is like Format but takes the zone (for X) as an argument
+func (d SuDate) FormatIn(fmt string, loc *time.Location) string {
 	fmtlen := len(fmt)
 	var dst strings.Builder
 	add := func(i int) {
@@ -363,6 +369,26 @@ func (d SuDate) Format(fmt string) string {
 			if n > 1 {
 				dst.WriteRune('M')
 			}
+		case 'X': // zone
+			name, offset := d.In(loc).Zone()
+			if n >= 3 {
+				dst.WriteString(name)
+				break
+			}
+			if offset < 0 {
+				dst.WriteByte('-')
+				offset = -offset
+			} else {
+				dst.WriteByte('+')
+			}
+			offset /= 60
+			add(offset / 600)
+			add((offset / 60) % 10)
+			if n == 2 {
+				dst.WriteByte(':')
+			}
+			add((offset % 60) / 10)
+			add(offset % 10)
 		case '\'':
 			for i++; i < fmtlen && (fmt[i] != '\''); i++ {
 				dst.WriteByte(fmt[i])
@@ -387,9 +413,19 @@ var days = []string{
 	"Saturday"}
 
 // ParseDate converts a human readable date to a SuDate.
+// ISO 8601 date times with a zone offset are converted to local time.
 //
 // Returns NilDate if it fails.
 func ParseDate(s string, order string) SuDate {
+	return ParseDateIn(s, order, time.Local)
+}
+
+// ParseDateIn is like ParseDate but converts ISO 8601 date times
+// with a zone offset to the wall clock time in loc
+func ParseDateIn(s string, order string, loc *time.Location) SuDate {
+	if t, ok := parseIsoZone(s); ok {
+		return FromGoTime(t.In(loc))
+	}
 	NOTSET := 9999
 	year := NOTSET
 	month := 0
@@ -616,6 +652,35 @@ func ParseDate(s string, order string) SuDate {
 	return NewDate(year, month, day, hour, minute, second, millisecond)
 }
 
+var isoLayouts = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05Z07",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04Z0700",
+	"2006-01-02T15:04Z07",
+}
+
+// parseIsoZone handles ISO 8601 date times with a zone designator
+// e.g. 2024-03-10T14:30:00Z or 2024-03-10 14:30:00.123-05:00
+// Fractional seconds are allowed after the seconds.
+func parseIsoZone(s string) (time.Time, bool) {
+	if len(s) < 17 || s[4] != '-' || s[7] != '-' ||
+		(s[10] != 'T' && s[10] != ' ') ||
+		(s[len(s)-1] != 'Z' && !strings.ContainsAny(s[11:], "+-")) {
+		return time.Time{}, false
+	}
+	if s[10] == ' ' {
+		s = s[:10] + "T" + s[11:]
+	}
+	for _, layout := range isoLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
 func nextWord(s string, si int) string {
 	dst := []byte{}
 	for ; si < len(s) && ascii.IsLetter(s[si]); si++ {
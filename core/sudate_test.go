@@ -6,6 +6,7 @@ package core
 import (
 	"cmp"
 	"testing"
+	"time"
 
 	"github.com/apmckinlay/gsuneido/util/assert"
 )
@@ -289,3 +290,60 @@ func FuzzParseDate(f *testing.F) {
 }
 
 // to run: go test -fuzz=FuzzParseDate -run=FuzzParseDate
+
+func TestDateZone(t *testing.T) {
+	assert := assert.T(t).This
+	date := func(s string) SuDate {
+		return DateFromLiteral(s).(SuDate)
+	}
+	utc := LoadZone("UTC")
+	tor := LoadZone("America/Toronto")
+	ind := LoadZone("Asia/Kolkata")
+	assert(LoadZone("")).Is(time.Local)
+	assert(func() { LoadZone("Nowhere/Special") }).
+		Panics("invalid time zone: Nowhere/Special")
+
+	assert(date("20240115.1200").ToZone(utc, tor)).Is(date("20240115.0700"))
+	assert(date("20240715.1200").ToZone(utc, tor)).Is(date("20240715.0800"))
+	assert(date("20240715.0800").ToZone(tor, utc)).Is(date("20240715.1200"))
+	assert(date("20240115.2330").ToZone(utc, ind)).Is(date("20240116.0500"))
+
+	d := date("20240115.123456")
+	assert(d.FormatIn("yyyy-MM-dd HH:mm X", tor)).Is("2024-01-15 12:34 -0500")
+	assert(d.FormatIn("yyyy-MM-dd HH:mm XX", ind)).Is("2024-01-15 12:34 +05:30")
+	assert(d.FormatIn("HH:mm XXX", tor)).Is("12:34 EST")
+	assert(date("20240715").FormatIn("XX XXX", tor)).Is("-04:00 EDT")
+	assert(d.FormatIn("XX", utc)).Is("+00:00")
+	// Z is not a format character
+	assert(d.Format("yyyy-MM-ddTHH:mm:ssZ")).Is("2024-01-15T12:34:56Z")
+	assert(d.FormatIn("yyyy-MM-ddTHH:mm:ssZ", tor)).Is("2024-01-15T12:34:56Z")
+
+	parse := func(s string, loc *time.Location, expected string) {
+		t.Helper()
+		assert(ParseDateIn(s, "yMd", loc)).Is(date(expected))
+	}
+	parse("2024-01-15T12:34:56Z", tor, "20240115.073456")
+	parse("2024-01-15T12:34:56.789Z", utc, "20240115.123456789")
+	parse("2024-01-15 12:34:56+05:30", utc, "20240115.070456")
+	parse("2024-01-15T12:34-0500", utc, "20240115.1734")
+	parse("2024-07-15T12:34:56-04", tor, "20240715.123456")
+	parse("2024-01-15T12:34:56", tor, "20240115.123456") // no zone
+	assert(ParseDateIn("2024-01-15T12:34:56+25:00", "yMd", utc)).Is(NilDate)
+
+	plus := func(s string, hr, min int, expected string) {
+		t.Helper()
+		assert(date(s).PlusIn(tor, 0, 0, 0, hr, min, 0, 0)).Is(date(expected))
+	}
+	// spring forward 2024-03-10 02:00 => 03:00
+	plus("20240310.0130", 1, 0, "20240310.0330")
+	plus("20240310.0330", -1, 0, "20240310.0130")
+	plus("20240310.0159", 0, 1, "20240310.0300")
+	// fall back 2024-11-03 02:00 => 01:00
+	plus("20241103.0030", 2, 0, "20241103.0130")
+	plus("20241102.1200", 24, 0, "20241103.1100")
+	// days are calendar days
+	assert(date("20240309.1200").PlusIn(tor, 0, 0, 1, 0, 0, 0, 0)).
+		Is(date("20240310.1200"))
+	assert(date("20240309.1200").Plus(0, 0, 0, 24, 0, 0, 0)).
+		Is(date("20240310.1200"))
+}
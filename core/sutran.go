@@ -111,9 +111,9 @@ func (st *SuTran) GetRow(th *Thread, query Value, dir Dir) (Row, *Header, string
 	return st.itran.Get(th, query, dir)
 }
 
-func (st *SuTran) Query(th *Thread, query string) *SuQuery {
+func (st *SuTran) Query(th *Thread, query string, params Value) *SuQuery {
 	st.ckActive()
-	iquery := st.itran.Query(query, nil)
+	iquery := st.itran.Query(query, params, nil)
 	return NewSuQuery(th, st, query, iquery)
 }
 
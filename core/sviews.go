@@ -43,3 +43,13 @@ func (sv *Sviews) DropSview(name string) bool {
 	}
 	return false
 }
+
+// Empty returns true if there are no session views
+func (sv *Sviews) Empty() bool {
+	if sv == nil {
+		return true
+	}
+	sv.lock.Lock()
+	defer sv.lock.Unlock()
+	return len(sv.defs) == 0
+}
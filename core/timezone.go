@@ -0,0 +1,66 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package core
+
+import (
+	"sync"
+	"time"
+	_ "time/tzdata" // embed the IANA database so zones work on any server
+)
+
+// SuDate does not store a time zone.
+// These functions interpret a date as the wall clock time in a given zone.
+
+var zones = map[string]*time.Location{}
+var zonesLock sync.Mutex
+
+// LoadZone returns the location for an IANA time zone name
+// e.g. "America/Toronto". "" or "Local" give the OS local zone.
+// It panics if the zone is not found.
+func LoadZone(name string) *time.Location {
+	if name == "" || name == "Local" {
+		return time.Local
+	}
+	zonesLock.Lock()
+	defer zonesLock.Unlock()
+	if loc, ok := zones[name]; ok {
+		return loc
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic("invalid time zone: " + name)
+	}
+	zones[name] = loc
+	return loc
+}
+
+// In returns the Go time for the date as a wall clock time in loc.
+// Times that are skipped by a daylight savings change
+// are moved forward by the length of the change.
+func (d SuDate) In(loc *time.Location) time.Time {
+	return time.Date(d.Year(), time.Month(d.Month()), d.Day(), d.Hour(),
+		d.Minute(), d.Second(), d.Millisecond()*1000000, loc)
+}
+
+// ToZone converts the date from a wall clock time in one zone
+// to the wall clock time for the same instant in another zone.
+func (d SuDate) ToZone(from, to *time.Location) SuDate {
+	return FromGoTime(d.In(from).In(to))
+}
+
+// PlusIn is like Plus but takes daylight savings changes in loc
+// into account. Years, months, and days are calendar amounts,
+// hours, minutes, seconds, and milliseconds are elapsed time.
+func (d SuDate) PlusIn(loc *time.Location,
+	yr int, mon int, day int, hr int, min int, sec int, ms int) SuDate {
+	t := time.Date(d.Year()+yr, time.Month(d.Month()+mon), d.Day()+day,
+		d.Hour(), d.Minute(), d.Second(), d.Millisecond()*1000000, loc)
+	t = t.Add(time.Duration(hr)*time.Hour + time.Duration(min)*time.Minute +
+		time.Duration(sec)*time.Second + time.Duration(ms)*time.Millisecond)
+	nd := FromGoTime(t.In(loc))
+	if nd == NilDate {
+		panic("bad date")
+	}
+	return nd
+}
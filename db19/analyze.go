@@ -0,0 +1,127 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package db19
+
+import (
+	"github.com/apmckinlay/gsuneido/db19/meta"
+	"github.com/apmckinlay/gsuneido/util/hll"
+	"github.com/apmckinlay/gsuneido/util/kll"
+	"github.com/apmckinlay/gsuneido/util/ss"
+)
+
+// statsMcv is the maximum number of most common values per column
+const statsMcv = 10
+
+// statsBuckets is the maximum number of histogram buckets per column
+const statsBuckets = 32
+
+// Analyze reads a table and saves the column statistics in the metadata.
+// The statistics are used by query optimization.
+// Like Database.Distinct and Top10 it reads the entire table.
+// The first pass finds the distinct counts and most common values.
+// The second pass builds histograms of the remaining values.
+func (db *Database) Analyze(table string) *meta.Stats {
+	if db.IsCorrupted() {
+		panic("database is locked")
+	}
+	st := db.analyze(table)
+	db.UpdateState(func(state *DbState) {
+		state.Meta = state.Meta.PutStats(st)
+	})
+	return st
+}
+
+func (db *Database) analyze(table string) *meta.Stats {
+	rt := db.NewReadTran()
+	ts := rt.getSchema(table)
+	type colSketch struct {
+		hll *hll.HLL
+		ss  *ss.Sketch[string]
+		kll *kll.Sketch[string]
+		mcv map[string]struct{}
+	}
+	sketches := make([]colSketch, len(ts.Columns))
+	for i, col := range ts.Columns {
+		if col != "-" {
+			sketches[i] = colSketch{hll: hll.New(),
+				ss: ss.New[string](2 * statsMcv), kll: kll.New[string]()}
+		}
+	}
+	scan := func(fn func(sk *colSketch, val string)) int {
+		nrows := 0
+		iter := rt.IndexIter(table, 0)
+		for iter.Next(rt); !iter.Eof(); iter.Next(rt) {
+			rec := OffToRec(db.Store, iter.CurOff())
+			for i := range sketches {
+				if sk := &sketches[i]; sk.hll != nil {
+					fn(sk, rec.GetRaw(i))
+				}
+			}
+			nrows++
+		}
+		return nrows
+	}
+	// first pass for distinct counts and most common values
+	nrows := scan(func(sk *colSketch, val string) {
+		sk.hll.Add(val)
+		sk.ss.Add(val)
+	})
+	st := &meta.Stats{Table: table, Nrows: nrows}
+	for i, col := range ts.Columns {
+		sk := &sketches[i]
+		if sk.hll == nil {
+			continue
+		}
+		cs := meta.ColStats{Column: col,
+			Distinct: min(int(sk.hll.Count()), nrows)}
+		if nrows > 0 {
+			cs.Distinct = max(cs.Distinct, 1)
+		}
+		sk.mcv = make(map[string]struct{})
+		for _, e := range sk.ss.Top() {
+			count := int(e.Count - e.Error)
+			// only keep values that are more common than average
+			if len(cs.Mcv) >= statsMcv || count < 2 ||
+				count*cs.Distinct <= nrows {
+				break
+			}
+			if len(e.Value) > meta.MaxStatsValue {
+				continue
+			}
+			cs.Mcv = append(cs.Mcv, e.Value)
+			cs.McvCount = append(cs.McvCount, count)
+			sk.mcv[e.Value] = struct{}{}
+		}
+		st.Cols = append(st.Cols, cs)
+	}
+	// second pass for the histograms of the rest of the values
+	scan(func(sk *colSketch, val string) {
+		if _, ok := sk.mcv[val]; !ok {
+			sk.kll.Insert(val[:min(len(val), meta.MaxStatsValue)])
+		}
+	})
+	c := 0
+	for i := range sketches {
+		sk := &sketches[i]
+		if sk.hll == nil {
+			continue
+		}
+		cs := &st.Cols[c]
+		c++
+		if sk.kll.Count() == 0 {
+			continue
+		}
+		nb := max(1, min(statsBuckets, cs.Distinct-len(cs.Mcv)))
+		cs.Hist = make([]string, 0, nb+1)
+		for j := range nb + 1 {
+			cs.Hist = append(cs.Hist, sk.kll.Query(float64(j)/float64(nb)))
+		}
+	}
+	return st
+}
+
+// GetStats returns the column statistics for a table, or nil if none
+func (db *Database) GetStats(table string) *meta.Stats {
+	return db.GetState().Meta.GetStats(table)
+}
@@ -0,0 +1,350 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package db19
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	"github.com/apmckinlay/gsuneido/util/system"
+)
+
+// The archive is used for continuous (point-in-time) backup.
+//
+// The database file is append-only, so every persisted state,
+// along with all the data and index nodes it references,
+// is contained in the prefix of the file up to that state.
+// The archive is simply a copy of the file, split into segment files,
+// each named by its starting offset in hex with an .arc extension.
+//
+// Data is shipped to the archive after each persist, up to shipEnd
+// (see Database.persist). When the database is closed everything is shipped.
+//
+// RestoreArchive rebuilds a database as of any persisted state.
+
+const archiveExt = ".arc"
+const archiveSegmentSize = 64 * 1024 * 1024 // 64 mb
+
+type archive struct {
+	dir string
+	// f is the current (last) segment file
+	f        *os.File
+	segStart uint64
+	// shipped is the end of what has been written to the archive
+	shipped uint64
+	failed  bool
+	lock    sync.Mutex
+}
+
+// StartArchive starts shipping the database to an archive directory.
+// If the directory is empty the entire database is copied to it.
+// Otherwise it continues the existing archive,
+// which must be from this database.
+// It must be called before StartConcur.
+func (db *Database) StartArchive(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	segs, err := archiveSegments(dir)
+	if err != nil {
+		return err
+	}
+	ar := &archive{dir: dir}
+	if len(segs) > 0 {
+		last := segs[len(segs)-1]
+		ar.f, err = os.OpenFile(last.path, os.O_RDWR|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		ar.segStart = last.start
+		ar.shipped = last.start + last.size
+		if err := ar.verify(db.Store); err != nil {
+			ar.f.Close()
+			return err
+		}
+	}
+	// no concurrent writers yet, so it is safe to ship everything
+	if err := ar.ship(db.Store, db.Store.Size()); err != nil {
+		return err
+	}
+	db.archive = ar
+	return nil
+}
+
+// verify checks that the end of the archive matches the database
+func (ar *archive) verify(store *stor.Stor) error {
+	const n = 4096
+	if ar.shipped > store.Size() {
+		return errors.New("archive: does not match database (archive is larger)")
+	}
+	from := ar.segStart
+	if ar.shipped-from > n {
+		from = ar.shipped - n
+	}
+	buf := make([]byte, ar.shipped-from)
+	if _, err := ar.f.ReadAt(buf, int64(from-ar.segStart)); err != nil {
+		return err
+	}
+	var data []byte
+	storeRange(store, from, ar.shipped, func(b []byte) error {
+		data = append(data, b...)
+		return nil
+	})
+	if !bytes.Equal(buf, data) {
+		return errors.New("archive: does not match database")
+	}
+	return nil
+}
+
+// persisted is called by persist to ship up to end
+func (ar *archive) persisted(store *stor.Stor, end uint64) {
+	ar.lock.Lock()
+	defer ar.lock.Unlock()
+	if ar.failed {
+		return
+	}
+	if err := ar.ship(store, end); err != nil {
+		ar.fail(err)
+	}
+}
+
+// close ships everything up to the end of the final state
+// and closes the current segment
+func (ar *archive) close(store *stor.Stor, end uint64) {
+	ar.lock.Lock()
+	defer ar.lock.Unlock()
+	if !ar.failed {
+		if err := ar.ship(store, end); err != nil {
+			ar.fail(err)
+		}
+	}
+	if ar.f != nil {
+		ar.f.Close()
+		ar.f = nil
+	}
+}
+
+func (ar *archive) fail(err error) {
+	log.Println("ERROR: archive:", err, "(archiving stopped)")
+	ar.failed = true
+}
+
+// ship appends the store contents from shipped up to end to the archive,
+// starting new segments as necessary
+func (ar *archive) ship(store *stor.Stor, end uint64) error {
+	if end <= ar.shipped {
+		return nil
+	}
+	err := storeRange(store, ar.shipped, end, func(b []byte) error {
+		for len(b) > 0 {
+			if ar.f == nil || ar.shipped-ar.segStart >= archiveSegmentSize {
+				if err := ar.newSegment(); err != nil {
+					return err
+				}
+			}
+			n := min(len(b), int(archiveSegmentSize-(ar.shipped-ar.segStart)))
+			if _, err := ar.f.Write(b[:n]); err != nil {
+				return err
+			}
+			ar.shipped += uint64(n)
+			b = b[n:]
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return ar.f.Sync()
+}
+
+func (ar *archive) newSegment() error {
+	if ar.f != nil {
+		if err := ar.f.Sync(); err != nil {
+			return err
+		}
+		ar.f.Close()
+	}
+	path := filepath.Join(ar.dir, segmentName(ar.shipped))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	ar.f = f
+	ar.segStart = ar.shipped
+	return nil
+}
+
+// storeRange calls fn with the store data from..to, one chunk at a time
+func storeRange(store *stor.Stor, from, to uint64, fn func([]byte) error) error {
+	for from < to {
+		buf := store.Data(from)
+		n := min(uint64(len(buf)), to-from)
+		if err := fn(buf[:n]); err != nil {
+			return err
+		}
+		from += n
+	}
+	return nil
+}
+
+func segmentName(start uint64) string {
+	return fmt.Sprintf("%016x", start) + archiveExt
+}
+
+type segment struct {
+	path  string
+	start uint64
+	size  uint64
+}
+
+// archiveSegments returns the segments in an archive directory,
+// in order, verifying that they are contiguous
+func archiveSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segs []segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || filepath.Ext(name) != archiveExt {
+			continue
+		}
+		start, err := strconv.ParseUint(name[:len(name)-len(archiveExt)], 16, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, segment{path: filepath.Join(dir, name),
+			start: start, size: uint64(info.Size())})
+	}
+	slices.SortFunc(segs, func(x, y segment) int {
+		return cmp.Compare(x.start, y.start)
+	})
+	next := uint64(0)
+	for _, seg := range segs {
+		if seg.start != next {
+			return nil, fmt.Errorf("archive: missing segment %s",
+				segmentName(next))
+		}
+		next += seg.size
+	}
+	return segs, nil
+}
+
+//-------------------------------------------------------------------
+
+// RestoreArchive builds dbfile from the archive in dir
+// as of the last state at or before asof (unix milli).
+// It returns the time of the restored state.
+func RestoreArchive(dir, dbfile string, asof int64) (int64, error) {
+	segs, err := archiveSegments(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(segs) == 0 {
+		return 0, errors.New("restore: no archive segments found in " + dir)
+	}
+	tmpfile, err := copySegments(segs, filepath.Dir(dbfile))
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmpfile)
+	store, err := stor.MmapStor(tmpfile, stor.Read)
+	if err != nil {
+		return 0, err
+	}
+	off, t := findStateAsof(store, asof)
+	store.Close(true)
+	if off == 0 {
+		return 0, errors.New("restore: no state found as of the requested time")
+	}
+	if err := truncateToState(tmpfile, off); err != nil {
+		return 0, err
+	}
+	return t, system.RenameBak(tmpfile, dbfile)
+}
+
+func copySegments(segs []segment, dir string) (string, error) {
+	dst, err := os.CreateTemp(dir, "gs*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+	tmpfile := dst.Name()
+	for _, seg := range segs {
+		if err := appendFile(dst, seg.path); err != nil {
+			os.Remove(tmpfile)
+			return "", err
+		}
+	}
+	return tmpfile, nil
+}
+
+func appendFile(dst *os.File, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// findStateAsof returns the offset and time of the last valid state
+// at or before asof, or 0 if none
+func findStateAsof(store *stor.Stor, asof int64) (uint64, int64) {
+	version(store)
+	off := store.Size()
+	for {
+		if off = store.LastOffset(off, magic1, nil); off == 0 {
+			return 0, 0
+		}
+		if off+uint64(stateLen) > store.Size() {
+			continue // incomplete
+		}
+		if _, _, t := readStateSafe(store, off); t != 0 && t <= asof &&
+			getState(store, off) != nil {
+			return off, t
+		}
+	}
+}
+
+func readStateSafe(store *stor.Stor, off uint64) (offSchema, offInfo uint64, t int64) {
+	defer func() {
+		if e := recover(); e != nil {
+			t = 0
+		}
+	}()
+	return readState(store, off)
+}
+
+// truncateToState truncates the file after the state at off
+// and adds a shutdown marker
+func truncateToState(filename string, off uint64) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	size := int64(off) + int64(stateLen)
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+	_, err = f.WriteAt([]byte(shutdown), size)
+	return err
+}
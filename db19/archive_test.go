@@ -0,0 +1,83 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package db19
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	dbfile := filepath.Join(dir, "tmp.db")
+	arcdir := filepath.Join(dir, "archive")
+	db, err := CreateDatabase(dbfile)
+	ck(err)
+	createTbl(db)
+	db.CheckerSync()
+	ck(db.StartArchive(arcdir))
+
+	var times []int64
+	persist := func() {
+		time.Sleep(2 * time.Millisecond) // ensure distinct state times
+		state := db.persist(&execPersistSingle{}, false)
+		times = append(times, ReadState(db.Store, state.Off).Asof)
+	}
+	const nout = 10
+	for range 3 {
+		for range nout {
+			db.CommitMerge(output1(db))
+		}
+		persist()
+	}
+	db.Close()
+
+	// reopen and continue the existing archive
+	db, err = OpenDatabase(dbfile)
+	ck(err)
+	db.CheckerSync()
+	ck(db.StartArchive(arcdir))
+	for range nout {
+		db.CommitMerge(output1(db))
+	}
+	persist()
+	db.Close()
+
+	nrows := func(file string) int {
+		db, err := OpenDatabase(file)
+		ck(err)
+		defer db.Close()
+		return db.NewReadTran().GetInfo("mytable").Nrows
+	}
+	restored := filepath.Join(dir, "restored.db")
+	for i, asof := range times {
+		tr, err := RestoreArchive(arcdir, restored, asof)
+		ck(err)
+		assert.T(t).This(tr).Is(asof)
+		assert.T(t).This(nrows(restored)).Is((i + 1) * nout)
+		ck(CheckDatabase(restored, true))
+	}
+	_, err = RestoreArchive(arcdir, restored, math.MaxInt64)
+	ck(err)
+	assert.T(t).This(nrows(restored)).Is(len(times) * nout)
+	_, err = RestoreArchive(arcdir, restored, times[0]-1000)
+	assert.T(t).This(err.Error()).
+		Is("restore: no state found as of the requested time")
+
+	// a different database does not match the archive
+	other := filepath.Join(dir, "other.db")
+	db, err = CreateDatabase(other)
+	ck(err)
+	createTbl(db)
+	db.PersistClose()
+	db, err = OpenDatabase(other)
+	ck(err)
+	assert.T(t).This(db.StartArchive(arcdir).Error()).
+		Is("archive: does not match database (archive is larger)")
+	db.Close()
+}
@@ -0,0 +1,160 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package db19
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19/meta"
+)
+
+// Change data capture records the inserts, updates, and deletes
+// to the tables listed in the capture table, in the changes table.
+// They are normal tables, created by the application, e.g.
+//
+//	capture (table) key(table)
+//	changes (timestamp, table, action, before, after) key(timestamp)
+//
+// action is "insert", "update", or "delete".
+// before and after are the old and new records, or "".
+//
+// The changes are written when the transaction commits,
+// while commits are serialized by the checker.
+// So the timestamps are unique and in commit order,
+// and consumers can resume from the last timestamp they processed.
+// Since the changes are in the database they are as durable as the data.
+
+const CaptureTable = "capture"
+const ChangesTable = "changes"
+
+type change struct {
+	table  string
+	action string
+	before string // packed SuRecord or ""
+	after  string // packed SuRecord or ""
+}
+
+// captured returns whether changes to a table are recorded.
+// It is cached for the transaction.
+func (t *UpdateTran) captured(table string) bool {
+	if table == ChangesTable {
+		return false
+	}
+	c, ok := t.capture[table]
+	if !ok {
+		c = t.isCaptured(table)
+		if t.capture == nil {
+			t.capture = make(map[string]bool)
+		}
+		t.capture[table] = c
+	}
+	return c
+}
+
+// isCaptured looks up the table in the capture table.
+// It does not register a read with the checker
+// so changing capture does not conflict with updates.
+func (t *UpdateTran) isCaptured(table string) bool {
+	if t.tran.GetInfo(ChangesTable) == nil ||
+		t.tran.GetInfo(CaptureTable) == nil {
+		return false
+	}
+	for i, ix := range t.tran.GetSchema(CaptureTable).Indexes {
+		if ix.Mode == 'k' && slices.Equal(ix.Columns, []string{"table"}) {
+			return t.ReadTran.Lookup(CaptureTable, i,
+				core.Pack(core.SuStr(table))) != nil
+		}
+	}
+	return false
+}
+
+// addChange records a change if the table is captured
+func (t *UpdateTran) addChange(ts *meta.Schema, action string,
+	oldrec, newrec core.Record) {
+	if !t.captured(ts.Table) {
+		return
+	}
+	t.changes = append(t.changes, change{table: ts.Table, action: action,
+		before: packRec(ts, oldrec), after: packRec(ts, newrec)})
+}
+
+func packRec(ts *meta.Schema, rec core.Record) string {
+	if rec == "" {
+		return ""
+	}
+	ob := &core.SuObject{}
+	for i, col := range ts.Columns {
+		if raw := rec.GetRaw(i); col != "-" && raw != "" {
+			ob.Set(core.SuStr(col), core.Unpack(raw))
+		}
+	}
+	return core.PackValue(core.SuRecordFromObject(ob))
+}
+
+// writeChanges adds the changes to the changes table.
+// It is called by commit, from the checker, so commits are serialized.
+// This bypasses the checker, the new keys can't conflict.
+func (t *UpdateTran) writeChanges() {
+	ts := t.getSchema(ChangesTable)
+	ti := t.getRwInfo(ChangesTable)
+	for _, c := range t.changes {
+		var rb core.RecordBuilder
+		for _, col := range ts.Columns {
+			switch col {
+			case "timestamp":
+				rb.Add(Timestamp())
+			case "table":
+				rb.Add(core.SuStr(c.table))
+			case "action":
+				rb.Add(core.SuStr(c.action))
+			case "before":
+				rb.AddRaw(c.before)
+			case "after":
+				rb.AddRaw(c.after)
+			default:
+				rb.AddRaw("")
+			}
+		}
+		t.putRecord(ts, ti, rb.Trim().Build())
+	}
+}
+
+//-------------------------------------------------------------------
+
+// changesNotify is used to wait for new changes
+type changesNotify struct {
+	ch   chan struct{}
+	lock sync.Mutex
+}
+
+// notifyChanges wakes up any WaitChanges
+func (db *Database) notifyChanges() {
+	db.changed.lock.Lock()
+	defer db.changed.lock.Unlock()
+	if db.changed.ch != nil {
+		close(db.changed.ch)
+		db.changed.ch = nil
+	}
+}
+
+// WaitChanges returns a function that waits until there are new changes
+// or the timeout expires. It must be called before checking for changes
+// so it does not miss changes committed in between.
+func (db *Database) WaitChanges() func(timeout time.Duration) {
+	db.changed.lock.Lock()
+	defer db.changed.lock.Unlock()
+	if db.changed.ch == nil {
+		db.changed.ch = make(chan struct{})
+	}
+	ch := db.changed.ch
+	return func(timeout time.Duration) {
+		select {
+		case <-ch:
+		case <-time.After(timeout):
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package db19
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19/meta/schema"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestChanges(t *testing.T) {
+	assert := assert.T(t)
+	StartTimestamps()
+	db := CreateDb(stor.HeapStor(8192))
+	StartConcur(db, 50*time.Millisecond)
+	createTbl(db)
+	db.Create(&schema.Schema{
+		Table:   "other",
+		Columns: []string{"one", "two"},
+		Indexes: []schema.Index{{Mode: 'k', Columns: []string{"one"}}},
+	})
+	db.Create(&schema.Schema{
+		Table:   CaptureTable,
+		Columns: []string{"table"},
+		Indexes: []schema.Index{{Mode: 'k', Columns: []string{"table"}}},
+	})
+	db.Create(&schema.Schema{
+		Table:   ChangesTable,
+		Columns: []string{"timestamp", "table", "action", "before", "after"},
+		Indexes: []schema.Index{{Mode: 'k', Columns: []string{"timestamp"}}},
+	})
+	ut := db.NewUpdateTran()
+	ut.Output(nil, CaptureTable, mkrec("mytable"))
+	ut.Commit()
+
+	ut = db.NewUpdateTran()
+	ut.Output(nil, "mytable", mkrec("a", "1"))
+	ut.Output(nil, "other", mkrec("a", "1")) // not captured
+	ut.Commit()
+
+	ut = db.NewUpdateTran()
+	ut.Output(nil, "mytable", mkrec("b", "2"))
+	ut.Abort() // not recorded
+
+	ut = db.NewUpdateTran()
+	key := ut.getSchema("mytable").Indexes[0].Ixspec.Key(mkrec("a"))
+	off := ut.Lookup("mytable", 0, key).Off
+	off = ut.Update(nil, "mytable", off, mkrec("a", "2"))
+	ut.Delete(nil, "mytable", off)
+	ut.Commit()
+
+	rt := db.NewReadTran()
+	var list []string
+	prev := core.NilDate
+	iter := rt.IndexIter(ChangesTable, 0)
+	for iter.Next(rt); !iter.Eof(); iter.Next(rt) {
+		rec := rt.GetRecord(iter.CurOff())
+		ts := core.Unpack(rec.GetRaw(0)).(core.SuDate)
+		assert.That(ts.Compare(prev) > 0)
+		prev = ts
+		list = append(list, core.Unpack(rec.GetRaw(1)).String()+" "+
+			core.Unpack(rec.GetRaw(2)).String()+" "+
+			core.Unpack(rec.GetRaw(3)).String()+" "+
+			core.Unpack(rec.GetRaw(4)).String())
+	}
+	assert.This(list).Is([]string{
+		`"mytable" "insert" "" [one: 'a', two: '1']`,
+		`"mytable" "update" [one: 'a', two: '1'] [one: 'a', two: '2']`,
+		`"mytable" "delete" [one: 'a', two: '2'] ""`,
+	})
+	assert.This(rt.GetInfo(ChangesTable).Nrows).Is(3)
+	db.MustCheck()
+}
@@ -4,9 +4,11 @@
 package db19
 
 import (
+	"cmp"
 	"log"
 	"math"
 	"math/rand/v2"
+	"slices"
 	"strconv"
 	"sync/atomic"
 
@@ -92,6 +94,7 @@ type CkTran struct {
 	// birth is used by tick to abort long transactions
 	birth        int
 	readCount    int
+	writeCount   int
 	hasUpdates   bool
 	readConflict string
 }
@@ -339,6 +342,7 @@ func (ck *Check) gotUpdate(t *CkTran) bool {
 		}
 		t.hasUpdates = true
 	}
+	t.writeCount++
 	return true
 }
 
@@ -573,6 +577,13 @@ func (ck *Check) commit(ut *UpdateTran) []string {
 	if !ok {
 		return nil // it's gone, presumably aborted
 	}
+	commitTables := ut.commitTables()
+	for _, table := range commitTables {
+		if t.start < ck.exclusive[table] {
+			ck.Abort(ut.ct, "conflict with exclusive ("+table+")")
+			return nil
+		}
+	}
 	t.end = ck.next()
 	if t.start == ck.oldest {
 		ck.oldest = math.MaxInt // need to find the new oldest
@@ -583,6 +594,11 @@ func (ck *Check) commit(ut *UpdateTran) []string {
 		assert.That(ut.ct.readConflict == "")
 		ck.cmtdTran[tn] = t
 		tw = ck.commitByTable(t)
+		for _, table := range commitTables {
+			if !slices.Contains(tw, table) {
+				tw = append(tw, table) // written by UpdateTran.commit
+			}
+		}
 	} else {
 		traceln(t, "commit with no updates")
 		ck.removeByTable(t)
@@ -689,6 +705,42 @@ func (ck *Check) Transactions() []int {
 	return trans
 }
 
+// TranInfo is the state of an active update transaction
+// for the transactions system table
+type TranInfo struct {
+	Tables []string
+	Tran   int
+	Age    int // seconds
+	Reads  int
+	Writes int
+}
+
+// TranInfo returns the state of the active update transactions
+// ordered by start
+func (ck *Check) TranInfo() []TranInfo {
+	trans := make([]TranInfo, 0, len(ck.actvTran))
+	for _, t := range ck.actvTran {
+		trans = append(trans, TranInfo{Tran: t.start, Age: ck.clock - t.birth,
+			Reads: t.readCount, Writes: t.writeCount,
+			Tables: slices.Clone(t.tables)})
+	}
+	slices.SortFunc(trans,
+		func(x, y TranInfo) int { return cmp.Compare(x.Tran, y.Tran) })
+	return trans
+}
+
+// Exclusive returns the tables that are currently exclusive, ordered by name
+func (ck *Check) Exclusive() []string {
+	list := make([]string, 0, len(ck.exclusive))
+	for table, end := range ck.exclusive {
+		if end == math.MaxInt {
+			list = append(list, table)
+		}
+	}
+	slices.Sort(list)
+	return list
+}
+
 // Final returns the count of committed transactions overlapping with outstanding
 func (ck *Check) Final() int {
 	return len(ck.cmtdTran)
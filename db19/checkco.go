@@ -101,6 +101,14 @@ type ckTrans struct {
 	ret chan []int
 }
 
+type ckTranInfo struct {
+	ret chan []TranInfo
+}
+
+type ckExclusive struct {
+	ret chan []string
+}
+
 type ckFinal struct {
 	ret chan int
 }
@@ -247,6 +255,18 @@ func (ck *CheckCo) Transactions() []int {
 	return <-ret
 }
 
+func (ck *CheckCo) TranInfo() []TranInfo {
+	ret := make(chan []TranInfo, 1)
+	ck.pq.Put(lowPriority, 0, &ckTranInfo{ret: ret})
+	return <-ret
+}
+
+func (ck *CheckCo) Exclusive() []string {
+	ret := make(chan []string, 1)
+	ck.pq.Put(lowPriority, 0, &ckExclusive{ret: ret})
+	return <-ret
+}
+
 func (ck *CheckCo) Final() int {
 	ret := make(chan int, 1)
 	ck.pq.Put(lowPriority, 0, &ckFinal{ret: ret})
@@ -360,6 +380,10 @@ func (ck *Check) dispatch(msg any, mergeChan chan todo) {
 		msg.ret <- state.(*DbState)
 	case *ckTrans:
 		msg.ret <- ck.Transactions()
+	case *ckTranInfo:
+		msg.ret <- ck.TranInfo()
+	case *ckExclusive:
+		msg.ret <- ck.Exclusive()
 	case *ckFinal:
 		msg.ret <- ck.Final()
 	case *ckTick:
@@ -390,6 +414,8 @@ type Checker interface {
 	Persist() *DbState
 	Stop()
 	Transactions() []int
+	TranInfo() []TranInfo
+	Exclusive() []string
 	Final() int
 	AddExclusive(table string) bool
 	EndExclusive(table string)
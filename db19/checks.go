@@ -0,0 +1,164 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package db19
+
+import (
+	"slices"
+
+	"github.com/apmckinlay/gsuneido/compile"
+	"github.com/apmckinlay/gsuneido/compile/ast"
+	tok "github.com/apmckinlay/gsuneido/compile/tokens"
+	"github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19/meta"
+	"github.com/apmckinlay/gsuneido/db19/meta/schema"
+)
+
+// Check constraints are declared in the schema with required(columns)
+// and check(expression). They are stored in meta (see meta/checks.go)
+// and enforced by Output and Update.
+// Check expressions are query expressions on the columns of the table.
+// They must evaluate to true.
+// Required columns must not be empty.
+
+// constraints are the compiled check constraints for a table
+type constraints struct {
+	table    string
+	required []int // field indexes
+	srcs     []string
+	exprs    []ast.Expr
+	hdr      *core.Header
+}
+
+func newConstraints(ts *meta.Schema, required, checks []string) *constraints {
+	if len(required) == 0 && len(checks) == 0 {
+		return nil
+	}
+	c := &constraints{table: ts.Table, srcs: checks,
+		hdr: core.SimpleHeader(ts.Columns)}
+	for _, col := range required {
+		c.required = append(c.required, slices.Index(ts.Columns, col))
+	}
+	for _, src := range checks {
+		c.exprs = append(c.exprs, compileCheck(ts, src))
+	}
+	return c
+}
+
+// compileCheck parses a check expression.
+// It panics if the expression is invalid
+// or uses columns that are not in the table.
+func compileCheck(ts *meta.Schema, src string) ast.Expr {
+	p := compile.QueryParser(src)
+	p.EqToIs = true
+	expr := p.Expression()
+	if p.Token != tok.Eof {
+		p.Error("invalid check: " + src)
+	}
+	for _, col := range expr.Columns() {
+		if !slices.Contains(ts.Columns, col) {
+			panic("check: nonexistent column: " + col + " in " + src)
+		}
+	}
+	return expr
+}
+
+// check panics if a record does not satisfy the constraints
+func (c *constraints) check(th *core.Thread, rec core.Record) {
+	for _, fld := range c.required {
+		if rec.GetRaw(fld) == "" {
+			panic("empty required column: " + c.hdr.Fields[0][fld] +
+				" in " + c.table)
+		}
+	}
+	if len(c.exprs) == 0 {
+		return
+	}
+	if th == nil {
+		th = &core.Thread{}
+	}
+	ctx := &ast.RowContext{Th: th, Hdr: c.hdr,
+		Row: core.Row{core.DbRec{Record: rec}}}
+	for i, expr := range c.exprs {
+		if expr.Eval(ctx) != core.True {
+			panic("failed check: " + c.srcs[i] + " in " + c.table)
+		}
+	}
+}
+
+// checkRecord panics if a record does not satisfy the table's constraints.
+// The compiled constraints are cached for the transaction.
+func (t *UpdateTran) checkRecord(th *core.Thread, ts *meta.Schema,
+	rec core.Record) {
+	c, ok := t.checks[ts.Table]
+	if !ok {
+		required, checks := t.meta.GetChecks(ts.Table)
+		c = newConstraints(ts, required, checks)
+		if t.checks == nil {
+			t.checks = make(map[string]*constraints)
+		}
+		t.checks[ts.Table] = c
+	}
+	if c != nil {
+		c.check(th, rec)
+	}
+}
+
+// validateChecks compiles the new constraints in a schema
+// and checks that the existing records satisfy them.
+// It is used by Ensure and AlterCreate, while the table is exclusive.
+func (db *Database) validateChecks(sch *schema.Schema) {
+	if len(sch.Required) == 0 && len(sch.Checks) == 0 {
+		return
+	}
+	rt := db.NewReadTran()
+	ts := rt.meta.GetRoSchema(sch.Table)
+	if ts == nil {
+		return // new table, validated by create
+	}
+	required, checks := rt.meta.GetChecks(sch.Table)
+	tsNew := *ts // copy
+	for _, col := range sch.Columns {
+		if !slices.Contains(tsNew.Columns, col) {
+			tsNew.Columns = append(slices.Clip(tsNew.Columns), col)
+		}
+	}
+	var newReq, newChecks []string
+	for _, col := range sch.Required {
+		if !slices.Contains(required, col) {
+			if !slices.Contains(tsNew.Columns, col) {
+				panic("required: nonexistent column: " + col)
+			}
+			newReq = append(newReq, col)
+		}
+	}
+	for _, src := range sch.Checks {
+		if !slices.Contains(checks, src) {
+			newChecks = append(newChecks, src)
+		}
+	}
+	c := newConstraints(&tsNew, newReq, newChecks)
+	if c == nil || rt.meta.GetRoInfo(sch.Table).Nrows == 0 {
+		return
+	}
+	th := &core.Thread{}
+	iter := rt.IndexIter(sch.Table, 0)
+	for iter.Next(rt); !iter.Eof(); iter.Next(rt) {
+		c.check(th, rt.GetRecord(iter.CurOff()))
+	}
+}
+
+// checksUse panics if columns are used by check expressions,
+// since the expressions are not updated.
+func (db *Database) checksUse(table string, cols []string, action string) {
+	_, checks := db.GetState().Meta.GetChecks(table)
+	for _, src := range checks {
+		p := compile.QueryParser(src)
+		for _, col := range p.Expression().Columns() {
+			if slices.Contains(cols, col) {
+				panic("can't " + action + " column used by check: " +
+					col + " in " + src)
+			}
+		}
+	}
+}
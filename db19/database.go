@@ -47,6 +47,18 @@ type Database struct {
 
 	closed    atomic.Bool
 	corrupted atomic.Bool
+
+	// persistEnd is the end of the most recent persisted state
+	persistEnd atomic.Uint64
+	// shipEnd is the end of the previous persisted state,
+	// the limit for archiving and replication
+	shipEnd atomic.Uint64
+	// archive is set by StartArchive for continuous backup
+	archive *archive
+	// replica is set by OpenReplica. A replica is read-only.
+	replica bool
+	// changed is used to wait for captured changes (see changes.go)
+	changed changesNotify
 }
 
 const magic = "gsndo004"
@@ -76,6 +88,7 @@ func CreateDb(store *stor.Stor) *Database {
 	copy(buf, magic)
 	db.Store = store
 	db.mode = stor.Create
+	db.setShipEnd(store.Size())
 	return &db
 }
 
@@ -123,6 +136,7 @@ func OpenDbStor(store *stor.Stor, mode stor.Mode, check bool) (db *Database, err
 	}()
 	state := ReadState(db.Store, size-uint64(stateLen))
 	db.state.set(state)
+	db.setShipEnd(store.Size())
 	if check {
 		if err := db.QuickCheck(); err != nil {
 			return nil, err
@@ -131,6 +145,13 @@ func OpenDbStor(store *stor.Stor, mode stor.Mode, check bool) (db *Database, err
 	return db, nil
 }
 
+// setShipEnd is used when there is no concurrent activity
+// so everything up to end can be shipped
+func (db *Database) setShipEnd(end uint64) {
+	db.persistEnd.Store(end)
+	db.shipEnd.Store(end)
+}
+
 // version checks the version of the database
 func version(store *stor.Stor) {
 	buf := store.Data(0)
@@ -165,7 +186,7 @@ func (db *Database) AddNewTable(ts *meta.Schema, ti *meta.Info) {
 		if state.Meta.GetRoSchema(ts.Table) != nil {
 			panic("duplicate table")
 		}
-		state.Meta = state.Meta.Put(ts, ti)
+		state.Meta = putTable(state.Meta, ts, ti)
 	})
 }
 
@@ -173,10 +194,17 @@ func (db *Database) AddNewTable(ts *meta.Schema, ti *meta.Info) {
 // If the table already exists it is replaced.
 func (db *Database) OverwriteTable(ts *meta.Schema, ti *meta.Info) {
 	db.UpdateState(func(state *DbState) {
-		state.Meta = state.Meta.Put(ts, ti)
+		state.Meta = putTable(state.Meta, ts, ti)
 	})
 }
 
+// putTable stores the check constraints (e.g. from load) separately
+func putTable(m *meta.Meta, ts *meta.Schema, ti *meta.Info) *meta.Meta {
+	required, checks := ts.Required, ts.Checks
+	ts.Required, ts.Checks = nil, nil
+	return m.Put(ts, ti).PutChecks(ts.Table, required, checks)
+}
+
 // CheckAllFkeys is used after loading an entire database.
 func (db *Database) CheckAllFkeys() {
 	state := db.GetState()
@@ -208,6 +236,9 @@ func (db *Database) lockSchema() {
 	if db.IsCorrupted() {
 		panic("database is locked")
 	}
+	if db.replica {
+		panic(replicaReadOnly)
+	}
 	if !db.schemaLock.CompareAndSwap(false, true) {
 		panic("concurrent schema modifications are not allowed")
 	}
@@ -220,6 +251,7 @@ func (db *Database) unlockSchema() {
 func (db *Database) create(state *DbState, schema *schema.Schema) {
 	schema.Check()
 	ts := &meta.Schema{Schema: *schema}
+	newConstraints(ts, schema.Required, schema.Checks) // validate
 	ts.SetupIndexes()
 	indexes := db.createIndexes(ts.Indexes)
 	ti := meta.NewInfo(schema.Table, indexes, 0, 0)
@@ -252,6 +284,7 @@ func (db *Database) Ensure(sch *schema.Schema) {
 	handled := false
 	var newIdxs []schema.Index
 	db.RunExclusive(sch.Table, func() {
+		db.validateChecks(sch)
 		db.UpdateState(func(state *DbState) {
 			ts := state.Meta.GetRoSchema(sch.Table)
 			if ts == nil { // table doesn't exist
@@ -305,6 +338,11 @@ func (db *Database) schemaSubset(schema *schema.Schema) bool {
 		!set.Subset(ts.Derived, schema.Derived) {
 		return false
 	}
+	required, checks := state.Meta.GetChecks(schema.Table)
+	if !set.Subset(required, schema.Required) ||
+		!set.Subset(checks, schema.Checks) {
+		return false
+	}
 	for i := range schema.Indexes {
 		ix := ts.FindIndex(schema.Indexes[i].Columns)
 		if ix == nil {
@@ -472,10 +510,14 @@ func (db *Database) Drop(table string) error {
 func (db *Database) AlterRename(table string, from, to []string) bool {
 	db.lockSchema()
 	defer db.unlockSchema()
+	db.checksUse(table, from, "rename")
 	result := false
 	db.RunExclusive(table, func() {
 		db.UpdateState(func(state *DbState) {
 			if m := state.Meta.AlterRename(table, from, to); m != nil {
+				if historyKey(state.Meta, table) >= 0 {
+					m = renameHistory(m, table, from, to)
+				}
 				state.Meta = m
 				result = true
 			}
@@ -495,6 +537,7 @@ func (db *Database) AlterCreate(sch *schema.Schema) {
 			panic(e)
 		}
 	}()
+	db.validateChecks(sch)
 	// buildIndexes is potentially slow (if there's a lot of data)
 	// so we don't want to do it inside UpdateState
 	ovs := db.buildIndexes(sch.Table, sch.Columns, sch.Indexes)
@@ -517,6 +560,7 @@ func (db *Database) AlterCreate(sch *schema.Schema) {
 func (db *Database) AlterDrop(schema *schema.Schema) bool {
 	db.lockSchema()
 	defer db.unlockSchema()
+	db.checksUse(schema.Table, schema.Columns, "drop")
 	result := false
 	db.RunExclusive(schema.Table, func() {
 		db.UpdateState(func(state *DbState) {
@@ -555,7 +599,12 @@ func (db *Database) Schema(table string) string {
 	if ts == nil {
 		return ""
 	}
-	return ts.Schema.String2()
+	sch := ts.Schema // copy
+	sch.Required, sch.Checks = state.Meta.GetChecks(table)
+	if historyKey(state.Meta, table) >= 0 {
+		return sch.String2() + " history"
+	}
+	return sch.String2()
 }
 
 func (db *Database) Size() uint64 {
@@ -573,6 +622,26 @@ func (db *Database) Transactions() []int {
 	return db.ck.Transactions()
 }
 
+// TranInfo returns the state of the active update transactions.
+// It returns nil if corrupted.
+func (db *Database) TranInfo() []TranInfo {
+	db.ckOpen()
+	if db.IsCorrupted() {
+		return nil
+	}
+	return db.ck.TranInfo()
+}
+
+// Exclusive returns the tables that are currently exclusive
+// e.g. for load table or adding an index
+func (db *Database) Exclusive() []string {
+	db.ckOpen()
+	if db.IsCorrupted() {
+		return nil
+	}
+	return db.ck.Exclusive()
+}
+
 func (db *Database) Final() int {
 	db.ckOpen()
 	if db.IsCorrupted() {
@@ -624,6 +693,9 @@ func (db *Database) close(unmap bool) {
 	if db.ck != nil {
 		db.ck.Stop() // writes final state
 	}
+	if db.archive != nil {
+		db.archive.close(db.Store, db.persistEnd.Load())
+	}
 	if db.mode != stor.Read && !db.IsCorrupted() && db.readTail() != shutdown {
 		_, buf := db.Store.Alloc(tailSize)
 		copy(buf, shutdown)
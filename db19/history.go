@@ -0,0 +1,303 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package db19
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19/meta"
+	"github.com/apmckinlay/gsuneido/db19/meta/schema"
+	"github.com/apmckinlay/gsuneido/util/cksum"
+)
+
+// History tables keep every version of the records in a table.
+// A table has history if there is a table with the same name plus _history
+// e.g. orders_history, with the columns of the table plus valid_from and
+// valid_to, and a key of the table's key plus valid_to.
+// valid_from and valid_to are the commit timestamps of the transactions
+// that created and ended the version.
+// valid_to is "" for the current version.
+// valid_from is "" for records that existed before the history was created.
+//
+// History tables are created by CreateHistory, e.g. from the history option
+// on create or ensure. The versions are written when the transaction commits,
+// while commits are serialized by the checker, like changes (see changes.go).
+// All the versions from one transaction have the same timestamp.
+// Transactions can not output, update, or delete in history tables,
+// only writeHistory and CreateHistory write them.
+//
+// Renaming columns also renames them in the history table (see AlterRename).
+// Dropping or renaming a table does not drop or rename its history.
+
+const HistorySuffix = "_history"
+
+const validFrom = "valid_from"
+const validTo = "valid_to"
+
+// historyBatch limits the size of the transactions used by CreateHistory
+const historyBatch = 5000
+
+type histVersion struct {
+	table  string
+	oldrec core.Record
+	newrec core.Record
+}
+
+// HistorySchema returns the schema for the history table for a table
+func HistorySchema(ts *schema.Schema) *schema.Schema {
+	cols := make([]string, 0, len(ts.Columns)+2)
+	for _, col := range ts.Columns {
+		if col != "-" {
+			cols = append(cols, col)
+		}
+	}
+	cols = append(cols, validFrom, validTo)
+	var key []string
+	for _, ix := range ts.Indexes {
+		if ix.Mode == 'k' {
+			key = ix.Columns
+			break
+		}
+	}
+	return &schema.Schema{
+		Table:   ts.Table + HistorySuffix,
+		Columns: cols,
+		Indexes: []schema.Index{{Mode: 'k',
+			Columns: append(slices.Clip(key), validTo)}},
+	}
+}
+
+// HasHistory returns whether a table has a history table
+func (db *Database) HasHistory(table string) bool {
+	return historyKey(db.GetState().Meta, table) >= 0
+}
+
+// historyKey returns the index of the history table key
+// that contains valid_to, or -1 if the table does not have history
+func historyKey(m *meta.Meta, table string) int {
+	hs := m.GetRoSchema(table + HistorySuffix)
+	if hs == nil || !slices.Contains(hs.Columns, validFrom) ||
+		!slices.Contains(hs.Columns, validTo) {
+		return -1
+	}
+	for i, ix := range hs.Indexes {
+		if ix.Mode == 'k' && slices.Contains(ix.Columns, validTo) {
+			return i
+		}
+	}
+	return -1
+}
+
+// renameHistory renames the columns in the history table for a table.
+// Columns that are not in the history (e.g. rules) are skipped.
+// It panics if a column is renamed to valid_from or valid_to.
+func renameHistory(m *meta.Meta, table string, from, to []string) *meta.Meta {
+	hist := table + HistorySuffix
+	hs := m.GetRoSchema(hist)
+	var hfrom, hto []string
+	for i, f := range from {
+		if slices.Contains(hs.Columns, f) {
+			hfrom = append(hfrom, f)
+			hto = append(hto, to[i])
+		}
+	}
+	if len(hfrom) == 0 {
+		return m
+	}
+	return m.AlterRename(hist, hfrom, hto)
+}
+
+// CreateHistory creates the history table for a table, if it doesn't exist,
+// and adds the existing records as current versions.
+// The table is exclusive while this is done
+// so updates can't be missed.
+func (db *Database) CreateHistory(table string) {
+	rt := db.NewReadTran()
+	ts := rt.meta.GetRoSchema(table)
+	if ts == nil {
+		panic("history: nonexistent table: " + table)
+	}
+	if historyKey(rt.meta, table) >= 0 {
+		return // already has history
+	}
+	if rt.meta.GetRoSchema(table+HistorySuffix) != nil {
+		panic("history: invalid history table: " + table + HistorySuffix)
+	}
+	db.AddExclusive(table)
+	defer db.EndExclusive(table)
+	db.Create(HistorySchema(&ts.Schema))
+
+	rt = db.NewReadTran() // after exclusive so no one else is updating
+	hist := table + HistorySuffix
+	hs := rt.getSchema(hist)
+	ts = rt.getSchema(table)
+	var ut *UpdateTran
+	n := 0
+	iter := rt.IndexIter(table, 0)
+	for iter.Next(rt); !iter.Eof(); iter.Next(rt) {
+		if ut == nil {
+			ut = db.NewUpdateTran()
+			ut.histWriter = true
+		}
+		rec := rt.GetRecord(iter.CurOff())
+		ut.Output(nil, hist, versionRec(hs, ts, rec, "", ""))
+		if n++; n >= historyBatch {
+			ut.mustComplete()
+			ut = nil
+			n = 0
+		}
+	}
+	if ut != nil {
+		ut.mustComplete()
+	}
+}
+
+func (t *UpdateTran) mustComplete() {
+	if err := t.Complete(); err != "" {
+		panic("history: " + err)
+	}
+}
+
+// hasHistory returns whether a table has history.
+// It is cached for the transaction.
+func (t *UpdateTran) hasHistory(table string) bool {
+	if strings.HasSuffix(table, HistorySuffix) {
+		return false
+	}
+	h, ok := t.history[table]
+	if !ok {
+		h = historyKey(t.meta, table) >= 0
+		if t.history == nil {
+			t.history = make(map[string]bool)
+		}
+		t.history[table] = h
+	}
+	return h
+}
+
+// checkHistoryWrite panics if table is a history table
+// unless the transaction is from CreateHistory
+func (t *UpdateTran) checkHistoryWrite(table string) {
+	if t.histWriter {
+		return
+	}
+	if base, ok := strings.CutSuffix(table, HistorySuffix); ok &&
+		t.hasHistory(base) {
+		panic("history: can't modify " + table)
+	}
+}
+
+// addVersion records a change if the table has history
+func (t *UpdateTran) addVersion(table string, oldrec, newrec core.Record) {
+	if t.hasHistory(table) {
+		t.versions = append(t.versions,
+			histVersion{table: table, oldrec: oldrec, newrec: newrec})
+	}
+}
+
+// historyTables returns the history tables written by writeHistory
+func (t *UpdateTran) historyTables() []string {
+	var tables []string
+	for _, v := range t.versions {
+		if hist := v.table + HistorySuffix; !slices.Contains(tables, hist) {
+			tables = append(tables, hist)
+		}
+	}
+	return tables
+}
+
+// writeHistory ends the old versions and adds the new versions.
+// It is called by commit, from the checker, so commits are serialized.
+// Like writeChanges, this bypasses the checker.
+// Concurrent updates to the same records are prevented
+// by the checker for the base table.
+func (t *UpdateTran) writeHistory() {
+	ts := core.Pack(Timestamp())
+	for _, v := range t.versions {
+		hist := v.table + HistorySuffix
+		hs := t.getSchema(hist)
+		hi := t.getRwInfo(hist)
+		bs := t.getSchema(v.table)
+		if v.oldrec != "" {
+			t.endVersion(hs, hi, bs, v.oldrec, ts)
+		}
+		if v.newrec != "" {
+			t.putRecord(hs, hi, versionRec(hs, bs, v.newrec, ts, ""))
+		}
+	}
+}
+
+// endVersion sets valid_to on the current version of a record
+func (t *UpdateTran) endVersion(hs *meta.Schema, hi *meta.Info,
+	bs *meta.Schema, rec core.Record, ts string) {
+	ik := historyKey(t.meta, bs.Table)
+	key := hs.Indexes[ik].Ixspec.Key(versionRec(hs, bs, rec, "", ""))
+	off := hi.Indexes[ik].Lookup(key)
+	if off == 0 {
+		// shouldn't happen since CreateHistory adds the existing records
+		t.putRecord(hs, hi, versionRec(hs, bs, rec, "", ts))
+		return
+	}
+	cur := t.GetRecord(off)
+	t.removeRecord(hs, hi, cur, off)
+	from := slices.Index(hs.Columns, validFrom)
+	if cur.GetRaw(from) == ts {
+		return // created by this transaction so not a version
+	}
+	var rb core.RecordBuilder
+	for i, col := range hs.Columns {
+		if col == validTo {
+			rb.AddRaw(ts)
+		} else {
+			rb.AddRaw(cur.GetRaw(i))
+		}
+	}
+	t.putRecord(hs, hi, rb.Trim().Build())
+}
+
+// versionRec converts a record from the table to a history record
+func versionRec(hs, bs *meta.Schema, rec core.Record, from, to string) core.Record {
+	var rb core.RecordBuilder
+	for _, col := range hs.Columns {
+		switch col {
+		case validFrom:
+			rb.AddRaw(from)
+		case validTo:
+			rb.AddRaw(to)
+		default:
+			if i := slices.Index(bs.Columns, col); i >= 0 {
+				rb.AddRaw(rec.GetRaw(i))
+			} else {
+				rb.AddRaw("")
+			}
+		}
+	}
+	return rb.Trim().Build()
+}
+
+// putRecord adds a record and its index entries without the checker.
+// It is used at commit by writeChanges and writeHistory.
+func (t *UpdateTran) putRecord(ts *meta.Schema, ti *meta.Info, rec core.Record) {
+	n := rec.Len()
+	off, buf := t.db.Store.Alloc(n + cksum.Len)
+	copy(buf, rec[:n])
+	cksum.Update(buf)
+	for i := range ts.Indexes {
+		ti.Indexes[i].Insert(ts.Indexes[i].Ixspec.Key(rec), off)
+	}
+	ti.Nrows++
+	ti.Size += int64(n)
+}
+
+// removeRecord removes the index entries for a record without the checker
+func (t *UpdateTran) removeRecord(ts *meta.Schema, ti *meta.Info,
+	rec core.Record, off uint64) {
+	for i := range ts.Indexes {
+		ti.Indexes[i].Delete(ts.Indexes[i].Ixspec.Key(rec), off)
+	}
+	ti.Nrows--
+	ti.Size -= int64(rec.Len())
+}
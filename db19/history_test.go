@@ -0,0 +1,129 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package db19
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19/meta/schema"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestHistory(t *testing.T) {
+	assert := assert.T(t)
+	StartTimestamps()
+	db := CreateDb(stor.HeapStor(8192))
+	StartConcur(db, 50*time.Millisecond)
+	createTbl(db)
+	ut := db.NewUpdateTran()
+	ut.Output(nil, "mytable", mkrec("a", "1")) // before history
+	ut.Commit()
+
+	db.CreateHistory("mytable")
+	assert.That(db.HasHistory("mytable"))
+	assert.This(db.Schema("mytable_history")).
+		Is("mytable_history (one,two,valid_from,valid_to) key(one,valid_to)")
+	assert.This(func() { db.CreateHistory("nonex") }).
+		Panics("nonexistent table")
+	db.CreateHistory("mytable") // already has history
+
+	ut = db.NewUpdateTran()
+	ut.Output(nil, "mytable", mkrec("b", "1"))
+	key := ut.getSchema("mytable").Indexes[0].Ixspec.Key(mkrec("a"))
+	off := ut.Lookup("mytable", 0, key).Off
+	ut.Update(nil, "mytable", off, mkrec("a", "2"))
+	ut.Commit()
+
+	ut = db.NewUpdateTran()
+	off = ut.Lookup("mytable", 0, key).Off
+	off = ut.Update(nil, "mytable", off, mkrec("a", "3"))
+	ut.Delete(nil, "mytable", off)
+	ut.Output(nil, "mytable", mkrec("c", "1"))
+	ut.Update(nil, "mytable",
+		ut.Lookup("mytable", 0, ut.getSchema("mytable").Indexes[0].
+			Ixspec.Key(mkrec("c"))).Off, mkrec("c", "2")) // same transaction
+	ut.Commit()
+
+	rt := db.NewReadTran()
+	str := func(raw string) string {
+		if raw == "" {
+			return "-"
+		}
+		if d, ok := core.Unpack(raw).(core.SuDate); ok {
+			return d.String()[:1] // just #
+		}
+		return core.Unpack(raw).String()
+	}
+	var list []string
+	var dates []core.SuDate
+	iter := rt.IndexIter("mytable_history", 0)
+	for iter.Next(rt); !iter.Eof(); iter.Next(rt) {
+		rec := rt.GetRecord(iter.CurOff())
+		list = append(list, str(rec.GetRaw(0))+" "+str(rec.GetRaw(1))+" "+
+			str(rec.GetRaw(2))+" "+str(rec.GetRaw(3)))
+		for i := 2; i <= 3; i++ {
+			if d, ok := core.Unpack(rec.GetRaw(i)).(core.SuDate); ok {
+				dates = append(dates, d)
+			}
+		}
+	}
+	assert.This(list).Is([]string{
+		`'a' '1' - #`, // from before history
+		`'a' '2' # #`,
+		`'b' '1' # -`,
+		`'c' '2' # -`,
+	})
+	// a: valid_to of 1 is valid_from of 2
+	assert.This(dates[0]).Is(dates[1])
+	assert.That(dates[1].Compare(dates[2]) < 0)
+	assert.This(rt.GetInfo("mytable_history").Nrows).Is(4)
+
+	// transactions can't modify the history
+	ut = db.NewUpdateTran()
+	assert.This(func() {
+		ut.Output(nil, "mytable_history", mkrec("d", "1", "", ""))
+	}).Panics("history: can't modify mytable_history")
+	hoff := ut.Lookup("mytable_history", 0,
+		ut.getSchema("mytable_history").Indexes[0].Ixspec.
+			Key(mkrec("b", "1", "", ""))).Off
+	assert.That(hoff != 0)
+	assert.This(func() {
+		ut.Update(nil, "mytable_history", hoff, mkrec("b", "2", "", ""))
+	}).Panics("history: can't modify mytable_history")
+	assert.This(func() { ut.Delete(nil, "mytable_history", hoff) }).
+		Panics("history: can't modify mytable_history")
+	ut.Abort()
+	db.MustCheck()
+}
+
+func TestHistoryRename(t *testing.T) {
+	assert := assert.T(t)
+	db := CreateDb(stor.HeapStor(8192))
+	StartConcur(db, 50*time.Millisecond)
+	createTbl(db)
+	db.CreateHistory("mytable")
+	assert.That(db.AlterRename("mytable", []string{"two"}, []string{"three"}))
+	assert.This(db.Schema("mytable")).
+		Is("mytable (one,three) key(one) history")
+	assert.This(db.Schema("mytable_history")).
+		Is("mytable_history (one,three,valid_from,valid_to) key(one,valid_to)")
+	assert.This(func() {
+		db.AlterRename("mytable", []string{"three"}, []string{"valid_to"})
+	}).Panics("can't rename to existing column")
+	assert.This(db.Schema("mytable")).
+		Is("mytable (one,three) key(one) history")
+}
+
+func TestHistorySchema(t *testing.T) {
+	ts := &schema.Schema{Table: "tbl",
+		Columns: []string{"a", "-", "b"},
+		Indexes: []schema.Index{
+			{Mode: 'i', Columns: []string{"b"}},
+			{Mode: 'k', Columns: []string{"a"}}}}
+	assert.T(t).This(HistorySchema(ts).String()).
+		Is("tbl_history (a,b,valid_from,valid_to) key(a,valid_to)")
+}
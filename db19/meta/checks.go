@@ -0,0 +1,107 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package meta
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/apmckinlay/gsuneido/db19/meta/schema"
+	"github.com/apmckinlay/gsuneido/util/set"
+)
+
+// Note: check constraints are stored with the name in Schema.Table
+// prefixed by '!'. Schema.Columns[0] is the number of required columns,
+// followed by the required columns and then the check expressions.
+// Like stats, this means they are persisted along with the rest of the schema
+// without changing the format.
+// The checks are compiled and enforced by db19 (see db19/checks.go)
+
+func (m *Meta) newSchemaChecks(table string, required, checks []string) *Schema {
+	cols := make([]string, 0, 1+len(required)+len(checks))
+	cols = append(cols, strconv.Itoa(len(required)))
+	cols = append(cols, required...)
+	cols = append(cols, checks...)
+	return &Schema{Schema: schema.Schema{Table: "!" + table, Columns: cols}}
+}
+
+func (ts *Schema) isChecks() bool {
+	return !ts.IsTomb() && ts.Table[0] == '!'
+}
+
+// GetChecks returns the required columns and check expressions for a table.
+// The results are shared and must not be modified.
+func (m *Meta) GetChecks(table string) (required, checks []string) {
+	ts, ok := m.schema.Get("!" + table)
+	if !ok || !ts.isChecks() {
+		return nil, nil
+	}
+	nreq, _ := strconv.Atoi(ts.Columns[0])
+	return ts.Columns[1 : 1+nreq], ts.Columns[1+nreq:]
+}
+
+// PutChecks replaces the check constraints for a table.
+// It is used by load and compact.
+func (m *Meta) PutChecks(table string, required, checks []string) *Meta {
+	mu := newMetaUpdate(m)
+	m.putChecks(mu, table, required, checks)
+	return mu.freeze()
+}
+
+// putChecks replaces the check constraints for a table,
+// adding a tombstone if there are none.
+func (m *Meta) putChecks(mu *metaUpdate, table string, required, checks []string) {
+	if len(required) == 0 && len(checks) == 0 {
+		m.dropChecks(mu, table)
+		return
+	}
+	mu.putSchema(m.newSchemaChecks(table, required, checks))
+}
+
+// addChecks adds the check constraints from create, ensure, or alter create
+func (m *Meta) addChecks(mu *metaUpdate, ts *Schema, ac *schema.Schema) {
+	if len(ac.Required) == 0 && len(ac.Checks) == 0 {
+		return
+	}
+	for _, col := range ac.Required {
+		if !slices.Contains(ts.Columns, col) {
+			panic("required: nonexistent column: " + col)
+		}
+	}
+	required, checks := m.GetChecks(ts.Table)
+	newReq := set.Union(required, ac.Required)
+	newChecks := set.Union(checks, ac.Checks)
+	if len(newReq) > len(required) || len(newChecks) > len(checks) {
+		m.putChecks(mu, ts.Table, newReq, newChecks)
+	}
+}
+
+// removeChecks removes the check constraints from alter drop
+// and removes dropped columns from required
+func (m *Meta) removeChecks(mu *metaUpdate, ad *schema.Schema) {
+	required, checks := m.GetChecks(ad.Table)
+	for _, col := range ad.Required {
+		if !slices.Contains(required, col) {
+			panic("can't drop nonexistent required: " + col)
+		}
+	}
+	for _, check := range ad.Checks {
+		if !slices.Contains(checks, check) {
+			panic("can't drop nonexistent check: " + check)
+		}
+	}
+	newReq := set.Difference(required, ad.Required)
+	newReq = set.Difference(newReq, ad.Columns)
+	newChecks := set.Difference(checks, ad.Checks)
+	if len(newReq) < len(required) || len(newChecks) < len(checks) {
+		m.putChecks(mu, ad.Table, newReq, newChecks)
+	}
+}
+
+// dropChecks adds a tombstone for the check constraints for a table (if any)
+func (m *Meta) dropChecks(mu *metaUpdate, table string) {
+	if ts, ok := m.schema.Get("!" + table); ok && ts.isChecks() {
+		mu.putSchema(m.newSchemaTomb("!" + table))
+	}
+}
@@ -153,10 +153,12 @@ func (m *Meta) PutNew(ts *Schema, ti *Info, ac *schema.Schema) *Meta {
 	if _, ok := m.info.Get(ti.Table); !ok {
 		ti.created = m.info.Clock
 	}
+	ts.Required, ts.Checks = nil, nil // stored separately
 	mu := newMetaUpdate(m)
 	mu.putSchema(ts)
 	mu.putInfo(ti)
 	m.createFkeys(mu, &ts.Schema, ac)
+	m.addChecks(mu, ts, ac)
 	return mu.freeze()
 }
 
@@ -189,7 +191,8 @@ func (mu *metaUpdate) getSchema(table string) *Schema {
 }
 
 func (mu *metaUpdate) putSchema(ts *Schema) {
-	assert.That(len(ts.Indexes) > 0 || ts.IsTomb())
+	assert.That(len(ts.Indexes) > 0 || ts.IsTomb() || ts.isStats() ||
+		ts.isChecks())
 	if mu.schema == (SchemaHamt{}) {
 		mu.schema = mu.meta.schema.Mutable()
 	}
@@ -308,6 +311,7 @@ func (m *Meta) Ensure(a *schema.Schema, store *stor.Stor) ([]schema.Index, *Meta
 	mu.putSchema(ts)
 	mu.putInfo(ti)
 	m.createFkeys(mu, &ts.Schema, ac)
+	m.addChecks(mu, ts, a)
 	return newIdxs, mu.freeze()
 }
 
@@ -332,6 +336,16 @@ func (m *Meta) RenameTable(from, to string) *Meta {
 	mu.putSchema(&tsNew)
 	mu.putInfo(m.newInfoTomb(from))
 	mu.putInfo(&tiNew)
+	if st := m.GetStats(from); st != nil {
+		m.dropStats(mu, from)
+		stNew := *st // copy
+		stNew.Table = to
+		mu.putSchema(m.newSchemaStats(&stNew))
+	}
+	if required, checks := m.GetChecks(from); required != nil || checks != nil {
+		m.dropChecks(mu, from)
+		mu.putSchema(m.newSchemaChecks(to, required, checks))
+	}
 	m.dropFkeys(mu, &ts.Schema)
 	m.createFkeys(mu, &tsNew.Schema, &tsNew.Schema)
 	return mu.freeze()
@@ -368,6 +382,8 @@ func (m *Meta) Drop(name string) *Meta {
 	} else {
 		mu.putInfo(m.newInfoTomb(name))
 	}
+	m.dropStats(mu, name)
+	m.dropChecks(mu, name)
 	m.dropFkeys(mu, &ts.Schema)
 	return mu.freeze()
 }
@@ -420,6 +436,9 @@ func (m *Meta) AlterRename(table string, from, to []string) *Meta {
 	for _, i := range affectedIdxs {
 		m.renameFkey(mu, &tsNew.Schema, i)
 	}
+	if required, checks := m.GetChecks(table); len(required) > 0 {
+		m.putChecks(mu, table, replace(required, from, to), checks)
+	}
 	return mu.freeze()
 }
 
@@ -513,6 +532,7 @@ func (m *Meta) AlterCreate(ac *schema.Schema, store *stor.Stor) *Meta {
 	mu.putSchema(ts)
 	mu.putInfo(ti)
 	m.createFkeys(mu, &ts.Schema, ac)
+	m.addChecks(mu, ts, ac)
 	return mu.freeze()
 }
 
@@ -722,6 +742,7 @@ func (m *Meta) AlterDrop(ad *schema.Schema) *Meta {
 	mu.putInfo(ti)
 	m.dropFkeys(mu, ad)
 	updateFkeysIIndex(mu, &ts.Schema)
+	m.removeChecks(mu, ad)
 	return mu.freeze()
 }
 
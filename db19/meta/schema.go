@@ -21,6 +21,8 @@ import (
 
 // Note: views are stored with the name in Schema.Table prefixed by '='
 // and the definition in Schema.Columns[0]
+// Column statistics are stored with the name prefixed by '%' (see stats.go)
+// Check constraints are stored with the name prefixed by '!' (see checks.go)
 
 type SchemaHamt = hamt.Hamt[string, *Schema]
 
@@ -32,6 +34,8 @@ type Schema struct {
 	// created is used to avoid tombstones (and persisting them)
 	// for temporary tables (e.g. from tests)
 	created int
+	// stats is the decoded column statistics (only for '%' entries)
+	stats *Stats
 }
 
 func (ts *Schema) Key() string {
@@ -115,6 +119,9 @@ func ReadSchema(_ *stor.Stor, r *stor.Reader) *Schema {
 		}
 		ts.Ixspecs(0)
 	}
+	if ts.isStats() {
+		ts.stats = ts.toStats()
+	}
 	return &ts
 }
 
@@ -301,7 +308,8 @@ func (ts *Schema) isView() bool {
 	return !ts.IsTomb() && ts.Table[0] == '='
 }
 
-// isTable returns true if not a view and not a tombstone
+// isTable returns true if not a view, not stats, not checks,
+// and not a tombstone
 func (ts *Schema) isTable() bool {
-	return !ts.IsTomb() && !ts.isView()
+	return !ts.IsTomb() && !ts.isView() && !ts.isStats() && !ts.isChecks()
 }
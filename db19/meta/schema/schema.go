@@ -24,6 +24,10 @@ type Schema struct {
 	// Derived are the rules (capitalized) and _lower!
 	Derived []string
 	Indexes []Index
+	// Required are the columns that must not be empty
+	Required []string
+	// Checks are expressions that must be true for every record
+	Checks []string
 }
 
 type Index struct {
@@ -61,6 +65,8 @@ const (
 	CascadeUpdates = 1
 	CascadeDeletes = 2
 	Cascade        = CascadeUpdates | CascadeDeletes
+	// SetEmpty clears the referencing columns on delete
+	SetEmpty = 4
 )
 
 func (sc *Schema) String() string {
@@ -110,6 +116,19 @@ func (sc *Schema) string(fktohere, delcols, emptycols bool, firstIdx int) string
 		sb.WriteString(sc.Indexes[i].string(fktohere))
 		sep = " "
 	}
+	if len(sc.Required) > 0 {
+		sb.WriteString(sep)
+		sb.WriteString("required")
+		sb.WriteString(str.Join("(,)", sc.Required))
+		sep = " "
+	}
+	for _, check := range sc.Checks {
+		sb.WriteString(sep)
+		sb.WriteString("check(")
+		sb.WriteString(check)
+		sb.WriteString(")")
+		sep = " "
+	}
 	return sb.String()
 }
 
@@ -128,10 +147,13 @@ func (ix *Index) string(fktohere bool) string {
 		}
 		if ix.Fk.Mode&Cascade != 0 {
 			s += " cascade"
-			if ix.Fk.Mode == CascadeUpdates {
+			if ix.Fk.Mode&Cascade == CascadeUpdates {
 				s += " update"
 			}
 		}
+		if ix.Fk.Mode&SetEmpty != 0 {
+			s += " set empty"
+		}
 	}
 	if fktohere {
 		toHere := make([]string, len(ix.FkToHere))
@@ -0,0 +1,237 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package meta
+
+import (
+	"iter"
+	"sort"
+	"strconv"
+
+	"github.com/apmckinlay/gsuneido/db19/meta/schema"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+)
+
+// Note: column statistics (from Database.Analyze) are stored
+// with the name in Schema.Table prefixed by '%'.
+// Schema.Columns[0] is the number of rows when analyzed
+// and the rest of Schema.Columns are the encoded ColStats.
+// This means they are persisted along with the rest of the schema
+// without changing the format.
+
+// Stats are the column statistics for a table
+type Stats struct {
+	Table string
+	// Nrows is the number of rows when the table was analyzed.
+	// Fractions are relative to this.
+	Nrows int
+	Cols  []ColStats
+}
+
+// ColStats are the statistics for one column.
+// Values are packed.
+type ColStats struct {
+	Column string
+	// Distinct is the (estimated) number of distinct values
+	Distinct int
+	// Mcv are the most common values, in descending order of McvCount
+	Mcv      []string
+	McvCount []int
+	// Hist are the bounds of an equi-depth histogram
+	// i.e. each bucket has roughly the same number of rows.
+	// There is one more bound than the number of buckets.
+	Hist []string
+}
+
+// MaxStatsValue is the maximum length of a stored value.
+// Longer histogram bounds are truncated, longer common values are skipped.
+const MaxStatsValue = 256
+
+// Col returns the ColStats for a column or nil if not found
+func (st *Stats) Col(col string) *ColStats {
+	if st == nil {
+		return nil
+	}
+	for i := range st.Cols {
+		if st.Cols[i].Column == col {
+			return &st.Cols[i]
+		}
+	}
+	return nil
+}
+
+// EqFrac returns the estimated fraction of rows with the value
+func (cs *ColStats) EqFrac(val string, nrows int) float64 {
+	if nrows <= 0 {
+		return 0
+	}
+	mcvTotal := 0
+	for i, v := range cs.Mcv {
+		if v == val {
+			return float64(cs.McvCount[i]) / float64(nrows)
+		}
+		mcvTotal += cs.McvCount[i]
+	}
+	rest := cs.Distinct - len(cs.Mcv)
+	if rest <= 0 {
+		// all the values are common and this isn't one of them
+		return .5 / float64(nrows)
+	}
+	frac := float64(nrows-mcvTotal) / float64(rest) / float64(nrows)
+	return min(max(frac, 0), 1)
+}
+
+// RangeFrac returns the estimated fraction of rows
+// with values >= org and < end.
+// The common values are counted exactly,
+// the rest are estimated from the histogram.
+func (cs *ColStats) RangeFrac(org, end string, nrows int) float64 {
+	if end <= org || nrows <= 0 {
+		return 0
+	}
+	mcvTotal := 0
+	mcvIn := 0
+	for i, v := range cs.Mcv {
+		mcvTotal += cs.McvCount[i]
+		if org <= v && v < end {
+			mcvIn += cs.McvCount[i]
+		}
+	}
+	frac := float64(mcvIn) / float64(nrows)
+	rest := float64(nrows-mcvTotal) / float64(nrows)
+	if rest <= 0 || len(cs.Hist) == 0 {
+		return min(frac, 1)
+	}
+	return min(frac+rest*cs.histFrac(org, end), 1)
+}
+
+// histFrac returns the fraction of the histogram from org to end
+func (cs *ColStats) histFrac(org, end string) float64 {
+	nb := len(cs.Hist) - 1
+	if nb < 1 {
+		// single value
+		if org <= cs.Hist[0] && cs.Hist[0] < end {
+			return 1
+		}
+		return 0
+	}
+	pos := func(val string) float64 {
+		// i is the number of bounds < val
+		i := sort.Search(len(cs.Hist), func(i int) bool { return cs.Hist[i] >= val })
+		if i > nb {
+			return 1
+		}
+		if cs.Hist[i] == val || i == 0 {
+			return float64(i) / float64(nb)
+		}
+		return (float64(i-1) + .5) / float64(nb) // middle of the bucket
+	}
+	frac := pos(end) - pos(org)
+	if frac <= 0 {
+		if end <= cs.Hist[0] || org > cs.Hist[nb] {
+			return 0 // outside the histogram
+		}
+		frac = .5 / float64(nb) // within one bucket
+	}
+	return frac
+}
+
+func (cs *ColStats) encode() string {
+	size := stor.LenStr(cs.Column) + 5 + 1 + 1
+	for _, v := range cs.Mcv {
+		size += stor.LenStr(v) + 5
+	}
+	for _, b := range cs.Hist {
+		size += stor.LenStr(b)
+	}
+	buf := make([]byte, size)
+	w := stor.NewWriter(buf)
+	w.PutStr(cs.Column).Put5(int64(cs.Distinct)).Put1(len(cs.Mcv))
+	for i, v := range cs.Mcv {
+		w.PutStr(v).Put5(int64(cs.McvCount[i]))
+	}
+	w.Put1(len(cs.Hist))
+	for _, b := range cs.Hist {
+		w.PutStr(b)
+	}
+	return string(buf)
+}
+
+func decodeColStats(s string) ColStats {
+	r := stor.NewReader([]byte(s))
+	var cs ColStats
+	cs.Column = r.GetStr()
+	cs.Distinct = int(r.Get5())
+	for range r.Get1() {
+		cs.Mcv = append(cs.Mcv, r.GetStr())
+		cs.McvCount = append(cs.McvCount, int(r.Get5()))
+	}
+	for range r.Get1() {
+		cs.Hist = append(cs.Hist, r.GetStr())
+	}
+	return cs
+}
+
+func (m *Meta) newSchemaStats(st *Stats) *Schema {
+	cols := make([]string, 0, 1+len(st.Cols))
+	cols = append(cols, strconv.Itoa(st.Nrows))
+	for i := range st.Cols {
+		cols = append(cols, st.Cols[i].encode())
+	}
+	return &Schema{Schema: schema.Schema{Table: "%" + st.Table, Columns: cols},
+		stats: st}
+}
+
+func (ts *Schema) isStats() bool {
+	return !ts.IsTomb() && ts.Table[0] == '%'
+}
+
+func (ts *Schema) toStats() *Stats {
+	nrows, _ := strconv.Atoi(ts.Columns[0])
+	st := &Stats{Table: ts.Table[1:], Nrows: nrows,
+		Cols: make([]ColStats, 0, len(ts.Columns)-1)}
+	for _, s := range ts.Columns[1:] {
+		st.Cols = append(st.Cols, decodeColStats(s))
+	}
+	return st
+}
+
+// GetStats returns the column statistics for a table, or nil if none.
+// The result is shared and must not be modified.
+func (m *Meta) GetStats(table string) *Stats {
+	ts, ok := m.schema.Get("%" + table)
+	if !ok || !ts.isStats() {
+		return nil
+	}
+	return ts.stats
+}
+
+// PutStats replaces the column statistics for a table
+func (m *Meta) PutStats(st *Stats) *Meta {
+	if m.GetRoSchema(st.Table) == nil {
+		panic("analyze: nonexistent table: " + st.Table)
+	}
+	return m.Put(m.newSchemaStats(st), nil)
+}
+
+func (m *Meta) AllStats() iter.Seq[*Stats] {
+	return func(yield func(*Stats) bool) {
+		m.schema.All()(func(schema *Schema) bool {
+			return !schema.isStats() || yield(schema.stats)
+		})
+	}
+}
+
+// dropStats adds a tombstone for the statistics for a table (if any)
+func (m *Meta) dropStats(mu *metaUpdate, table string) {
+	if ts, ok := m.schema.Get("%" + table); ok && ts.isStats() {
+		mu.putSchema(m.newSchemaTomb("%" + table))
+	}
+}
+
+// String is for tests and debugging
+func (cs *ColStats) String() string {
+	return cs.Column + " distinct " + strconv.Itoa(cs.Distinct) +
+		" mcv " + strconv.Itoa(len(cs.Mcv)) +
+		" hist " + strconv.Itoa(max(len(cs.Hist)-1, 0))
+}
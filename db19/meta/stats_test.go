@@ -0,0 +1,43 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package meta
+
+import (
+	"testing"
+
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestStatsPersist(t *testing.T) {
+	st := &Stats{Table: "tbl", Nrows: 1000, Cols: []ColStats{
+		{Column: "a", Distinct: 3, Mcv: []string{"x", "y"},
+			McvCount: []int{600, 300}, Hist: []string{"a", "m", "z"}},
+		{Column: "b", Distinct: 1000, Hist: []string{"0", "5", "9"}},
+		{Column: "c"},
+	}}
+	ts := (&Meta{}).newSchemaStats(st)
+	assert.T(t).That(ts.isStats())
+	assert.T(t).That(!ts.isTable())
+	buf := make([]byte, ts.StorSize())
+	ts.Write(stor.NewWriter(buf))
+	ts2 := ReadSchema(nil, stor.NewReader(buf))
+	assert.T(t).That(ts2.isStats())
+	assert.T(t).This(ts2.stats).Is(st)
+}
+
+func TestColStatsFrac(t *testing.T) {
+	assert := assert.T(t)
+	cs := ColStats{Column: "a", Distinct: 12, Mcv: []string{"c", "x"},
+		McvCount: []int{500, 300}, Hist: []string{"a", "e", "k", "p", "z"}}
+	assert.This(cs.EqFrac("c", 1000)).Is(.5)
+	assert.This(cs.EqFrac("x", 1000)).Is(.3)
+	assert.This(cs.EqFrac("b", 1000)).Is(.02) // (1000 - 800) / 10 / 1000
+	assert.This(cs.RangeFrac("a", "zz", 1000)).Is(1.0)
+	assert.This(cs.RangeFrac("x", "y", 1000)).Is(.3 + .2*.125)
+	assert.This(cs.RangeFrac("0", "1", 1000)).Is(0.0)
+	assert.This(cs.RangeFrac("y", "x", 1000)).Is(0.0)
+	// the first half of the histogram plus "c"
+	assert.This(cs.RangeFrac("a", "k", 1000)).Is(.5 + .2*.5)
+}
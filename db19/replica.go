@@ -0,0 +1,145 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package db19
+
+import (
+	"errors"
+	"hash/crc32"
+	"math"
+	"os"
+
+	"github.com/apmckinlay/gsuneido/db19/meta"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+)
+
+// A replica is a read-only copy of a primary database.
+// Like the archive (see archive.go) it relies on the database file
+// being append-only. The replica requests the primary's file contents
+// from the end of its own file (ReplicaData) and appends them (ApplyReplica)
+// so the offsets are the same in both files.
+// After each append the replica state is advanced to the last state received.
+//
+// A replica does not have a checker, does not persist,
+// and does not write a shutdown marker,
+// so its file remains an exact prefix of the primary's.
+
+const replicaReadOnly = "replica is read-only"
+
+// OpenReplica opens or creates a replica database file.
+// An existing replica is truncated to its last valid state.
+func OpenReplica(filename string) (*Database, error) {
+	var db *Database
+	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+		store, err := stor.MmapStor(filename, stor.Create)
+		if err != nil {
+			return nil, err
+		}
+		db = CreateDb(store)
+	} else {
+		if db, err = openReplica(filename); err != nil {
+			return nil, err
+		}
+	}
+	db.filename = filename
+	db.mode = stor.Read // prevent writing shutdown marker
+	db.replica = true
+	return db, nil
+}
+
+func openReplica(filename string) (*Database, error) {
+	store, err := stor.MmapStor(filename, stor.Read)
+	if err != nil {
+		return nil, err
+	}
+	off, _ := findStateAsof(store, math.MaxInt64)
+	store.Close(true)
+	size := int64(len(magic))
+	if off != 0 {
+		size = int64(off) + int64(stateLen)
+	}
+	if err := os.Truncate(filename, size); err != nil {
+		return nil, err
+	}
+	if store, err = stor.MmapStor(filename, stor.Update); err != nil {
+		return nil, err
+	}
+	db := &Database{Store: store}
+	if off == 0 {
+		db.state.set(&DbState{store: store, Meta: &meta.Meta{}})
+	} else {
+		db.state.set(ReadState(store, off))
+		if err := db.QuickCheck(); err != nil {
+			store.Close(true)
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// IsReplica returns whether the database is a read-only replica
+func (db *Database) IsReplica() bool {
+	return db.replica
+}
+
+// ReplicaData is used by the primary to return up to n bytes
+// of its database file starting at off.
+// crc must match ReplicaCrc for off, to verify that the replica
+// is a copy of this database.
+// It only returns data up to shipEnd (see persist).
+func (db *Database) ReplicaData(off uint64, crc uint32, n int) ([]byte, error) {
+	end := db.shipEnd.Load()
+	if off > end || crc != ReplicaCrc(db.Store, off) {
+		return nil, errors.New("replica does not match primary database")
+	}
+	end = min(end, off+uint64(n))
+	data := make([]byte, 0, end-off)
+	storeRange(db.Store, off, end, func(b []byte) error {
+		data = append(data, b...)
+		return nil
+	})
+	return data, nil
+}
+
+// ReplicaCrc returns a checksum of the data preceding off.
+// It is used by the replica and the primary to verify they match.
+func ReplicaCrc(store *stor.Stor, off uint64) uint32 {
+	const n = 256
+	from := off - min(off, n)
+	crc := crc32.NewIEEE()
+	storeRange(store, from, off, func(b []byte) error {
+		crc.Write(b)
+		return nil
+	})
+	return crc.Sum32()
+}
+
+// ApplyReplica appends data from the primary to the replica
+// and advances the replica state to the last complete state in the data.
+// It returns whether the state was advanced.
+func (db *Database) ApplyReplica(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	from := db.Store.Size()
+	db.Store.Append(from, data)
+	end := db.Store.Size()
+	db.Store.FlushTo(end)
+	off := end
+	for {
+		off = db.Store.LastOffset(off, magic1, nil)
+		if off == 0 || off+uint64(stateLen) <= from {
+			return false // no new state
+		}
+		if off+uint64(stateLen) > end {
+			continue // incomplete, rest will come in the next data
+		}
+		if _, _, t := readStateSafe(db.Store, off); t != 0 {
+			state := ReadState(db.Store, off)
+			db.state.mutex.Lock()
+			db.state.set(state)
+			db.state.mutex.Unlock()
+			return true
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package db19
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestReplica(t *testing.T) {
+	dir := t.TempDir()
+	primary, err := CreateDatabase(filepath.Join(dir, "primary.db"))
+	ck(err)
+	createTbl(primary)
+	primary.CheckerSync()
+	replfile := filepath.Join(dir, "replica.db")
+	replica, err := OpenReplica(replfile)
+	ck(err)
+
+	// small n to exercise partial states
+	catchup := func() {
+		for {
+			off := replica.Store.Size()
+			data, err := primary.ReplicaData(off,
+				ReplicaCrc(replica.Store, off), 100)
+			ck(err)
+			if len(data) == 0 {
+				return
+			}
+			replica.ApplyReplica(data)
+		}
+	}
+	nrows := func(db *Database) int {
+		ti := db.NewReadTran().GetInfo("mytable")
+		if ti == nil {
+			return -1
+		}
+		return ti.Nrows
+	}
+	const nout = 10
+	for i := range 3 {
+		for range nout {
+			primary.CommitMerge(output1(primary))
+		}
+		primary.persist(&execPersistSingle{}, false)
+		catchup()
+		// replication lags by one persist
+		if i == 0 {
+			assert.T(t).This(nrows(replica)).Is(-1)
+		} else {
+			assert.T(t).This(nrows(replica)).Is(i * nout)
+		}
+	}
+	primary.persist(&execPersistSingle{}, false)
+	catchup()
+	assert.T(t).This(nrows(replica)).Is(3 * nout)
+
+	assert.T(t).This(func() { replica.NewUpdateTran() }).
+		Panics("replica is read-only")
+	assert.T(t).This(func() { createTbl(replica) }).
+		Panics("replica is read-only")
+
+	// reopen and continue
+	replica.Close()
+	replica, err = OpenReplica(replfile)
+	ck(err)
+	assert.T(t).This(nrows(replica)).Is(3 * nout)
+	for range nout {
+		primary.CommitMerge(output1(primary))
+	}
+	primary.persist(&execPersistSingle{}, false)
+	primary.persist(&execPersistSingle{}, false)
+	catchup()
+	assert.T(t).This(nrows(replica)).Is(4 * nout)
+	ck(replica.Check(true))
+
+	// a replica that does not match
+	other, err := OpenReplica(filepath.Join(dir, "other.db"))
+	ck(err)
+	other.Store.Alloc(100)
+	_, err = primary.ReplicaData(other.Store.Size(),
+		ReplicaCrc(other.Store, other.Store.Size()), 100)
+	assert.T(t).This(err.Error()).Is("replica does not match primary database")
+	other.Close()
+	replica.Close()
+	primary.Close()
+}
@@ -66,6 +66,9 @@ func (db *Database) Persist() *DbState {
 // UpdateState is guarded by a mutex
 func (db *Database) UpdateState(fn func(*DbState)) {
 	assert.That(!db.IsCorrupted())
+	if db.replica {
+		panic(replicaReadOnly)
+	}
 	db.state.updateState(fn)
 }
 
@@ -137,6 +140,14 @@ func (db *Database) persist(exec execPersist, flush bool) *DbState {
 	if flush {
 		db.Store.FlushTo(off)
 	}
+	// Transactions can be writing records concurrently with persist
+	// so it is only safe to ship (archive or replicate)
+	// up to the end of the previous persisted state.
+	shipEnd := db.persistEnd.Swap(off + uint64(stateLen))
+	db.shipEnd.Store(shipEnd)
+	if db.archive != nil {
+		db.archive.persisted(db.Store, shipEnd)
+	}
 	return newState
 }
 
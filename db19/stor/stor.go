@@ -123,6 +123,23 @@ func (s *Stor) Alloc(n int) (Offset, []byte) {
 	panic("Stor.Alloc too many retries")
 }
 
+// Append adds data at the end of the storage, which must be at off.
+// The data is split at chunk boundaries (as Alloc would)
+// so it ends up at the same offsets as in the storage it was copied from.
+// It is used by replication which is single threaded.
+func (s *Stor) Append(off Offset, data []byte) {
+	for len(data) > 0 {
+		assert.That(s.Size() == off)
+		room := s.chunksize - off&(s.chunksize-1)
+		n := int(min(uint64(len(data)), room))
+		o, buf := s.Alloc(n)
+		assert.That(o == off)
+		copy(buf, data[:n])
+		off += uint64(n)
+		data = data[n:]
+	}
+}
+
 // extend adds another chunk to the storage.
 // Multiple concurrent threads may call extend
 // but only one (the first) does the actual extending.
@@ -40,6 +40,22 @@ func TestData(t *testing.T) {
 	}
 }
 
+func TestAppend(t *testing.T) {
+	src := HeapStor(64)
+	src.Alloc(40)
+	src.Alloc(40) // straddles so leaves a gap at the end of the first chunk
+	data := make([]byte, src.Size())
+	for i := range data {
+		data[i] = byte(i)
+	}
+	dst := HeapStor(64)
+	dst.Append(0, data[:30])
+	dst.Append(30, data[30:])
+	assert.T(t).This(dst.Size()).Is(src.Size())
+	assert.T(t).This(dst.Data(0)[:64]).Is(data[:64])
+	assert.T(t).This(dst.Data(64)[:40]).Is(data[64:])
+}
+
 func TestMmapRead(t *testing.T) {
 	ms, _ := MmapStor("stor_test.go", Read) // use code as test file
 	buf := ms.Data(0)
@@ -77,6 +77,8 @@ func Compact(dbfile string) (nTables, nViews int, oldSize, newSize uint64, err e
 	}
 	close(channel)
 	wg.Wait()
+	copyStats(state, dst)
+	copyChecks(state, dst)
 	dst.GetState().Write()
 	newSize = dst.Store.Size()
 	dst.Close()
@@ -104,6 +106,27 @@ func copyViews(state *DbState, dst *Database) int {
 	return n
 }
 
+// copyStats copies the column statistics from Database.Analyze
+func copyStats(state *DbState, dst *Database) {
+	for st := range state.Meta.AllStats() {
+		dst.UpdateState(func(state *DbState) {
+			state.Meta = state.Meta.PutStats(st)
+		})
+	}
+}
+
+// copyChecks copies the check constraints
+func copyChecks(state *DbState, dst *Database) {
+	for ts := range state.Meta.Tables() {
+		if required, checks := state.Meta.GetChecks(ts.Table); required != nil ||
+			checks != nil {
+			dst.UpdateState(func(state *DbState) {
+				state.Meta = state.Meta.PutChecks(ts.Table, required, checks)
+			})
+		}
+	}
+}
+
 func compactTable(state *DbState, src *Database, ts *meta.Schema, dst *Database) {
 	defer func() {
 		if e := recover(); e != nil {
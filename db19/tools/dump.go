@@ -5,6 +5,7 @@ package tools
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -15,6 +16,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/apmckinlay/gsuneido/core"
 	. "github.com/apmckinlay/gsuneido/db19"
@@ -28,6 +30,7 @@ import (
 	"github.com/apmckinlay/gsuneido/util/openpgputil"
 	"github.com/apmckinlay/gsuneido/util/str"
 	"github.com/apmckinlay/gsuneido/util/system"
+	"golang.org/x/time/rate"
 )
 
 const dumpVersion = "Suneido dump 3\n"
@@ -47,11 +50,40 @@ func DumpDatabase(dbfile, to string) (nTables, nViews int, err error) {
 // Dump exports an entire open database to a file
 // If checks as it dumps and could mark the database as corrupted.
 func Dump(db *Database, to, publicKey string) (nTables, nViews int, err error) {
+	return DumpOnline(db, to, publicKey, nil)
+}
+
+// DumpOnline is like Dump but is monitored and controlled by ctl (see DumpCtl)
+// so it can run in the background on a live server.
+// It uses a single index checker so it does not compete with the server.
+// The dump is from a single persisted state so it does not block updates.
+func DumpOnline(db *Database, to, publicKey string, ctl *DumpCtl) (
+	nTables, nViews int, err error) {
+	return dumpDb(db, to, publicKey, ctl, &manifest{})
+}
+
+// DumpSince is like Dump but only dumps the tables that have changed
+// since the given time (unix milli) e.g. since the previous dump.
+// Like a full dump, it also writes a manifest (see manifest.go).
+func DumpSince(db *Database, to, publicKey string, since int64) (
+	nTables, nViews int, err error) {
+	return dumpDb(db, to, publicKey, nil, &manifest{since: since})
+}
+
+// dumpDb dumps the database and writes the manifest to <to>.manifest
+// so the dump can be the base for loading a chain of incremental dumps.
+
+func dumpDb(db *Database, to, publicKey string, ctl *DumpCtl, mf *manifest) (
+	nTables, nViews int, err error) {
 	if db.IsCorrupted() {
 		return 0, 0, fmt.Errorf("dump not allowed when database is locked")
 	}
 	defer func() {
 		if e := recover(); e != nil {
+			if e == errDumpCancelled {
+				err = errDumpCancelled
+				return
+			}
 			if strings.HasPrefix(fmt.Sprint(e), "OpenPGP: ") {
 				panic(e)
 			}
@@ -65,7 +97,7 @@ func Dump(db *Database, to, publicKey string) (nTables, nViews int, err error) {
 	}
 	tmpfile := f.Name()
 	defer func() { f.Close(); os.Remove(tmpfile) }()
-	nTables, nViews = dump(db, w)
+	nTables, nViews = dump(db, w, ctl, mf)
 	if err := w.Flush(); err != nil {
 		return 0, 0, fmt.Errorf("dump failed: %v", err)
 	}
@@ -73,12 +105,21 @@ func Dump(db *Database, to, publicKey string) (nTables, nViews int, err error) {
 	if err := system.RenameBak(tmpfile, to); err != nil {
 		return 0, 0, fmt.Errorf("dump failed: %v", err)
 	}
+	if err := mf.write(to + manifestSuffix); err != nil {
+		return 0, 0, fmt.Errorf("dump failed: %v", err)
+	}
 	return nTables, nViews, nil
 }
 
-func dump(db *Database, w WriterPlus) (nTables, nViews int) {
-	ics := newIndexCheckers()
+func dump(db *Database, w WriterPlus, ctl *DumpCtl, mf *manifest) (
+	nTables, nViews int) {
+	nw := options.Nworkers
+	if ctl != nil {
+		nw = 1
+	}
+	ics := newIndexCheckers(nw)
 	defer ics.finish()
+	asof := time.Now().UnixMilli() // before Persist so it is <= the state
 	state := db.Persist()
 	nViews = dumpViews(state, w)
 	tables := make([]string, 0, 512)
@@ -86,12 +127,84 @@ func dump(db *Database, w WriterPlus) (nTables, nViews int) {
 		tables = append(tables, sc.Table)
 	}
 	sort.Strings(tables)
+	tables = mf.changed(db, state, tables, asof)
+	ctl.start(len(tables))
 	for _, table := range tables {
-		dumpTable2(db, state, table, true, w, ics)
+		dumpTable2(db, state, table, true, w, ics, ctl)
+		ctl.tableDone()
 	}
 	return len(tables), nViews
 }
 
+// DumpCtl is used to monitor and control a DumpOnline from other threads.
+// The zero value is not valid, use NewDumpCtl.
+type DumpCtl struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	limiter *rate.Limiter // nil if not rate limited
+	// NTables is the total number of tables to dump
+	NTables atomic.Int32
+	// Tables is the number of tables dumped so far
+	Tables atomic.Int32
+	// Bytes is the number of bytes written so far (before any encryption)
+	Bytes atomic.Int64
+}
+
+var errDumpCancelled = errors.New("dump cancelled")
+
+// NewDumpCtl returns a DumpCtl that limits writing to bytesPerSec.
+// bytesPerSec <= 0 means no limit.
+func NewDumpCtl(bytesPerSec int) *DumpCtl {
+	ctx, cancel := context.WithCancel(context.Background())
+	ctl := &DumpCtl{ctx: ctx, cancel: cancel}
+	if bytesPerSec > 0 {
+		ctl.limiter = rate.NewLimiter(rate.Limit(bytesPerSec), bytesPerSec)
+	}
+	return ctl
+}
+
+// Cancel stops the dump, it will return a "dump cancelled" error.
+// The partial dump file is removed.
+func (ctl *DumpCtl) Cancel() {
+	ctl.cancel()
+}
+
+// The following methods allow ctl to be nil (for a regular Dump)
+
+func (ctl *DumpCtl) start(nTables int) {
+	if ctl != nil {
+		ctl.NTables.Store(int32(nTables))
+	}
+}
+
+func (ctl *DumpCtl) tableDone() {
+	if ctl != nil {
+		ctl.Tables.Add(1)
+	}
+}
+
+// wrote is called after each record is written.
+// It handles cancelling and rate limiting.
+func (ctl *DumpCtl) wrote(n int) {
+	if ctl == nil {
+		return
+	}
+	ctl.Bytes.Add(int64(n))
+	if ctl.limiter == nil {
+		if ctl.ctx.Err() != nil {
+			panic(errDumpCancelled)
+		}
+		return
+	}
+	for n > 0 {
+		k := min(n, ctl.limiter.Burst())
+		if ctl.limiter.WaitN(ctl.ctx, k) != nil {
+			panic(errDumpCancelled)
+		}
+		n -= k
+	}
+}
+
 // DumpTable exports a table to a binary file
 func DumpTable(dbfile, table, to string) (nrecs int, err error) {
 	db, err := OpenDb(dbfile, stor.Read, false)
@@ -139,10 +252,10 @@ func DumpDbTable(db *Database, table, to, publicKey string) (nrecs int, err erro
 }
 
 func dumpDbTable(db *Database, table string, w WriterPlus) int {
-	ics := newIndexCheckers()
+	ics := newIndexCheckers(options.Nworkers)
 	defer ics.finish()
 	state := db.Persist()
-	return dumpTable2(db, state, table, false, w, ics)
+	return dumpTable2(db, state, table, false, w, ics, nil)
 }
 
 func dumpOpen(to, publicKey string) (*os.File, WriterPlus, error) {
@@ -195,7 +308,7 @@ func (w writerPlus) Flush() error {
 }
 
 func dumpTable2(db *Database, state *DbState, table string, multi bool,
-	w WriterPlus, ics *indexCheckers) int {
+	w WriterPlus, ics *indexCheckers, ctl *DumpCtl) int {
 	w.WriteString("====== ")
 	sc := state.Meta.GetRoSchema(table)
 	if sc == nil {
@@ -205,11 +318,14 @@ func dumpTable2(db *Database, state *DbState, table string, multi bool,
 	hasdel := sc.HasDeleted()
 	info := state.Meta.GetRoInfo(table)
 	ixi := info.SmallestKeyIndex(sc.Indexes)
-	schema := sc.DumpString(ixi)
+	sch := sc.Schema // copy
+	sch.Required, sch.Checks = state.Meta.GetChecks(table)
+	schema := sch.DumpString(ixi)
 	if !multi {
 		schema = str.AfterFirst(schema, " ")
 	}
 	w.WriteString(schema + "\n")
+	ctl.wrote(len("====== ") + len(schema) + 1)
 	sum := uint64(0)
 	nrows := info.Indexes[ixi].CheckBtree(func(off uint64) {
 		sum += off                       // addition so order doesn't matter
@@ -219,6 +335,7 @@ func dumpTable2(db *Database, state *DbState, table string, multi bool,
 		}
 		writeInt(w, len(rec))
 		w.WriteString(string(rec))
+		ctl.wrote(4 + len(rec))
 	})
 	writeInt(w, 0) // end of table records
 	if nrows != info.Nrows {
@@ -273,10 +390,9 @@ func dumpViews(state *DbState, w WriterPlus) int {
 // ------------------------------------------------------------------
 // Concurrent checking of additional indexes. Also used by compact.
 
-func newIndexCheckers() *indexCheckers {
+func newIndexCheckers(nw int) *indexCheckers {
 	ics := indexCheckers{work: make(chan indexCheck, 32), // ???
 		stop: make(chan void)}
-	nw := options.Nworkers
 	for range nw {
 		ics.wg.Go(ics.worker)
 	}
@@ -26,6 +26,7 @@ import (
 	"github.com/apmckinlay/gsuneido/util/errs"
 	"github.com/apmckinlay/gsuneido/util/openpgputil"
 	"github.com/apmckinlay/gsuneido/util/sortlist"
+	"github.com/apmckinlay/gsuneido/util/str"
 	"github.com/apmckinlay/gsuneido/util/system"
 )
 
@@ -44,6 +45,16 @@ type loadJob struct {
 // It does NOT check foreign key data
 // because it assumes the dump was from a valid database.
 func LoadDatabase(from, dbfile, privateKey, passphrase string) (
+	nTables, nViews int, err error) {
+	return LoadDatabaseChain([]string{from}, dbfile, privateKey, passphrase)
+}
+
+// LoadDatabaseChain imports a base dump followed by
+// a chain of incremental dumps (see DumpSince).
+// Each table is loaded from the latest dump that contains it.
+// The views are loaded from the latest dump.
+// Tables that are not in the manifest of the latest dump are not loaded.
+func LoadDatabaseChain(froms []string, dbfile, privateKey, passphrase string) (
 	nTables, nViews int, err error) {
 	var errVal atomic.Value // error
 	defer func() {
@@ -51,8 +62,7 @@ func LoadDatabase(from, dbfile, privateKey, passphrase string) (
 			err = errs.From(e)
 		}
 	}()
-	f, r := loadOpen(from, privateKey, passphrase)
-	defer f.Close()
+	want := chainTables(froms)
 	db, tmpfile := tmpdb()
 	defer func() { db.Close(); os.Remove(tmpfile) }()
 
@@ -72,30 +82,59 @@ func LoadDatabase(from, dbfile, privateKey, passphrase string) (
 			}
 		})
 	}
-
-	// load the tables
-	nTables = 0
-	for ; errVal.Load() == nil; nTables++ {
-		schema := readLinePrefixed(r, "====== ")
-		if schema == "" {
-			break
-		}
-		sch := query.NewAdminParser(schema).Schema()
-		ts := &meta.Schema{Schema: sch}
-		nrecs, size, list := loadTable1(db, r, schema)
-		if strings.HasPrefix(schema, "views ") {
-			nViews = nrecs
-			nTables--
-		} else {
-			channel <- &loadJob{db: db, ts: ts,
-				nrecs: nrecs, size: size, list: list}
+	defer func() {
+		if channel != nil { // if we panic
+			close(channel)
+			wg.Wait()
 		}
+	}()
+
+	// load the tables, latest dump first
+	loaded := make(map[string]bool)
+	for i := len(froms) - 1; i >= 0 && errVal.Load() == nil; i-- {
+		latest := i == len(froms)-1
+		func() {
+			f, r := loadOpen(froms[i], privateKey, passphrase)
+			defer f.Close()
+			for errVal.Load() == nil {
+				schema := readLinePrefixed(r, "====== ")
+				if schema == "" {
+					break
+				}
+				if strings.HasPrefix(schema, "views ") {
+					if latest {
+						nViews, _, _ = loadTable1(db, r, schema)
+					} else {
+						skipRecords(r)
+					}
+					continue
+				}
+				table := str.BeforeFirst(schema, " ")
+				if loaded[table] || (want != nil && !want[table]) {
+					skipRecords(r)
+					continue
+				}
+				loaded[table] = true
+				sch := query.NewAdminParser(schema).Schema()
+				ts := &meta.Schema{Schema: sch}
+				nrecs, size, list := loadTable1(db, r, schema)
+				channel <- &loadJob{db: db, ts: ts,
+					nrecs: nrecs, size: size, list: list}
+				nTables++
+			}
+		}()
 	}
 	close(channel)
+	channel = nil
 	wg.Wait()
 	if errVal.Load() != nil {
 		return 0, 0, errVal.Load().(error)
 	}
+	for table := range want {
+		if !loaded[table] {
+			return 0, 0, fmt.Errorf("load: %s is not in any of the dumps", table)
+		}
+	}
 	trace("SIZE", db.Store.Size())
 	db.CheckAllFkeys()
 	db.GetState().Write()
@@ -158,6 +197,55 @@ func loadDbTable(table, from, privateKey, passphrase string,
 	return nrecs, nil
 }
 
+// LoadDbTableChain is used by Database.Load with incremental dumps.
+// It loads the table from the latest of the dumps that contains it.
+func LoadDbTableChain(table string, froms []string,
+	privateKey, passphrase string, db *Database) (n int, err error) {
+	if db.IsCorrupted() {
+		return 0, fmt.Errorf("load not allowed when database is locked")
+	}
+	db.AddExclusive(table)
+	defer func() {
+		db.EndExclusive(table)
+		if e := recover(); e != nil {
+			err = fmt.Errorf("error loading %s: %v", table, e)
+		}
+	}()
+	want := chainTables(froms)
+	if want != nil && !want[table] {
+		panic("not in the latest dump")
+	}
+	for i := len(froms) - 1; i >= 0; i-- {
+		if n, ok := loadChainTable(table, froms[i], privateKey, passphrase, db); ok {
+			db.Persist() // for safety, not strictly required
+			return n, nil
+		}
+	}
+	panic("not found in dumps")
+}
+
+// loadChainTable loads the table if it is in the (multi-table) dump
+func loadChainTable(table, from, privateKey, passphrase string,
+	db *Database) (int, bool) {
+	f, r := loadOpen(from, privateKey, passphrase)
+	defer f.Close()
+	for {
+		schema := readLinePrefixed(r, "====== ")
+		if schema == "" {
+			return 0, false
+		}
+		if str.BeforeFirst(schema, " ") != table ||
+			strings.HasPrefix(schema, "views ") {
+			skipRecords(r)
+			continue
+		}
+		ts := tableSchema(db, schema)
+		nrecs, size, list := loadTable1(db, r, schema)
+		loadTable2(db, ts, nrecs, size, list, true)
+		return nrecs, true
+	}
+}
+
 func loadOpen(filename, privateKey, passphrase string) (*os.File, *bufio.Reader) {
 	f, err := os.Open(filename)
 	ck(err)
@@ -233,6 +321,24 @@ func readLinePrefixed(r *bufio.Reader, pre string) string {
 	return s[len(pre):]
 }
 
+// skipRecords reads past the records of a table that is not being loaded
+func skipRecords(in *bufio.Reader) {
+	intbuf := make([]byte, 4)
+	for { // each record
+		_, err := io.ReadFull(in, intbuf)
+		if err == io.EOF {
+			break
+		}
+		ck(err)
+		n := int(binary.BigEndian.Uint32(intbuf))
+		if n == 0 {
+			break
+		}
+		_, err = in.Discard(n)
+		ck(err)
+	}
+}
+
 func readRecords(in *bufio.Reader, store *stor.Stor, list *slBuilder) (
 	nrecs int, size int64) {
 	intbuf := make([]byte, 4)
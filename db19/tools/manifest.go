@@ -0,0 +1,186 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	. "github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/meta"
+)
+
+// An incremental dump (DumpSince) only contains the tables
+// that have changed since a given time (plus all the views).
+// Changes are determined by comparing the dumped state
+// to the persisted state as of the since time.
+// Since the database file is append-only, the old state is still available.
+// If there is no state as of the since time
+// (e.g. the database was compacted or loaded since then)
+// then all the tables are dumped.
+//
+// The manifest is a text file written alongside a full or incremental dump
+// of the database. For a full dump since is 0 and all the tables are dumped.
+// It lists all the tables in the database (not just the ones dumped)
+// so loading a chain of dumps can determine which tables were dropped.
+//
+//	Suneido dump manifest 1
+//	since <unix milli>
+//	asof <unix milli>
+//	dumped <table>
+//	unchanged <table>
+//
+// The asof time can be used as the since for the next incremental dump.
+
+const manifestVersion = "Suneido dump manifest 1"
+const manifestSuffix = ".manifest"
+
+type manifest struct {
+	since  int64
+	asof   int64
+	dumped []string
+	tables []string // all the tables, sorted
+}
+
+// changed returns the tables that have changed since mf.since
+// (all of them for a full dump) and records them in the manifest
+func (mf *manifest) changed(db *Database, state *DbState, tables []string,
+	asof int64) []string {
+	mf.asof = asof
+	mf.tables = tables
+	if mf.since == 0 {
+		mf.dumped = tables
+		return tables
+	}
+	old := stateSince(db, mf.since)
+	for _, table := range tables {
+		if old == nil || tableChanged(old.Meta, state.Meta, table) {
+			mf.dumped = append(mf.dumped, table)
+		}
+	}
+	return mf.dumped
+}
+
+func stateSince(db *Database, since int64) (state *DbState) {
+	defer func() {
+		if e := recover(); e != nil {
+			state = nil // no state as of since
+		}
+	}()
+	return StateAsof(db.Store, since)
+}
+
+// tableChanged compares the schema and the indexes.
+// Any change to the data will have changed the btrees.
+func tableChanged(old, cur *meta.Meta, table string) bool {
+	osc := old.GetRoSchema(table)
+	if osc == nil || osc.String() != cur.GetRoSchema(table).String() {
+		return true
+	}
+	oti := old.GetRoInfo(table)
+	cti := cur.GetRoInfo(table)
+	if oti == nil || oti.Nrows != cti.Nrows || oti.Size != cti.Size ||
+		len(oti.Indexes) != len(cti.Indexes) {
+		return true
+	}
+	for i, ov := range oti.Indexes {
+		if ov.Cksum() != cti.Indexes[i].Cksum() ||
+			ov.BtreeLevels() != cti.Indexes[i].BtreeLevels() {
+			return true
+		}
+	}
+	return false
+}
+
+func (mf *manifest) write(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, manifestVersion)
+	fmt.Fprintln(w, "since", mf.since)
+	fmt.Fprintln(w, "asof", mf.asof)
+	i := 0
+	for _, table := range mf.tables {
+		if i < len(mf.dumped) && mf.dumped[i] == table {
+			fmt.Fprintln(w, "dumped", table)
+			i++
+		} else {
+			fmt.Fprintln(w, "unchanged", table)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readManifest(filename string) (*manifest, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(buf)), "\n")
+	bad := func() (*manifest, error) {
+		return nil, fmt.Errorf("invalid dump manifest: %s", filename)
+	}
+	if len(lines) < 3 || strings.TrimSpace(lines[0]) != manifestVersion {
+		return bad()
+	}
+	mf := &manifest{}
+	for _, line := range lines[1:] {
+		key, val, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch key {
+		case "since":
+			mf.since, err = strconv.ParseInt(val, 10, 64)
+		case "asof":
+			mf.asof, err = strconv.ParseInt(val, 10, 64)
+		case "dumped":
+			mf.dumped = append(mf.dumped, val)
+			mf.tables = append(mf.tables, val)
+		case "unchanged":
+			mf.tables = append(mf.tables, val)
+		default:
+			return bad()
+		}
+		if err != nil {
+			return bad()
+		}
+	}
+	return mf, nil
+}
+
+// chainTables returns the tables that should result
+// from loading a base dump followed by incremental dumps,
+// or nil if there are no incremental dumps (i.e. all the tables).
+// It verifies that the base is a full dump
+// and that each incremental dump follows the previous one.
+func chainTables(froms []string) map[string]bool {
+	if len(froms) < 2 {
+		return nil
+	}
+	var prev *manifest
+	for _, from := range froms {
+		mf, err := readManifest(from + manifestSuffix)
+		ck(err)
+		if prev == nil && mf.since != 0 {
+			panic("base dump " + from + " is not a full dump")
+		}
+		if prev != nil && (mf.since > prev.asof || mf.asof < prev.asof) {
+			panic("incremental dump " + from +
+				" does not follow the previous dump")
+		}
+		prev = mf
+	}
+	tables := make(map[string]bool, len(prev.tables))
+	for _, table := range prev.tables {
+		tables[table] = true
+	}
+	return tables
+}
@@ -5,6 +5,7 @@ package tools_test
 
 import (
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -49,6 +50,8 @@ func TestTools(t *testing.T) {
 	defer os.Remove(dbName + ".bak")
 	ck(db19.CheckDatabase(dbName, true))
 	compareDb(dbName, "loaded_"+dbName)
+	assert.That(strings.Contains(getSchema(dbName),
+		"required(one) check(two isnt 'x')"))
 	tools.DumpDatabase(dbName, "dump3_"+dbName)
 	defer os.Remove("dump3_" + dbName)
 	compare("dump_"+dbName, "dump3_"+dbName)
@@ -82,6 +85,7 @@ func createDb() {
 		}
 	}
 	adm("alter bar drop (three)")
+	adm("alter foo create required(one) check(two isnt 'x')")
 	db.Close()
 }
 
@@ -111,3 +115,128 @@ func getSchema(dbName string) string {
 	defer db.Close()
 	return db.Schema("foo") + "\n" + db.Schema("bar")
 }
+
+func TestDumpOnline(t *testing.T) {
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	defer db.Close()
+	db19.MakeSuTran = func(ut *db19.UpdateTran) *core.SuTran {
+		return core.NewSuTran(nil, true)
+	}
+	for _, table := range []string{"foo", "bar"} {
+		query.DoAdmin(db, "create "+table+" "+schema, nil)
+		for _, d := range data {
+			ut := db.NewUpdateTran()
+			query.DoAction(nil, ut, "insert { one: '"+d[0]+"', two: '"+d[1]+
+				"' } into "+table)
+			ut.Commit()
+		}
+	}
+	dir := t.TempDir()
+	to := dir + "/online.su"
+	ctl := tools.NewDumpCtl(100)
+	nTables, _, err := tools.DumpOnline(db, to, "", ctl)
+	ck(err)
+	assert.T(t).This(nTables).Is(2)
+	assert.T(t).This(ctl.NTables.Load()).Is(2)
+	assert.T(t).This(ctl.Tables.Load()).Is(2)
+	st, _ := os.Stat(to)
+	// Bytes excludes the version and views
+	assert.T(t).That(ctl.Bytes.Load() > 0 && ctl.Bytes.Load() < st.Size())
+	_, _, err = tools.Dump(db, dir+"/dump.su", "")
+	ck(err)
+	compare(to, dir+"/dump.su")
+
+	ctl = tools.NewDumpCtl(0)
+	ctl.Cancel()
+	_, _, err = tools.DumpOnline(db, dir+"/cancelled.su", "", ctl)
+	assert.T(t).This(err.Error()).Is("dump cancelled")
+	_, err = os.Stat(dir + "/cancelled.su")
+	assert.T(t).That(os.IsNotExist(err))
+	assert.T(t).That(!db.IsCorrupted())
+}
+
+func TestDumpSince(t *testing.T) {
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	defer db.Close()
+	db19.MakeSuTran = func(ut *db19.UpdateTran) *core.SuTran {
+		return core.NewSuTran(nil, true)
+	}
+	insert := func(table, one string) {
+		ut := db.NewUpdateTran()
+		query.DoAction(nil, ut, "insert { one: '"+one+"' } into "+table)
+		ut.Commit()
+	}
+	for _, table := range []string{"foo", "bar", "baz"} {
+		query.DoAdmin(db, "create "+table+" "+schema, nil)
+		insert(table, "a")
+	}
+	db.Persist()
+	dir := t.TempDir() + "/"
+	_, _, err := tools.Dump(db, dir+"early.su", "")
+	ck(err)
+	time.Sleep(5 * time.Millisecond)
+	since := time.Now().UnixMilli()
+	_, _, err = tools.Dump(db, dir+"base.su", "")
+	ck(err)
+	mf, err := os.ReadFile(dir + "base.su.manifest")
+	ck(err)
+	assert.T(t).That(strings.HasPrefix(string(mf),
+		"Suneido dump manifest 1\nsince 0\n"))
+
+	insert("foo", "b")
+	query.DoAdmin(db, "drop baz", nil)
+	query.DoAdmin(db, "create qux "+schema, nil)
+	nTables, _, err := tools.DumpSince(db, dir+"inc1.su", "", since)
+	ck(err)
+	assert.T(t).This(nTables).Is(2)
+	mf, err = os.ReadFile(dir + "inc1.su.manifest")
+	ck(err)
+	lines := strings.Split(string(mf), "\n")
+	assert.T(t).This(lines[0]).Is("Suneido dump manifest 1")
+	assert.T(t).This(lines[3:]).
+		Is([]string{"unchanged bar", "dumped foo", "dumped qux", ""})
+	since = strToInt(strings.TrimPrefix(lines[2], "asof "))
+
+	insert("bar", "b")
+	nTables, _, err = tools.DumpSince(db, dir+"inc2.su", "", since)
+	ck(err)
+	assert.T(t).This(nTables).Is(1)
+
+	chain := []string{dir + "base.su", dir + "inc1.su", dir + "inc2.su"}
+	nTables, _, err = tools.LoadDatabaseChain(chain, dir+"loaded.db", "", "")
+	ck(err)
+	assert.T(t).This(nTables).Is(3)
+	_, _, err = tools.Dump(db, dir+"expected.su", "")
+	ck(err)
+	_, _, err = tools.DumpDatabase(dir+"loaded.db", dir+"loaded.su")
+	ck(err)
+	compare(dir+"expected.su", dir+"loaded.su")
+
+	n, err := tools.LoadDbTableChain("foo", chain, "", "", db)
+	ck(err)
+	assert.T(t).This(n).Is(2)
+	_, err = tools.LoadDbTableChain("baz", chain, "", "", db)
+	assert.T(t).This(err.Error()).Is("error loading baz: not in the latest dump")
+	// out of order
+	_, _, err = tools.LoadDatabaseChain(
+		[]string{dir + "base.su", dir + "inc2.su", dir + "inc1.su"},
+		dir+"loaded.db", "", "")
+	assert.T(t).That(strings.Contains(err.Error(), "does not follow"))
+	// base is older than the first incremental dump
+	_, _, err = tools.LoadDatabaseChain(
+		[]string{dir + "early.su", dir + "inc1.su", dir + "inc2.su"},
+		dir+"loaded.db", "", "")
+	assert.T(t).That(strings.Contains(err.Error(), "does not follow"))
+	// base is not a full dump
+	_, _, err = tools.LoadDatabaseChain(
+		[]string{dir + "inc1.su", dir + "inc2.su"}, dir+"loaded.db", "", "")
+	assert.T(t).That(strings.Contains(err.Error(), "is not a full dump"))
+}
+
+func strToInt(s string) int64 {
+	n, err := strconv.ParseInt(s, 10, 64)
+	ck(err)
+	return n
+}
@@ -73,6 +73,17 @@ func (t *tran) GetView(name string) string {
 	return t.db.GetView(name)
 }
 
+// GetStats returns the column statistics for a table, or nil if none
+func (t *tran) GetStats(table string) *meta.Stats {
+	return t.meta.GetStats(table)
+}
+
+// GetMeta returns the transaction's (read-only) meta data.
+// It is used by the dbms plan cache to check for schema changes.
+func (t *tran) GetMeta() *meta.Meta {
+	return t.meta
+}
+
 func (t *tran) GetStore() *stor.Stor {
 	return t.db.Store
 }
@@ -232,12 +243,25 @@ func (t *ReadTran) Abort() string {
 
 type UpdateTran struct {
 	ct *CkTran
+	// capture caches whether tables are captured (see changes.go)
+	capture map[string]bool
+	// history caches whether tables have history (see history.go)
+	history map[string]bool
+	// checks caches the compiled check constraints (see checks.go)
+	checks map[string]*constraints
 	ReadTran
+	changes    []change
+	versions   []histVersion
 	writeCount int
+	// histWriter allows writing history tables (see CreateHistory)
+	histWriter bool
 }
 
 func (db *Database) NewUpdateTran() *UpdateTran {
 	db.ckOpen()
+	if db.replica {
+		panic("update transactions are not allowed, " + replicaReadOnly)
+	}
 	ct := db.ck.StartTran()
 	if ct == nil {
 		return nil
@@ -282,11 +306,29 @@ func (t *UpdateTran) Commit() {
 	t.ck(t.db.ck.Commit(t))
 }
 
+// commitTables returns the tables that are written by commit
+func (t *UpdateTran) commitTables() []string {
+	tables := t.historyTables()
+	if len(t.changes) > 0 {
+		tables = append(tables, ChangesTable)
+	}
+	return tables
+}
+
 // commit is internal, called by checkco (to serialize)
 func (t *UpdateTran) commit() int {
+	if len(t.changes) > 0 {
+		t.writeChanges()
+	}
+	if len(t.versions) > 0 {
+		t.writeHistory()
+	}
 	t.db.UpdateState(func(state *DbState) {
 		state.Meta = t.meta.LayeredOnto(state.Meta)
 	})
+	if len(t.changes) > 0 {
+		t.db.notifyChanges()
+	}
 	return t.num()
 }
 
@@ -320,7 +362,8 @@ func (t *UpdateTran) Read(table string, iIndex int, from, to string) {
 
 const writeMax = 10000
 
-func (t *UpdateTran) write() {
+func (t *UpdateTran) write(table string) {
+	t.checkHistoryWrite(table)
 	if t.writeCount++; t.writeCount >= writeMax {
 		t.Abort()
 		panic("too many writes (output, update, or delete) in one transaction")
@@ -332,10 +375,11 @@ func (t *UpdateTran) Output(th *core.Thread, table string, rec core.Record) {
 		return // prevent appending to database
 	}
 	trace.Dbms.Println("tran Output", table)
-	t.write()
+	t.write(table)
 	ts := t.getSchema(table)
 	ti := t.tran.GetInfo(table) // readonly
 	rec = rec.Truncate(len(ts.Columns))
+	t.checkRecord(th, ts, rec)
 	n := rec.Len()
 	off, buf := t.db.Store.Alloc(n + cksum.Len)
 	copy(buf, rec[:n])
@@ -369,6 +413,8 @@ func (t *UpdateTran) Output(th *core.Thread, table string, rec core.Record) {
 	}()
 	ti.Nrows++
 	ti.Size += int64(n)
+	t.addChange(ts, "insert", "", rec)
+	t.addVersion(table, "", rec)
 	t.db.CallTrigger(th, t, table, "", rec)
 }
 
@@ -427,7 +473,7 @@ func (t *ReadTran) fkeyOutputExists(table string, iIndex int, key string) bool {
 
 func (t *UpdateTran) Delete(th *core.Thread, table string, off uint64) {
 	trace.Dbms.Println("tran Delete", table, off)
-	t.write()
+	t.write(table)
 	ts := t.getSchema(table)
 	rec := t.GetRecord(off)
 	n := int64(rec.Len())
@@ -435,7 +481,7 @@ func (t *UpdateTran) Delete(th *core.Thread, table string, off uint64) {
 	for i := range ts.Indexes {
 		is := ts.Indexes[i].Ixspec
 		keys[i] = is.Key(rec)
-		t.fkeyDeleteBlock(ts, i, keys[i])
+		t.fkeyDeleteBlock(ts, i, keys[i], false)
 	}
 	t.ck(t.db.ck.Delete(t.ct, table, off, keys))
 	func() {
@@ -460,10 +506,17 @@ func (t *UpdateTran) Delete(th *core.Thread, table string, off uint64) {
 		assert.That(ti.Size >= n)
 		ti.Size -= n
 	}()
+	t.addChange(ts, "delete", rec, "")
+	t.addVersion(table, rec, "")
 	t.db.CallTrigger(th, t, table, rec, "")
 }
 
-func (t *UpdateTran) fkeyDeleteBlock(ts *meta.Schema, i int, key string) {
+// fkeyDeleteBlock panics if there are records that reference key.
+// It is used by delete and by updates that change the key.
+// SetEmpty only applies to delete, updates are blocked
+// unless they cascade.
+func (t *UpdateTran) fkeyDeleteBlock(ts *meta.Schema, i int, key string,
+	update bool) {
 	if key == "" {
 		return
 	}
@@ -481,7 +534,8 @@ func (t *UpdateTran) fkeyDeleteBlock(ts *meta.Schema, i int, key string) {
 			}
 			fkey = encKey
 		}
-		if fkth.Mode == schema.Block &&
+		if (fkth.Mode == schema.Block ||
+			(update && fkth.Mode == schema.SetEmpty)) &&
 			t.fkeyDeleteExists(fkth, fkey, len(ix.Columns)) {
 			panic("delete blocked by foreign key: " +
 				fkth.Table + " " + str.Join("(,)", fkth.Columns))
@@ -544,7 +598,32 @@ func (t *UpdateTran) fkeyDeleteCascade(th *core.Thread, ts *meta.Schema, i int,
 			for iter.Next(ft); !iter.Eof(); iter.Next(ft) {
 				t.Delete(th, fkth.Table, iter.CurOff())
 			}
+		} else if fkth.Mode&schema.SetEmpty != 0 {
+			t.fkeySetEmpty(th, fkth, encoded, key, len(ix.Columns))
+		}
+	}
+}
+
+// fkeySetEmpty clears the foreign key columns
+// in the records that reference a deleted key
+func (t *UpdateTran) fkeySetEmpty(th *core.Thread, fkth *schema.Fkey,
+	encoded bool, key string, kn int) {
+	ts2 := t.GetSchema(fkth.Table)
+	iter := t.cascadeRange(fkth, encoded, key, kn)
+	ft := fkeyTran{t}
+	for iter.Next(ft); !iter.Eof(); iter.Next(ft) {
+		off := iter.CurOff()
+		oldrec := t.GetRecord(off)
+		rb := core.RecordBuilder{}
+		for i, col := range ts2.Columns {
+			if j := slices.Index(fkth.Columns, col); j != -1 && j < kn {
+				// col is part of the foreign key
+				rb.AddRaw("")
+			} else {
+				rb.AddRaw(oldrec.GetRaw(i))
+			}
 		}
+		t.update(th, fkth.Table, off, rb.Trim().Build(), false)
 	}
 }
 
@@ -568,7 +647,7 @@ func (fkeyTran) Read(string, int, string, string) {
 }
 
 func (t *UpdateTran) Update(th *core.Thread, table string, oldoff uint64, newrec core.Record) uint64 {
-	t.write()
+	t.write(table)
 	return t.update(th, table, oldoff, newrec, true)
 }
 
@@ -587,6 +666,7 @@ func (t *UpdateTran) update(th *core.Thread, table string, oldoff uint64, newrec
 		// so we should already have sent a read to the checker
 		return oldoff
 	}
+	t.checkRecord(th, ts, newrec)
 	newoff, buf := t.db.Store.Alloc(n + cksum.Len)
 	copy(buf, newrec)
 	cksum.Update(buf)
@@ -600,7 +680,7 @@ func (t *UpdateTran) update(th *core.Thread, table string, oldoff uint64, newrec
 		newkeys[i] = is.Key(newrec)
 		if oldkeys[i] != newkeys[i] {
 			t.dupOutputBlock(table, i, ix, ti.Indexes[i], newrec, newkeys[i])
-			t.fkeyDeleteBlock(ts, i, oldkeys[i])
+			t.fkeyDeleteBlock(ts, i, oldkeys[i], true)
 			if block {
 				t.fkeyOutputBlock(ts, i, newrec)
 			}
@@ -636,6 +716,8 @@ func (t *UpdateTran) update(th *core.Thread, table string, oldoff uint64, newrec
 			}
 		}
 	}()
+	t.addChange(ts, "update", oldrec, newrec)
+	t.addVersion(table, oldrec, newrec)
 	t.db.CallTrigger(th, t, table, oldrec, newrec)
 	return newoff
 }
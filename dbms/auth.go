@@ -5,71 +5,304 @@ package dbms
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/md5"
 	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 
 	. "github.com/apmckinlay/gsuneido/core"
 	"github.com/apmckinlay/gsuneido/util/hacks"
 	"github.com/apmckinlay/gsuneido/util/str"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/time/rate"
 )
 
+// Users are authenticated against the passhash column of the users table.
+//
+// The original (legacy) passhash is Md5(user $ password)
+// and Auth data is user $ '\x00' $ Sha1(nonce $ passhash)
+//
+// The newer passhash is SCRAM-SHA-256 style (RFC 5802)
+// stored in the RFC 5803 format:
+//
+//	SCRAM-SHA-256$<iterations>:<salt>$<StoredKey>:<ServerKey>
+//
+// with Argon2id as the key derivation function.
+// The client gets the salt and iterations with Database.Salt(user)
+// and then Auth data is "SCRAM-SHA-256" $ '\x00' $ user $ '\x00' $ ClientProof
+// where the AuthMessage is user $ '\x00' $ nonce.
+// Since the server only stores StoredKey, the passhash can not be used
+// to authenticate (unlike the legacy passhash).
+// The server signature is not used, TLS authenticates the server.
+//
+// Legacy passhash values continue to work until the password is changed
+// and the passhash is replaced with one from ScramPassHash.
+
 const nonceSize = 8
 const tokenSize = 16
+const saltSize = 16
+
+const scramName = "SCRAM-SHA-256"
+const scramPrefix = scramName + "$"
+
+// ScramIterations is the default Argon2id time (iterations) parameter
+const ScramIterations = 3
+
+// maxScramIterations limits the cost of a salt from the server
+const maxScramIterations = 100
+
+// SaltKeyFile holds the key used to give consistent fake salts
+// for nonexistent users and users with legacy passhash values.
+// It is created with a random key if it does not exist.
+// It needs to persist so fake salts do not change when the server restarts.
+var SaltKeyFile = "suneido.saltkey"
 
-var tokens = make(map[string]bool)
+const saltKeySize = 32
+
+var fakeSaltKey = sync.OnceValue(func() []byte {
+	key, err := os.ReadFile(SaltKeyFile)
+	if err == nil && len(key) == saltKeySize {
+		return key
+	}
+	key = randomBytes(saltKeySize)
+	if err := os.WriteFile(SaltKeyFile, key, 0600); err != nil {
+		log.Println("ERROR: auth: can't write", SaltKeyFile, err)
+	}
+	return key
+})
+
+// tokens maps tokens to the user that requested them (see DbmsRestricted)
+var tokens = make(map[string]tokenInfo)
 var tokensLock sync.Mutex
 
+type tokenInfo struct {
+	user string
+	old  bool
+}
+
 // authLimiter limits the rate of authentication attempts
 var authLimiter = rate.NewLimiter(rate.Limit(4), 1) // ???
 var authContext = context.Background()
 
 func Nonce() string {
-	buf := make([]byte, nonceSize)
-	if _, err := rand.Read(buf); err != nil {
-		panic("Nonce: " + err.Error())
-	}
-	return hacks.BStoS(buf)
+	return hacks.BStoS(randomBytes(nonceSize))
 }
 
 // Token generates a random token.
 // It is used by dbms.Token
 func Token() string {
-	buf := make([]byte, tokenSize)
-	if _, err := rand.Read(buf); err != nil {
-		panic("Token: " + err.Error())
-	}
-	s := hacks.BStoS(buf)
+	return tokenFor("")
+}
+
+func tokenFor(user string) string {
+	s := hacks.BStoS(randomBytes(tokenSize))
 	tokensLock.Lock()
 	defer tokensLock.Unlock()
-	tokens[s] = false // not old
+	tokens[s] = tokenInfo{user: user} // not old
 	return s
 }
 
 // AuthToken verifies that the given token is valid.
 // It is used by dbms.Auth
 func AuthToken(s string) bool {
+	_, ok := authToken(s)
+	return ok
+}
+
+// authToken returns the user the token was issued to, and whether it is valid
+func authToken(s string) (string, bool) {
 	authLimiter.Wait(authContext)
 	tokensLock.Lock()
 	defer tokensLock.Unlock()
-	if _, ok := tokens[s]; ok {
+	if ti, ok := tokens[s]; ok {
 		delete(tokens, s)
-		return true
+		return ti.user, true
 	}
-	return false
+	return "", false
+}
+
+func randomBytes(n int) []byte {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic("random: " + err.Error())
+	}
+	return buf
 }
 
+// AuthUser verifies user authentication data from the client.
+// It is used by dbms.Auth
 func AuthUser(th *Thread, s, nonce string) bool {
 	authLimiter.Wait(authContext)
 	if nonce == "" {
 		return false
 	}
+	if rest, ok := strings.CutPrefix(s, scramName+"\x00"); ok {
+		return authScram(th, rest, nonce)
+	}
 	user := str.BeforeFirst(s, "\x00")
 	passhash := getPassHash(th, user)
-	hash := sha1.Sum([]byte(nonce + passhash)) //TODO upgrade sha1
+	if passhash == "" || isScram(passhash) {
+		return false
+	}
+	hash := sha1.Sum([]byte(nonce + passhash))
 	t := user + "\x00" + string(hash[:])
-	return s == t
+	return subtle.ConstantTimeCompare([]byte(s), []byte(t)) == 1
+}
+
+// authUserName returns the user from AuthUser data
+func authUserName(s string) string {
+	if rest, ok := strings.CutPrefix(s, scramName+"\x00"); ok {
+		s = rest
+	}
+	return str.BeforeFirst(s, "\x00")
+}
+
+func authScram(th *Thread, s, nonce string) bool {
+	user, proof, ok := strings.Cut(s, "\x00")
+	if !ok || len(proof) != sha256.Size {
+		return false
+	}
+	_, _, storedKey, ok := parseScram(getPassHash(th, user))
+	if !ok {
+		return false
+	}
+	clientSig := hmacSha256(storedKey, user+"\x00"+nonce)
+	clientKey := make([]byte, sha256.Size)
+	subtle.XORBytes(clientKey, []byte(proof), clientSig)
+	hash := sha256.Sum256(clientKey)
+	return subtle.ConstantTimeCompare(hash[:], storedKey) == 1
+}
+
+func isScram(passhash string) bool {
+	return strings.HasPrefix(passhash, scramPrefix)
+}
+
+// parseScram returns the iterations, salt, and StoredKey from a passhash
+func parseScram(passhash string) (int, []byte, []byte, bool) {
+	s, ok := strings.CutPrefix(passhash, scramPrefix)
+	if !ok {
+		return 0, nil, nil, false
+	}
+	params, keys, _ := strings.Cut(s, "$")
+	iters, salt, err := parseSalt(params)
+	if err != nil {
+		return 0, nil, nil, false
+	}
+	storedKey, _, _ := strings.Cut(keys, ":")
+	sk, err := base64.StdEncoding.DecodeString(storedKey)
+	if err != nil || len(sk) != sha256.Size {
+		return 0, nil, nil, false
+	}
+	return iters, salt, sk, true
+}
+
+// parseSalt parses <iterations>:<salt>
+func parseSalt(s string) (int, []byte, error) {
+	it, salt, _ := strings.Cut(s, ":")
+	iters, err := strconv.Atoi(it)
+	if err != nil {
+		return 0, nil, err
+	}
+	if iters < 1 || iters > maxScramIterations {
+		return 0, nil, strconv.ErrRange
+	}
+	b, err := base64.StdEncoding.DecodeString(salt)
+	return iters, b, err
+}
+
+// Argon2id derives a key from a password and salt.
+// It is used by the Argon2id builtin.
+func Argon2id(password, salt string, iterations int) string {
+	return hacks.BStoS(argon2.IDKey([]byte(password), []byte(salt),
+		uint32(iterations), 64*1024, 1, 32))
+}
+
+func hmacSha256(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+// scramKeys returns the ClientKey, StoredKey, and ServerKey for a password
+func scramKeys(password string, salt []byte, iterations int) (
+	clientKey, storedKey, serverKey []byte) {
+	salted := []byte(Argon2id(password, string(salt), iterations))
+	clientKey = hmacSha256(salted, "Client Key")
+	sk := sha256.Sum256(clientKey)
+	return clientKey, sk[:], hmacSha256(salted, "Server Key")
+}
+
+// ScramPassHash returns a passhash for the users table
+// with a new random salt.
+// It is used by the ScramPassHash builtin.
+func ScramPassHash(password string, iterations int) string {
+	salt := randomBytes(saltSize)
+	_, storedKey, serverKey := scramKeys(password, salt, iterations)
+	enc := base64.StdEncoding.EncodeToString
+	return scramPrefix + strconv.Itoa(iterations) + ":" + enc(salt) + "$" +
+		enc(storedKey) + ":" + enc(serverKey)
+}
+
+// Salt returns SCRAM-SHA-256$<iterations>:<salt> for a user.
+// Nonexistent users and users with a legacy passhash
+// get a consistent fake salt so Salt does not reveal
+// which users exist or which have legacy passhash values.
+// Like Auth, it is rate limited.
+// It is used by dbms.Salt
+func Salt(th *Thread, user string) string {
+	authLimiter.Wait(authContext)
+	iters, salt, _, ok := parseScram(getPassHash(th, user))
+	if !ok {
+		iters = ScramIterations
+		salt = hmacSha256(fakeSaltKey(), user)[:saltSize]
+	}
+	return scramPrefix + strconv.Itoa(iters) + ":" +
+		base64.StdEncoding.EncodeToString(salt)
+}
+
+// PasswordAuth returns the Auth data for a user and password
+// given the result of Salt and a nonce.
+// If salt is "" it uses the legacy method.
+func PasswordAuth(user, password, salt, nonce string) string {
+	if salt == "" {
+		passhash := md5.Sum([]byte(user + password)) // same as stdlib PassHash
+		hash := sha1.Sum([]byte(nonce + string(passhash[:])))
+		return user + "\x00" + string(hash[:])
+	}
+	params, ok := strings.CutPrefix(salt, scramPrefix)
+	if !ok {
+		panic("Auth: invalid salt")
+	}
+	iters, b, err := parseSalt(params)
+	if err != nil {
+		panic("Auth: invalid salt")
+	}
+	clientKey, storedKey, _ := scramKeys(password, b, iters)
+	proof := hmacSha256(storedKey, user+"\x00"+nonce)
+	subtle.XORBytes(proof, proof, clientKey)
+	return scramName + "\x00" + user + "\x00" + string(proof)
+}
+
+// AuthPassword authorizes the connection with a user and password
+// using Salt, Nonce, and Auth.
+// Since Salt does not reveal legacy passhash values,
+// if SCRAM fails it tries the legacy method.
+// It is used by Database.AuthUser and replicas.
+func AuthPassword(th *Thread, dbms IDbms, user, password string) bool {
+	salt := dbms.Salt(th, user)
+	if dbms.Auth(th, PasswordAuth(user, password, salt, dbms.Nonce(th))) {
+		return true
+	}
+	return dbms.Auth(th, PasswordAuth(user, password, "", dbms.Nonce(th)))
 }
 
 func getPassHash(th *Thread, user string) (result string) {
@@ -91,3 +324,18 @@ func getPassHash(th *Thread, user string) (result string) {
 	hash := Unpack(row.GetRaw(hdr, "passhash"))
 	return string(hash.(SuStr))
 }
+
+// userExists returns whether user is in the users table.
+// It is used to map client certificate subjects to users.
+func (dbms *DbmsLocal) userExists(user string) bool {
+	rt := dbms.db.NewReadTran()
+	if rt.GetInfo("users") == nil {
+		return false
+	}
+	for i, ix := range rt.GetSchema("users").Indexes {
+		if ix.Mode == 'k' && slices.Equal(ix.Columns, []string{"user"}) {
+			return rt.Lookup("users", i, Pack(SuStr(user))) != nil
+		}
+	}
+	return false
+}
@@ -6,7 +6,10 @@
 package dbms
 
 import (
+	"crypto/md5"
 	"crypto/sha1"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -49,6 +52,76 @@ func TestAuthUser(*testing.T) {
 	assert.True(AuthUser(&Thread{}, s, nonce))
 }
 
+func TestScramAuth(t *testing.T) {
+	originalLimiter := authLimiter
+	defer func() { authLimiter = originalLimiter }()
+	authLimiter = rate.NewLimiter(rate.Inf, 1)
+	SaltKeyFile = t.TempDir() + "/suneido.saltkey"
+
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	qry.DoAdmin(db, "create users (user, passhash) key(user)", nil)
+	ut := db.NewUpdateTran()
+	output := func(user, passhash string) {
+		var b RecordBuilder
+		ut.Output(nil, "users",
+			b.Add(SuStr(user)).Add(SuStr(passhash)).Build())
+	}
+	output("joe", ScramPassHash("secret", 1))
+	legacy := md5.Sum([]byte("fred" + "secret"))
+	output("fred", string(legacy[:]))
+	ut.Commit()
+	dbms := NewDbmsLocal(db)
+	GetDbms = func() IDbms { return dbms }
+	th := &Thread{}
+
+	auth := func(user, password string) bool {
+		nonce := Nonce()
+		return AuthUser(th, PasswordAuth(user, password, Salt(th, user), nonce),
+			nonce)
+	}
+	assert.T(t).That(auth("joe", "secret"))
+	assert.T(t).That(!auth("joe", "wrong"))
+	assert.T(t).That(!auth("bob", "secret"))
+
+	// legacy passhash still works, with the fallback in AuthPassword
+	defer func(orig bool) { DbmsAuth = orig }(DbmsAuth)
+	authPassword := func(user, password string) bool {
+		DbmsAuth = false
+		return AuthPassword(th, dbms, user, password)
+	}
+	assert.T(t).That(!auth("fred", "secret"))
+	assert.T(t).That(authPassword("fred", "secret"))
+	assert.T(t).That(!authPassword("fred", "wrong"))
+	assert.T(t).That(authPassword("joe", "secret"))
+	assert.T(t).That(!authPassword("bob", "secret"))
+
+	// the stored passhash can not be used with the legacy method
+	passhash := getPassHash(th, "joe")
+	nonce := Nonce()
+	hash := sha1.Sum([]byte(nonce + passhash))
+	assert.T(t).That(!AuthUser(th, "joe\x00"+string(hash[:]), nonce))
+
+	// nonexistent and legacy users get a consistent fake salt
+	salt := Salt(th, "bob")
+	assert.T(t).That(strings.HasPrefix(salt, scramPrefix))
+	assert.T(t).This(Salt(th, "bob")).Is(salt)
+	assert.T(t).That(Salt(th, "sam") != salt)
+	legacySalt := Salt(th, "fred")
+	assert.T(t).That(strings.HasPrefix(legacySalt, scramPrefix))
+	assert.T(t).This(len(legacySalt)).Is(len(salt))
+	// the key persists e.g. across restarts
+	key, err := os.ReadFile(SaltKeyFile)
+	assert.T(t).This(err).Is(nil)
+	assert.T(t).This(key).Is(fakeSaltKey())
+	nonce = Nonce()
+	hash = sha1.Sum([]byte(nonce))
+	assert.T(t).That(!AuthUser(th, "bob\x00"+string(hash[:]), nonce))
+
+	// same password gets different salts
+	assert.T(t).That(ScramPassHash("secret", 1) != ScramPassHash("secret", 1))
+}
+
 func TestAuthRateLimit(t *testing.T) {
 	originalLimiter := authLimiter
 	defer func() { authLimiter = originalLimiter }()
@@ -0,0 +1,105 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package dbms
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19/tools"
+)
+
+// An online backup is a dump of the entire database
+// that runs in the background rather than in a session's worker.
+// It produces the same format as Database.Dump.
+// Only one backup can run at a time.
+// Any session can get the progress of the backup or cancel it.
+
+var backup struct {
+	ctl     *tools.DumpCtl
+	to      string
+	err     string
+	start   time.Time
+	end     time.Time
+	lock    sync.Mutex
+	running bool
+}
+
+// Backup starts a background dump of the database.
+// bytesPerSec limits the rate of writing, 0 means no limit.
+// It returns "" if the backup was started, otherwise an error message.
+func (dbms *DbmsLocal) Backup(to, publicKey string, bytesPerSec int) string {
+	if to == "" {
+		to = "database.su"
+	}
+	backup.lock.Lock()
+	defer backup.lock.Unlock()
+	if backup.running {
+		return "backup already running (to " + backup.to + ")"
+	}
+	if dbms.db.IsCorrupted() {
+		return "backup not allowed when database is locked"
+	}
+	ctl := tools.NewDumpCtl(bytesPerSec)
+	backup.ctl = ctl
+	backup.to = to
+	backup.err = ""
+	backup.start = time.Now()
+	backup.end = time.Time{}
+	backup.running = true
+	go func() {
+		_, _, err := tools.DumpOnline(dbms.db, to, publicKey, ctl)
+		backup.lock.Lock()
+		defer backup.lock.Unlock()
+		backup.running = false
+		backup.end = time.Now()
+		if err != nil {
+			backup.err = err.Error()
+			log.Println("backup to", to, "failed:", err)
+		} else {
+			log.Println("backup to", to, "finished in",
+				backup.end.Sub(backup.start).Round(time.Second))
+		}
+	}()
+	return ""
+}
+
+// BackupStatus returns an object with the progress of the current backup
+// or the result of the last one.
+func (*DbmsLocal) BackupStatus() Value {
+	backup.lock.Lock()
+	defer backup.lock.Unlock()
+	ob := &SuObject{}
+	ob.Set(SuStr("running"), SuBool(backup.running))
+	if backup.ctl == nil {
+		return ob // no backup since startup
+	}
+	ob.Set(SuStr("to"), SuStr(backup.to))
+	ob.Set(SuStr("tables"), IntVal(int(backup.ctl.Tables.Load())))
+	ob.Set(SuStr("nTables"), IntVal(int(backup.ctl.NTables.Load())))
+	ob.Set(SuStr("bytes"), Int64Val(backup.ctl.Bytes.Load()))
+	end := backup.end
+	if backup.running {
+		end = time.Now()
+	}
+	ob.Set(SuStr("seconds"), IntVal(int(end.Sub(backup.start).Seconds())))
+	if backup.err != "" {
+		ob.Set(SuStr("error"), SuStr(backup.err))
+	}
+	return ob
+}
+
+// BackupCancel stops the current backup.
+// It returns false if there was no backup running.
+func (*DbmsLocal) BackupCancel() bool {
+	backup.lock.Lock()
+	defer backup.lock.Unlock()
+	if !backup.running {
+		return false
+	}
+	backup.ctl.Cancel()
+	return true
+}
@@ -0,0 +1,75 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package dbms
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
+)
+
+// maxChangesWait limits how long Changes will tie up a connection
+const maxChangesWait = time.Minute
+
+func (dbms *DbmsLocal) Changes(th *Thread, tables []string, since SuDate,
+	limit int, wait time.Duration) *SuObject {
+	deadline := time.Now().Add(min(wait, maxChangesWait))
+	for {
+		waitChanges := dbms.db.WaitChanges()
+		list := dbms.changes(th, tables, since, limit)
+		if list.ListSize() > 0 || !time.Now().Before(deadline) {
+			return list
+		}
+		waitChanges(time.Until(deadline))
+	}
+}
+
+func (dbms *DbmsLocal) changes(th *Thread, tables []string, since SuDate,
+	limit int) *SuObject {
+	list := &SuObject{}
+	tran := dbms.db.NewReadTran()
+	if tran.GetInfo(db19.ChangesTable) == nil || limit <= 0 {
+		return list
+	}
+	var sb strings.Builder
+	sb.WriteString(db19.ChangesTable)
+	params := &SuObject{}
+	if since != NilDate {
+		sb.WriteString(" where timestamp > :since")
+		params.Set(SuStr("since"), since)
+	}
+	if len(tables) > 0 {
+		if since != NilDate {
+			sb.WriteString(" and table in (")
+		} else {
+			sb.WriteString(" where table in (")
+		}
+		for i, table := range tables {
+			name := "t" + strconv.Itoa(i)
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(":" + name)
+			params.Set(SuStr(name), SuStr(table))
+		}
+		sb.WriteString(")")
+	}
+	sb.WriteString(" sort timestamp")
+	query := sb.String()
+	q, ps := qry.ParseParams(query, params, tran, nil)
+	q, _, _ = plans.setup(planKey("changes", query, params, nil), q, ps,
+		qry.ReadMode, false, tran)
+	hdr := q.Header()
+	for row := q.Get(th, Next); row != nil; row = q.Get(th, Next) {
+		list.Add(SuRecordFromRow(row, hdr, "", nil).ToObject())
+		if list.ListSize() >= limit {
+			break
+		}
+	}
+	return list
+}
@@ -0,0 +1,84 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+//go:build !gui
+
+package dbms
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestChanges(t *testing.T) {
+	assert := assert.T(t)
+	db19.StartTimestamps()
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	db19.MakeSuTran = func(ut *db19.UpdateTran) *SuTran {
+		return NewSuTran(nil, true)
+	}
+	qry.DoAdmin(db, "create tmp (a, b) key(a)", nil)
+	qry.DoAdmin(db, "create tmp2 (a, b) key(a)", nil)
+	qry.DoAdmin(db, "create capture (table) key(table)", nil)
+	dbms := NewDbmsLocal(db)
+	th := &Thread{}
+	action := func(act string) {
+		ut := db.NewUpdateTran()
+		qry.DoAction(nil, ut, act)
+		ut.Commit()
+	}
+	// no changes table
+	assert.This(dbms.Changes(th, nil, NilDate, 10, 0).ListSize()).Is(0)
+
+	qry.DoAdmin(db,
+		"create changes (timestamp, table, action, before, after) key(timestamp)",
+		nil)
+	action("insert { table: 'tmp' } into capture")
+	action("insert { table: 'tmp2' } into capture")
+	action("insert { a: 1, b: 2 } into tmp")
+	action("insert { a: 1 } into tmp2")
+	action("update tmp set b = 3")
+	action("delete tmp")
+
+	str := func(list *SuObject) string {
+		s := ""
+		for i := range list.ListSize() {
+			ob := list.ListGet(i).(*SuObject)
+			s += ToStr(ob.Get(nil, SuStr("table"))) + " " +
+				ToStr(ob.Get(nil, SuStr("action"))) + ", "
+		}
+		return s
+	}
+	list := dbms.Changes(th, nil, NilDate, 10, 0)
+	assert.This(str(list)).
+		Is("tmp insert, tmp2 insert, tmp update, tmp delete, ")
+	list = dbms.Changes(th, []string{"tmp"}, NilDate, 10, 0)
+	assert.This(str(list)).Is("tmp insert, tmp update, tmp delete, ")
+	ob := list.ListGet(1).(*SuObject)
+	assert.This(ob.Get(nil, SuStr("before")).Get(nil, SuStr("b"))).Is(IntVal(2))
+	assert.This(ob.Get(nil, SuStr("after")).Get(nil, SuStr("b"))).Is(IntVal(3))
+
+	// resume from a position
+	list = dbms.Changes(th, nil, NilDate, 2, 0)
+	assert.This(str(list)).Is("tmp insert, tmp2 insert, ")
+	since := list.ListGet(1).Get(nil, SuStr("timestamp")).(SuDate)
+	list = dbms.Changes(th, nil, since, 10, 0)
+	assert.This(str(list)).Is("tmp update, tmp delete, ")
+	since = list.ListGet(1).Get(nil, SuStr("timestamp")).(SuDate)
+	assert.This(dbms.Changes(th, nil, since, 10, 0).ListSize()).Is(0)
+
+	// wait for new changes
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		action("insert { a: 5 } into tmp")
+	}()
+	list = dbms.Changes(th, []string{"tmp"}, since, 10, 10*time.Second)
+	assert.This(str(list)).Is("tmp insert, ")
+}
@@ -6,7 +6,6 @@ package dbms
 import (
 	"bytes"
 	"crypto/tls"
-	"crypto/x509"
 	_ "embed"
 	"errors"
 	"io"
@@ -41,14 +40,9 @@ func ConnectClient(addr string, port string) net.Conn {
 		cantConnect(errmsg)
 	}
 	// Upgrade to TLS after successful hello
-	caCertPool := x509.NewCertPool()
-	ok := caCertPool.AppendCertsFromPEM(ServerCert)
-	if !ok {
-		cantConnect("Failed to append embedded cert to pool")
-	}
-	config := &tls.Config{
-		RootCAs:    caCertPool,
-		ServerName: "localhost", // Must match CN or SAN
+	config, err := clientTLSConfig(addr)
+	if err != nil {
+		cantConnect("TLS: " + err.Error())
 	}
 	tlsConn := tls.Client(conn, config)
 	if err := tlsConn.Handshake(); err != nil {
@@ -49,4 +49,9 @@ const (
 	WriteCount
 	EndSession
 	Asof
+	Replicate
+	Salt
+	QueryParams
+	CursorParams
+	Changes
 )
@@ -8,6 +8,7 @@ package dbms
 import (
 	"crypto/tls"
 	"net"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -15,6 +16,7 @@ import (
 
 	. "github.com/apmckinlay/gsuneido/core"
 	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/meta/schema"
 	"github.com/apmckinlay/gsuneido/db19/stor"
 	"github.com/apmckinlay/gsuneido/dbms/mux"
 	"github.com/apmckinlay/gsuneido/options"
@@ -23,9 +25,39 @@ import (
 
 func TestClientServer(*testing.T) {
 	// trace.Set(int(trace.ClientServer))
-	options.BuiltDate = "Dec 29 2020 12:34"
 	db := db19.CreateDb(stor.HeapStor(8192))
-	dbmsLocal := NewDbmsLocal(db)
+	c := testClient(NewDbmsLocal(db))
+	ses := c.NewSession()
+	args := SuObjectOf(SuStr("tables sort table"))
+	ses.Get(nil, args, Next)
+
+	ses2 := c.NewSession()
+	ses2.Get(nil, args, Prev)
+	ses2.Close()
+
+	time.Sleep(25 * time.Millisecond)
+}
+
+func TestReplicate(t *testing.T) {
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db.CheckerSync()
+	db.Create(&schema.Schema{Table: "tbl", Columns: []string{"a"},
+		Indexes: []schema.Index{{Mode: 'k', Columns: []string{"a"}}}})
+	db.PersistSync()
+	db.PersistSync() // replication lags by one persist
+	ses := testClient(NewDbmsLocal(db)).NewSession()
+	replica, err := db19.OpenReplica(filepath.Join(t.TempDir(), "replica.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+	for replicate(ses, replica) {
+	}
+	assert.T(t).That(replica.NewReadTran().GetInfo("tbl") != nil)
+}
+
+func testClient(dbmsLocal *DbmsLocal) *dbmsClient {
+	options.BuiltDate = "Dec 29 2020 12:34"
 	p1, p2 := net.Pipe()
 	workers = mux.NewWorkers(doRequest)
 	cert, err := tls.X509KeyPair(ServerCert, ServerKey)
@@ -48,16 +80,7 @@ func TestClientServer(*testing.T) {
 	if err := tlsConn.Handshake(); err != nil {
 		panic(err)
 	}
-	c := NewDbmsClient(tlsConn)
-	ses := c.NewSession()
-	args := SuObjectOf(SuStr("tables sort table"))
-	ses.Get(nil, args, Next)
-
-	ses2 := c.NewSession()
-	ses2.Get(nil, args, Prev)
-	ses2.Close()
-
-	time.Sleep(25 * time.Millisecond)
+	return NewDbmsClient(tlsConn)
 }
 
 var A atomic.Bool
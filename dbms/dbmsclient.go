@@ -10,6 +10,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"slices"
 
@@ -105,6 +106,17 @@ func SendErrorLog(dbms IDbms, sid string) {
 	}
 }
 
+func (ms *muxSession) Changes(_ *Thread, tables []string, since SuDate,
+	limit int, wait time.Duration) *SuObject {
+	ms.PutCmd(commands.Changes).PutStrs(tables).PutBool(since != NilDate)
+	if since != NilDate {
+		ms.PutVal(since)
+	}
+	ms.PutInt(limit).PutInt64(wait.Milliseconds())
+	ms.Request()
+	return ms.GetVal().(*SuObject)
+}
+
 func (ms *muxSession) Check(full bool) string {
 	ms.PutCmd(commands.Check).PutBool(full)
 	ms.Request()
@@ -122,8 +134,12 @@ func (ms *muxSession) Connections() Value {
 	return ms.GetVal().(*SuObject)
 }
 
-func (ms *muxSession) Cursor(query string, _ *Sviews) ICursor {
-	ms.PutCmd(commands.Cursor).PutStr(query)
+func (ms *muxSession) Cursor(query string, params Value, _ *Sviews) ICursor {
+	if params == nil {
+		ms.PutCmd(commands.Cursor).PutStr(query)
+	} else {
+		ms.PutCmd(commands.CursorParams).PutStr(query).PutVal(params)
+	}
 	ms.Request()
 	cn := ms.GetInt()
 	return ms.newClientCursor(cn)
@@ -222,6 +238,12 @@ func (ms *muxSession) Nonce(*Thread) string {
 	return ms.GetStr_()
 }
 
+func (ms *muxSession) Salt(_ *Thread, user string) string {
+	ms.PutCmd(commands.Salt).PutStr(user)
+	ms.Request()
+	return ms.GetStr()
+}
+
 func (ms *muxSession) Run(_ *Thread, code string) Value {
 	ms.PutCmd(commands.Run).PutStr(code)
 	ms.Request()
@@ -352,8 +374,13 @@ func (tc *muxTran) Get(_ *Thread, query Value, dir Dir) (Row, *Header, string) {
 	return tc.get(tc.tn, query, dir)
 }
 
-func (tc *muxTran) Query(query string, _ *Sviews) IQuery {
-	tc.PutCmd(commands.Query).PutInt(tc.tn).PutStr(query)
+func (tc *muxTran) Query(query string, params Value, _ *Sviews) IQuery {
+	if params == nil {
+		tc.PutCmd(commands.Query).PutInt(tc.tn).PutStr(query)
+	} else {
+		tc.PutCmd(commands.QueryParams).PutInt(tc.tn).PutStr(query).
+			PutVal(params)
+	}
 	tc.Request()
 	qn := tc.GetInt()
 	return tc.muxSession.newClientQuery(qn)
@@ -4,10 +4,12 @@
 package dbms
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"slices"
 
@@ -36,6 +38,10 @@ type DbmsLocal struct {
 func NewDbmsLocal(db *db19.Database) *DbmsLocal {
 	dbms := DbmsLocal{db: db}
 	dbms.libraries.Store([]string{"stdlib"})
+	qry.GetSessions = sessions
+	qry.GetTranInfo = db.TranInfo
+	qry.GetExclusive = db.Exclusive
+	qry.GetServerInfo = func() *SuObject { return dbms.Info().(*SuObject) }
 	return &dbms
 }
 
@@ -48,6 +54,29 @@ func (dbms *DbmsLocal) Admin(admin string, sv *Sviews) {
 	qry.DoAdmin(dbms.db, admin, sv)
 }
 
+// Analyze saves column statistics for a table (for query optimization)
+// and returns a summary of them
+func (dbms *DbmsLocal) Analyze(table string) Value {
+	st := dbms.db.Analyze(table)
+	cols := &SuObject{}
+	for i := range st.Cols {
+		cs := &st.Cols[i]
+		ob := &SuObject{}
+		ob.Set(SuStr("distinct"), IntVal(cs.Distinct))
+		common := &SuObject{}
+		for j, v := range cs.Mcv {
+			common.Set(Unpack(v), IntVal(cs.McvCount[j]))
+		}
+		ob.Set(SuStr("common"), common)
+		ob.Set(SuStr("buckets"), IntVal(max(len(cs.Hist)-1, 0)))
+		cols.Set(SuStr(cs.Column), ob)
+	}
+	result := &SuObject{}
+	result.Set(SuStr("nrows"), IntVal(st.Nrows))
+	result.Set(SuStr("columns"), cols)
+	return result
+}
+
 func (dbms *DbmsLocal) Auth(th *Thread, s string) bool {
 	if DbmsAuth {
 		panic("already authorized")
@@ -79,18 +108,30 @@ func (*DbmsLocal) Connections() Value {
 	return connections()
 }
 
-func (dbms *DbmsLocal) Cursor(query string, sv *Sviews) ICursor {
+func (dbms *DbmsLocal) Cursor(query string, params Value, sv *Sviews) ICursor {
+	t := time.Now()
 	tran := dbms.db.NewReadTran()
-	q, fixcost, varcost := buildQuery(query, tran, sv, qry.CursorMode)
+	q, fixcost, varcost := buildQuery(query, params, tran, sv, qry.CursorMode)
 	trace.Query.Println("cursor", fixcost+varcost, "-", query)
-	return cursorLocal{queryLocal{
-		Query: q, cost: fixcost + varcost, mode: qry.CursorMode}}
+	return cursorLocal{queryLocal{Query: q, cost: fixcost + varcost,
+		mode: qry.CursorMode, timer: newQueryTimer(query, t)}}
 }
 
-func buildQuery(query string, tran qry.QueryTran, sv *Sviews,
+// buildQuery parses and sets up a query.
+// If there are params it uses the plan cache (see plancache.go)
+func buildQuery(query string, params Value, tran qry.QueryTran, sv *Sviews,
 	mode qry.Mode) (qry.Query, int, int) {
-	q := qry.ParseQuery(query, tran, sv)
-	q, fixcost, varcost := qry.Setup(q, mode, tran)
+	var q qry.Query
+	var ps qry.PlanParams
+	var key SuStr
+	if params == nil {
+		q = qry.ParseQuery(query, tran, sv)
+	} else {
+		ob := toParams(params)
+		q, ps = qry.ParseParams(query, ob, tran, sv)
+		key = planKey("query "+mode.String(), query, ob, sv)
+	}
+	q, fixcost, varcost := plans.setup(key, q, ps, mode, false, tran)
 	qry.Warnings(query, q)
 	return q, fixcost, varcost
 }
@@ -110,13 +151,22 @@ func (dbms *DbmsLocal) EnableTrigger(table string) {
 	dbms.db.EnableTrigger(table)
 }
 
-func (dbms *DbmsLocal) Dump(table, to, publicKey string) string {
+// Dump dumps the entire database or a single table.
+// If since is not zero, only the tables that have changed
+// since then are dumped (see tools.DumpSince)
+func (dbms *DbmsLocal) Dump(table, to, publicKey string, since int64) string {
 	var err error
 	if table == "" {
 		if to == "" {
 			to = "database.su"
 		}
-		_, _, err = tools.Dump(dbms.db, to, publicKey)
+		if since != 0 {
+			_, _, err = tools.DumpSince(dbms.db, to, publicKey, since)
+		} else {
+			_, _, err = tools.Dump(dbms.db, to, publicKey)
+		}
+	} else if since != 0 {
+		err = errors.New("dump: since is only valid for the entire database")
 	} else {
 		if to == "" {
 			to = table + ".su"
@@ -158,6 +208,9 @@ func (dbms *DbmsLocal) Info() Value {
 	ob := &SuObject{}
 	ob.Set(SuStr("currentSize"), Int64Val(int64(dbms.db.Size())))
 	ob.Set(SuStr("timeoutMin"), IntVal(int(options.TimeoutMinutes)))
+	if dbms.db.IsReplica() {
+		ob.Set(SuStr("replica"), True)
+	}
 	return ob
 }
 
@@ -168,11 +221,23 @@ func (*DbmsLocal) Kill(addr string) int {
 	panic("Kill is only available client-server")
 }
 
-func (dbms *DbmsLocal) Load(table, from, privateKey, passphrase string) int {
+// Load loads a single table.
+// If there are incremental dumps (from Dump with since)
+// the table is loaded from the latest dump that contains it.
+func (dbms *DbmsLocal) Load(table, from, privateKey, passphrase string,
+	incremental []string) int {
 	if from == "" {
 		from = table + ".su"
 	}
-	n, err := tools.LoadDbTable(table, from, privateKey, passphrase, dbms.db)
+	var n int
+	var err error
+	if len(incremental) == 0 {
+		n, err = tools.LoadDbTable(table, from, privateKey, passphrase, dbms.db)
+	} else {
+		froms := append([]string{from}, incremental...)
+		n, err = tools.LoadDbTableChain(table, froms,
+			privateKey, passphrase, dbms.db)
+	}
 	if err != nil {
 		panic(err.Error())
 	}
@@ -261,6 +326,10 @@ func (*DbmsLocal) Nonce(th *Thread) string {
 	return th.Nonce
 }
 
+func (*DbmsLocal) Salt(th *Thread, user string) string {
+	return Salt(th, user)
+}
+
 func (*DbmsLocal) Run(th *Thread, s string) Value {
 	defer UseMainSuneido(th)()
 	trace.Dbms.Println("Run", s)
@@ -358,6 +427,15 @@ func (dbms *DbmsLocal) FormatQuery(query string) string {
 	return qry.Format(t, query)
 }
 
+// AnalyzeQuery runs a query and returns its strategy
+// annotated with actual row counts and times (see query.Analyze)
+func (dbms *DbmsLocal) AnalyzeQuery(th *Thread, query string) string {
+	tran := dbms.db.NewReadTran()
+	defer tran.Complete()
+	q, _, _ := buildQuery(query, nil, tran, th.Sviews(), qry.ReadMode)
+	return qry.Analyze(th, q)
+}
+
 func (dbms *DbmsLocal) Close() {
 	dbms.db.Close()
 }
@@ -394,10 +472,13 @@ func (t ReadTranLocal) Get(th *Thread, query Value, dir Dir) (Row, *Header, stri
 	return get(th, t.ReadTran, query, dir)
 }
 
-func (t ReadTranLocal) Query(query string, sv *Sviews) IQuery {
-	q, fixcost, varcost := buildQuery(query, t.ReadTran, sv, qry.ReadMode)
+func (t ReadTranLocal) Query(query string, params Value, sv *Sviews) IQuery {
+	start := time.Now()
+	q, fixcost, varcost := buildQuery(query, params, t.ReadTran, sv,
+		qry.ReadMode)
 	trace.Query.Println(fixcost+varcost, "-", query)
-	return queryLocal{Query: q, cost: fixcost + varcost, mode: qry.ReadMode}
+	return queryLocal{Query: q, cost: fixcost + varcost, mode: qry.ReadMode,
+		timer: newQueryTimer(query, start)}
 }
 
 func (t ReadTranLocal) Action(*Thread, string) int {
@@ -418,10 +499,13 @@ func (t UpdateTranLocal) Output(th *Thread, table string, rec Record) {
 	assert.ShouldNotReachHere()
 }
 
-func (t UpdateTranLocal) Query(query string, sv *Sviews) IQuery {
-	q, fixcost, varcost := buildQuery(query, t.UpdateTran, sv, qry.UpdateMode)
+func (t UpdateTranLocal) Query(query string, params Value, sv *Sviews) IQuery {
+	start := time.Now()
+	q, fixcost, varcost := buildQuery(query, params, t.UpdateTran, sv,
+		qry.UpdateMode)
 	trace.Query.Println("update", fixcost+varcost, "-", query)
-	return queryLocal{Query: q, cost: fixcost + varcost, mode: qry.UpdateMode}
+	return queryLocal{Query: q, cost: fixcost + varcost, mode: qry.UpdateMode,
+		timer: newQueryTimer(query, start)}
 }
 
 func (t UpdateTranLocal) Action(th *Thread, action string) int {
@@ -455,9 +539,10 @@ func UseMainSuneido(th *Thread) func() {
 type queryLocal struct {
 	// Query is embedded so most methods are "inherited" directly
 	qry.Query
-	keys []string // cache
-	cost qry.Cost
-	mode qry.Mode
+	keys  []string // cache
+	cost  qry.Cost
+	mode  qry.Mode
+	timer *queryTimer // for the slow query log, nil if disabled
 }
 
 func (q queryLocal) Keys() []string {
@@ -495,6 +580,9 @@ func (q queryLocal) Order() []string {
 // And the underlying OverIter just sticks at eof regardless of direction.
 func (q queryLocal) Get(th *Thread, dir Dir) (Row, string) {
 	defer UseMainSuneido(th)()
+	if q.timer != nil {
+		defer q.timer.add(th, time.Now())
+	}
 	row := q.Query.Get(th, dir)
 	if row == nil {
 		// this is required for SuQuery to stick at eof unidirectionally
@@ -514,6 +602,7 @@ func (q queryLocal) Tree() Value {
 }
 
 func (q queryLocal) Close() {
+	q.timer.close(q.Query)
 }
 
 // cursorLocal
@@ -523,6 +612,10 @@ type cursorLocal struct {
 }
 
 func (q cursorLocal) Get(th *Thread, t ITran, dir Dir) (Row, string) {
-	q.Query.SetTran(t.(qry.QueryTran))
+	if tr, ok := t.(*tranRestricted); ok {
+		q.Query.SetTran(tr.qt)
+	} else {
+		q.Query.SetTran(t.(qry.QueryTran))
+	}
 	return q.queryLocal.Get(th, dir)
 }
@@ -0,0 +1,230 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package dbms
+
+import (
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
+)
+
+// DbmsRestricted is a wrapper for DbmsLocal for server connections
+// authorized as a user with roles. See perms.go
+// The tables used by queries and actions are checked
+// before they are passed on to DbmsLocal.
+// It is also used as the thread dbms for the connection's requests
+// so code run on the server (e.g. rules) is restricted the same way.
+type DbmsRestricted struct {
+	*DbmsLocal
+	perms *perms
+}
+
+var _ IDbms = (*DbmsRestricted)(nil)
+
+func (dr *DbmsRestricted) Admin(admin string, sv *Sviews) {
+	dr.perms.check("admin", "")
+	dr.DbmsLocal.Admin(admin, sv)
+}
+
+// Changes requires read permission for the tables,
+// or for the changes table for all tables
+func (dr *DbmsRestricted) Changes(th *Thread, tables []string, since SuDate,
+	limit int, wait time.Duration) *SuObject {
+	if len(tables) == 0 {
+		dr.perms.check("read", db19.ChangesTable)
+	}
+	dr.perms.checkTables("read", tables)
+	return dr.DbmsLocal.Changes(th, tables, since, limit, wait)
+}
+
+func (dr *DbmsRestricted) Check(full bool) string {
+	dr.perms.check("admin", "")
+	return dr.DbmsLocal.Check(full)
+}
+
+// Connections requires the same permission as the server_sessions table
+func (dr *DbmsRestricted) Connections() Value {
+	dr.perms.check("read", "server_sessions")
+	return dr.DbmsLocal.Connections()
+}
+
+func (dr *DbmsRestricted) Cursor(query string, params Value, sv *Sviews) ICursor {
+	rt := dr.db.NewReadTran()
+	defer rt.Complete()
+	checkQuery(dr.perms, rt, query, toParams(params), sv)
+	return dr.DbmsLocal.Cursor(query, params, sv)
+}
+
+func (dr *DbmsRestricted) DisableTrigger(table string) {
+	dr.perms.check("admin", "")
+	dr.DbmsLocal.DisableTrigger(table)
+}
+
+func (dr *DbmsRestricted) EnableTrigger(table string) {
+	dr.perms.check("admin", "")
+	dr.DbmsLocal.EnableTrigger(table)
+}
+
+// Exec requires eval, except for the admin builtins
+// that clients run on the server with Exec.
+// They check admin themselves (with Local)
+func (dr *DbmsRestricted) Exec(th *Thread, args Value) Value {
+	if !adminExec[ToStr(ToContainer(args).ListGet(0))] {
+		dr.perms.check("eval", "")
+	}
+	return dr.DbmsLocal.Exec(th, args)
+}
+
+// adminExec is the builtins that only require admin from a client.
+// Builtins take precedence over library definitions
+// so these can not be redefined.
+var adminExec = map[string]bool{
+	"Database.Analyze":      true,
+	"Database.Backup":       true,
+	"Database.BackupCancel": true,
+	"Database.BackupStatus": true,
+	"Database.Dump":         true,
+	"Database.Load":         true,
+	"Query.Analyze":         true,
+}
+
+func (dr *DbmsRestricted) Final() int {
+	dr.perms.check("admin", "")
+	return dr.DbmsLocal.Final()
+}
+
+func (dr *DbmsRestricted) Get(th *Thread, query Value, dir Dir) (Row, *Header, string) {
+	rt := dr.db.NewReadTran()
+	defer rt.Complete()
+	ob := query.(*SuObject)
+	checkQuery(dr.perms, rt, getQuery(ob), getParams(ob), th.Sviews())
+	return dr.DbmsLocal.Get(th, query, dir)
+}
+
+func (dr *DbmsRestricted) Kill(addr string) int {
+	dr.perms.check("kill", "")
+	return dr.DbmsLocal.Kill(addr)
+}
+
+func (dr *DbmsRestricted) Run(th *Thread, code string) Value {
+	dr.perms.check("eval", "")
+	return dr.DbmsLocal.Run(th, code)
+}
+
+func (dr *DbmsRestricted) Schema(table string) string {
+	dr.perms.check("read", table)
+	return dr.DbmsLocal.Schema(table)
+}
+
+// Token returns a token that authorizes with the same permissions
+func (dr *DbmsRestricted) Token() string {
+	return tokenFor(dr.perms.user)
+}
+
+func (dr *DbmsRestricted) Transaction(update bool) ITran {
+	t := dr.DbmsLocal.Transaction(update)
+	return &tranRestricted{ITran: t, qt: t.(qry.QueryTran), perms: dr.perms}
+}
+
+// Transactions requires the same permission as the server_transactions table
+func (dr *DbmsRestricted) Transactions() *SuObject {
+	dr.perms.check("read", "server_transactions")
+	return dr.DbmsLocal.Transactions()
+}
+
+func (dr *DbmsRestricted) Unuse(lib string) bool {
+	dr.perms.check("admin", "")
+	return dr.DbmsLocal.Unuse(lib)
+}
+
+func (dr *DbmsRestricted) Use(lib string) bool {
+	dr.perms.check("admin", "")
+	return dr.DbmsLocal.Use(lib)
+}
+
+func (dr *DbmsRestricted) Unwrap() IDbms {
+	return dr
+}
+
+func checkQuery(p *perms, t qry.QueryTran, query string, params *SuObject,
+	sv *Sviews) []string {
+	var q qry.Query
+	if params != nil {
+		q = qry.ParseParamQuery(query, params, t, sv)
+	} else {
+		q = qry.ParseQuery(query, t, sv)
+	}
+	tables := qry.SourceTables(q)
+	p.checkTables("read", tables)
+	return tables
+}
+
+// tranRestricted --------------------------------------------------------
+
+type tranRestricted struct {
+	ITran
+	qt    qry.QueryTran
+	perms *perms
+}
+
+func (t *tranRestricted) Get(th *Thread, query Value, dir Dir) (Row, *Header, string) {
+	ob := query.(*SuObject)
+	checkQuery(t.perms, t.qt, getQuery(ob), getParams(ob), th.Sviews())
+	return t.ITran.Get(th, query, dir)
+}
+
+func (t *tranRestricted) Query(query string, params Value, sv *Sviews) IQuery {
+	tables := checkQuery(t.perms, t.qt, query, toParams(params), sv)
+	return &queryRestricted{IQuery: t.ITran.Query(query, params, sv),
+		tables: tables, perms: t.perms}
+}
+
+func (t *tranRestricted) Action(th *Thread, action string) int {
+	read, write := qry.ActionTables(qry.ParseAction(action, t.qt, th.Sviews()))
+	t.perms.checkTables("read", read)
+	t.perms.checkTables("write", write)
+	return t.ITran.Action(th, action)
+}
+
+func (t *tranRestricted) Update(th *Thread, table string, off uint64, rec Record) uint64 {
+	t.perms.check("write", table)
+	return t.ITran.Update(th, table, off, rec)
+}
+
+func (t *tranRestricted) Delete(th *Thread, table string, off uint64) {
+	t.perms.check("write", table)
+	t.ITran.Delete(th, table, off)
+}
+
+// queryRestricted -------------------------------------------------------
+
+type queryRestricted struct {
+	IQuery
+	tables []string
+	perms  *perms
+}
+
+func (q *queryRestricted) Output(th *Thread, rec Record) {
+	q.perms.checkTables("write", q.tables)
+	q.IQuery.Output(th, rec)
+}
+
+// Local returns the DbmsLocal for a standalone or server side dbms
+// or nil for a client (which should use Exec).
+// For a restricted connection priv (if not "") must be granted.
+// It is used by builtins that require a local database.
+func Local(dbms IDbms, priv string) *DbmsLocal {
+	switch dbms := dbms.(type) {
+	case *DbmsLocal:
+		return dbms
+	case *DbmsRestricted:
+		if priv != "" {
+			dbms.perms.check(priv, "")
+		}
+		return dbms.DbmsLocal
+	}
+	return nil
+}
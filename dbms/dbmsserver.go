@@ -23,9 +23,11 @@ import (
 	"github.com/apmckinlay/gsuneido/core/trace"
 	"github.com/apmckinlay/gsuneido/dbms/commands"
 	"github.com/apmckinlay/gsuneido/dbms/mux"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
 	"github.com/apmckinlay/gsuneido/options"
 	"github.com/apmckinlay/gsuneido/util/assert"
 	"github.com/apmckinlay/gsuneido/util/atomics"
+	"github.com/apmckinlay/gsuneido/util/hacks"
 	"github.com/apmckinlay/gsuneido/util/str"
 	"golang.org/x/time/rate"
 )
@@ -46,6 +48,8 @@ type serverConn struct {
 	conn       net.Conn
 	sessions   map[uint32]*serverSession // the sessions on this connection
 	remoteAddr string
+	user       atomics.String // set when authorized
+	connected  SuDate
 	Sviews
 	idleCount    int          // guarded by serverConnsLock
 	sessionsLock sync.Mutex   // guards sessions
@@ -81,12 +85,9 @@ var ServerKey []byte
 // Server listens and accepts connections. It never returns.
 func Server(dbms *DbmsLocal) {
 	workers = mux.NewWorkers(doRequest)
-	cert, err := tls.X509KeyPair(ServerCert, ServerKey)
+	config, err := serverTLSConfig()
 	if err != nil {
-		Fatal("Failed to load embedded key pair:", err)
-	}
-	config := &tls.Config{
-		Certificates: []tls.Certificate{cert},
+		Fatal("dbms server: TLS:", err)
 	}
 	// Listen for plain TCP connection to handle version mismatch
 	l, err := net.Listen("tcp", ":"+options.Port)
@@ -133,11 +134,12 @@ func idleCheck() {
 func expireTokens() {
 	tokensLock.Lock()
 	defer tokensLock.Unlock()
-	for token, old := range tokens {
-		if old {
+	for token, ti := range tokens {
+		if ti.old {
 			delete(tokens, token)
 		} else {
-			tokens[token] = true // mark it as old
+			ti.old = true
+			tokens[token] = ti
 		}
 	}
 }
@@ -180,9 +182,16 @@ func newServerConn(dbms *DbmsLocal, conn net.Conn, config *tls.Config) {
 	addr := str.BeforeLast(tlsConn.RemoteAddr().String(), ":") // strip port
 	msc := mux.NewServerConn(tlsConn)
 	sc := &serverConn{dbms: dbms, id: msc.Id(), conn: tlsConn, remoteAddr: addr,
-		sessions: make(map[uint32]*serverSession)}
+		sessions: make(map[uint32]*serverSession), connected: Now()}
 	if dbms.db.HaveUsers() {
-		sc.dbms = &DbmsUnauth{dbms: dbms}
+		if user := certUser(tlsConn.ConnectionState()); user != "" &&
+			dbms.userExists(user) {
+			sc.serverLog("authorized by certificate as", user)
+			sc.user.Store(user)
+			sc.dbms = restrict(dbms, user)
+		} else {
+			sc.dbms = &DbmsUnauth{dbms: dbms}
+		}
 	}
 	serverConnsLock.Lock()
 	serverConns[sc.id] = sc
@@ -244,6 +253,11 @@ func doRequest(wb *mux.WriteBuf, th *Thread, id uint64, req []byte) {
 	ss.WriteBuf = wb
 	th.SetSession(ss.sessionId.Load())
 	th.SetSviews(&sc.Sviews)
+	if dr, ok := sc.dbms.(*DbmsRestricted); ok {
+		th.SetDbms(dr) // so code run by the request is also restricted
+	} else {
+		th.SetDbms(nil) // default
+	}
 	ss.thread = th
 	ss.request()
 }
@@ -376,6 +390,9 @@ func cmdAbort(ss *serverSession) {
 func (ss *serverSession) deleteTran(tn int) {
 	delete(ss.trans, tn)
 	for qn := range ss.tranQueries[tn] {
+		if q := ss.queries[qn]; q != nil {
+			q.Close()
+		}
 		delete(ss.queries, qn)
 		delete(ss.queryTrans, qn)
 		if len(ss.queries) != len(ss.queryTrans) {
@@ -419,21 +436,24 @@ func cmdAuth(ss *serverSession) {
 	if _, ok := ss.sc.dbms.(*DbmsUnauth); !ok {
 		panic("already authorized")
 	}
-	result := ss.auth(s)
+	user, result := ss.auth(s)
 	if result {
-		ss.sc.dbms = ss.sc.dbms.(*DbmsUnauth).dbms // remove DbmsUnauth
+		// remove DbmsUnauth, applying any permissions for the user
+		ss.sc.user.Store(user)
+		ss.sc.dbms = restrict(ss.sc.dbms.(*DbmsUnauth).dbms, user)
 	}
 	ss.PutBool(true).PutBool(result)
 }
 
-func (ss *serverSession) auth(s string) bool {
+// auth returns the authorized user and whether authorization succeeded
+func (ss *serverSession) auth(s string) (string, bool) {
 	nonce := ss.sc.nonce
 	ss.sc.nonce = ""
 	ss.sc.nonceOld = false
 	if AuthUser(ss.thread, s, nonce) {
-		return true
+		return authUserName(s), true
 	}
-	return AuthToken(s)
+	return authToken(s)
 }
 
 func cmdAsof(ss *serverSession) {
@@ -443,6 +463,18 @@ func cmdAsof(ss *serverSession) {
 	ss.PutBool(true).PutInt64(tran.Asof(asof))
 }
 
+func cmdChanges(ss *serverSession) {
+	tables := ss.GetStrs()
+	since := NilDate
+	if ss.GetBool() {
+		since = ss.GetVal().(SuDate)
+	}
+	limit := ss.GetInt()
+	wait := time.Duration(ss.GetInt64()) * time.Millisecond
+	list := ss.sc.dbms.Changes(ss.thread, tables, since, limit, wait)
+	ss.PutBool(true).PutVal(list)
+}
+
 func cmdCheck(ss *serverSession) {
 	full := ss.GetBool()
 	s := ss.sc.dbms.Check(full)
@@ -509,9 +541,36 @@ func connections() *SuObject {
 	return list
 }
 
+// sessions returns the current sessions for the sessions system table
+func sessions() []qry.Session {
+	var list []qry.Session
+	serverConnsLock.Lock()
+	defer serverConnsLock.Unlock()
+	for _, sc := range serverConns {
+		sc.sessionsLock.Lock()
+		for _, ss := range sc.sessions {
+			list = append(list, qry.Session{Session: ss.sessionId.Load(),
+				User: sc.user.Load(), Address: sc.remoteAddr,
+				Connected: sc.connected})
+		}
+		sc.sessionsLock.Unlock()
+	}
+	return list
+}
+
 func cmdCursor(ss *serverSession) {
 	query := ss.GetStr()
-	q := ss.sc.dbms.Cursor(query, &ss.sc.Sviews)
+	startCursor(ss, query, nil)
+}
+
+func cmdCursorParams(ss *serverSession) {
+	query := ss.GetStr()
+	params := ss.GetVal()
+	startCursor(ss, query, params)
+}
+
+func startCursor(ss *serverSession, query string, params Value) {
+	q := ss.sc.dbms.Cursor(query, params, &ss.sc.Sviews)
 	num := int(lastNum.Add(1))
 	ss.cursors[num] = q
 	ss.PutBool(true).PutInt(num)
@@ -695,7 +754,7 @@ func cmdKeys(ss *serverSession) {
 
 func cmdKill(ss *serverSession) {
 	sessionId := ss.GetStr()
-	n := kill(sessionId)
+	n := ss.sc.dbms.Kill(sessionId)
 	ss.PutBool(true).PutInt(n)
 }
 
@@ -768,6 +827,11 @@ func cmdNonce(ss *serverSession) {
 	ss.PutBool(true).PutStr_(ss.sc.nonce)
 }
 
+func cmdSalt(ss *serverSession) {
+	user := ss.GetStr()
+	ss.PutBool(true).PutStr(Salt(ss.thread, user))
+}
+
 func cmdOrder(ss *serverSession) {
 	order := ss.getQorC().Order()
 	ss.PutBool(true).PutStrs(order)
@@ -801,7 +865,18 @@ func (ss *serverSession) getCursor() ICursor {
 func cmdQuery(ss *serverSession) {
 	tran, tn := ss.getTran()
 	query := ss.GetStr()
-	q := tran.Query(query, &ss.sc.Sviews)
+	startQuery(ss, tran, tn, query, nil)
+}
+
+func cmdQueryParams(ss *serverSession) {
+	tran, tn := ss.getTran()
+	query := ss.GetStr()
+	params := ss.GetVal()
+	startQuery(ss, tran, tn, query, params)
+}
+
+func startQuery(ss *serverSession, tran ITran, tn int, query string, params Value) {
+	q := tran.Query(query, params, &ss.sc.Sviews)
 	qn := int(lastNum.Add(1))
 	ss.queries[qn] = q
 	ss.queryTrans[qn] = tn
@@ -815,6 +890,24 @@ func cmdQuery(ss *serverSession) {
 	ss.PutBool(true).PutInt(qn)
 }
 
+// replicaChunk limits the data per Replicate response
+// to stay under the mux message size limit
+const replicaChunk = 512 * 1024
+
+func cmdReplicate(ss *serverSession) {
+	off := uint64(ss.GetInt64())
+	crc := uint32(ss.GetInt64())
+	dbms := Local(ss.sc.dbms, "admin")
+	if dbms == nil {
+		panic(notauth)
+	}
+	data, err := dbms.db.ReplicaData(off, crc, replicaChunk)
+	if err != nil {
+		panic(err.Error())
+	}
+	ss.PutBool(true).PutStr_(hacks.BStoS(data))
+}
+
 func cmdReadCount(ss *serverSession) {
 	ss.getTran()
 	ss.PutBool(true).PutInt(0) //TODO
@@ -932,9 +1025,15 @@ var cmds = []command{ // order must match commmands.go
 	cmdWriteCount,
 	cmdEndSession,
 	cmdAsof,
+	cmdReplicate,
+	cmdSalt,
+	cmdQueryParams,
+	cmdCursorParams,
+	cmdChanges,
 	nil,
 }
 
 func init() {
-	assert.That(cmds[commands.Asof] != nil && cmds[commands.Asof+1] == nil)
+	assert.That(cmds[commands.Changes] != nil &&
+		cmds[commands.Changes+1] == nil)
 }
@@ -3,14 +3,18 @@
 
 package dbms
 
-import . "github.com/apmckinlay/gsuneido/core"
+import (
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+)
 
 func Unauth(dbms *DbmsLocal) IDbms {
 	return &DbmsUnauth{dbms: dbms}
 }
 
 // DbmsUnauth is a wrapper for DbmsLocal for unauthorized client connections.
-// Only allows Auth, LibGet, Libraries, Nonce, SessionId, and Use
+// Only allows Auth, LibGet, Libraries, Nonce, Salt, SessionId, and Use
 type DbmsUnauth struct {
 	dbms *DbmsLocal
 }
@@ -27,6 +31,11 @@ func (du *DbmsUnauth) Auth(th *Thread, data string) bool {
 	return du.dbms.Auth(th, data)
 }
 
+func (du *DbmsUnauth) Changes(*Thread, []string, SuDate, int,
+	time.Duration) *SuObject {
+	panic(notauth)
+}
+
 func (du *DbmsUnauth) Check(bool) string {
 	panic(notauth)
 }
@@ -39,7 +48,7 @@ func (du *DbmsUnauth) Connections() Value {
 	panic(notauth)
 }
 
-func (du *DbmsUnauth) Cursor(string, *Sviews) ICursor {
+func (du *DbmsUnauth) Cursor(string, Value, *Sviews) ICursor {
 	panic(notauth)
 }
 
@@ -99,6 +108,10 @@ func (du *DbmsUnauth) Nonce(th *Thread) string {
 	return du.dbms.Nonce(th)
 }
 
+func (du *DbmsUnauth) Salt(th *Thread, user string) string {
+	return du.dbms.Salt(th, user)
+}
+
 func (du *DbmsUnauth) Run(*Thread, string) Value {
 	panic(notauth)
 }
@@ -6,10 +6,12 @@ package dbms
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	. "github.com/apmckinlay/gsuneido/core"
 	"github.com/apmckinlay/gsuneido/core/trace"
 	qry "github.com/apmckinlay/gsuneido/dbms/query"
+	"github.com/apmckinlay/gsuneido/options"
 	"github.com/apmckinlay/gsuneido/util/assert"
 	"github.com/apmckinlay/gsuneido/util/str"
 )
@@ -27,6 +29,7 @@ func get(th *Thread, tran qry.QueryTran, args Value, dir Dir) (Row, *Header, str
 
 	ob := args.(*SuObject)
 	query := getQuery(ob)
+	var q qry.Query
 	if dir == Only || dir == Any ||
 		(dir == Strat && qry.GetSort(query) == "") {
 		query = qry.StripSort(query)
@@ -34,22 +37,25 @@ func get(th *Thread, tran qry.QueryTran, args Value, dir Dir) (Row, *Header, str
 			return row, hdr, s
 		}
 	}
+	if options.SlowQueryMs > 0 {
+		start := time.Now()
+		defer func() {
+			if q != nil {
+				logSlowQuery(th.Session(), query, q, time.Since(start))
+			}
+		}()
+	}
 	if where := getWhere(ob); where != "" {
 		// need a newline in case the query ends with //comment
 		query += "\n" + where
 	}
-	q := qry.ParseQuery(query, tran, th.Sviews())
-	qs, sorted := q.(*qry.Sort)
-	if dir == Only || dir == Any {
-		if sorted {
-			q = qs.Source() // remove sort
-		}
-	} else if !sorted && dir != Strat &&
-		!strings.Contains(query, "CHECKQUERY SUPPRESS: SORT REQUIRED") {
-		panic("QueryFirst and QueryLast require sort")
+	params := getParams(ob)
+	var key SuStr
+	if dir != Strat {
+		key = planKey(getKind(dir), query, params, th.Sviews())
 	}
-
-	q, fixcost, varcost := qry.Setup1(q, qry.ReadMode, tran)
+	q, ps := getSetup(th, tran, query, params, dir)
+	q, fixcost, varcost := plans.setup(key, q, ps, qry.ReadMode, true, tran)
 	qry.Warnings(query, q)
 	if dir == Strat {
 		n, _ := q.Nrows()
@@ -82,6 +88,37 @@ func get(th *Thread, tran qry.QueryTran, args Value, dir Dir) (Row, *Header, str
 	return row, q.Header(), q.Updateable()
 }
 
+// getSetup parses the query and removes or checks the sort
+func getSetup(th *Thread, tran qry.QueryTran, query string, params *SuObject,
+	dir Dir) (qry.Query, qry.PlanParams) {
+	var q qry.Query
+	var ps qry.PlanParams
+	if params != nil {
+		q, ps = qry.ParseParams(query, params, tran, th.Sviews())
+	} else {
+		q = qry.ParseQuery(query, tran, th.Sviews())
+	}
+	qs, sorted := q.(*qry.Sort)
+	if dir == Only || dir == Any {
+		if sorted {
+			q = qs.Source() // remove sort
+		}
+	} else if !sorted && dir != Strat &&
+		!strings.Contains(query, "CHECKQUERY SUPPRESS: SORT REQUIRED") {
+		panic("QueryFirst and QueryLast require sort")
+	}
+	return q, ps
+}
+
+// getKind is used for the plan cache key.
+// Query1 and QueryEmpty? remove the sort, QueryFirst and QueryLast do not.
+func getKind(dir Dir) string {
+	if dir == Only || dir == Any {
+		return "get1"
+	}
+	return "getsort"
+}
+
 var existsRow Row = []DbRec{{Record: "x"}}
 var existsHdr = SimpleHeader([]string{"x"})
 
@@ -94,6 +131,33 @@ func getQuery(ob *SuObject) string {
 	return ""
 }
 
+// getParams returns the params: argument for a parameterized query, or nil
+func getParams(ob *SuObject) *SuObject {
+	if p := ob.NamedGet(SuStr("params")); p != nil && isParams("params", p) {
+		return ToContainer(p).ToObject()
+	}
+	return nil
+}
+
+// toParams converts a params argument to an object
+func toParams(params Value) *SuObject {
+	if params == nil {
+		return nil
+	}
+	return ToContainer(params).ToObject()
+}
+
+// isParams returns true for a params: argument with the parameter values.
+// A string (or other non-container) value is a normal where field.
+// It should be equivalent to builtin queryWhere.
+func isParams(field string, v Value) bool {
+	if field != "params" {
+		return false
+	}
+	_, ok := v.ToContainer()
+	return ok
+}
+
 // fastGet returns a nil Header to indicate it was not applicable
 func fastGet(th *Thread, tran qry.QueryTran, query string, ob *SuObject, dir Dir) (row Row, hdr *Header, strarg string) {
 	strarg = query
@@ -109,7 +173,7 @@ func fastGet(th *Thread, tran qry.QueryTran, query string, ob *SuObject, dir Dir
 	iter := ob.Iter2(false, true)
 	for k, v := iter(); v != nil; k, v = iter() {
 		field := ToStr(k)
-		if field == "query" {
+		if field == "query" || isParams(field, v) {
 			continue
 		}
 		sels = append(sels, qry.NewSel(field, Pack(v.(Packable))))
@@ -305,7 +369,7 @@ func getWhere(ob *SuObject) string {
 	iter := ob.Iter2(false, true)
 	for k, v := iter(); v != nil; k, v = iter() {
 		field := ToStr(k)
-		if field == "query" {
+		if field == "query" || isParams(field, v) {
 			continue
 		}
 		sb.WriteString(sep)
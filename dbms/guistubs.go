@@ -7,6 +7,7 @@ package dbms
 
 import (
 	. "github.com/apmckinlay/gsuneido/core"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
 	"github.com/apmckinlay/gsuneido/util/assert"
 )
 
@@ -26,6 +27,10 @@ func connections() *SuObject {
 	return &SuObject{}
 }
 
+func sessions() []qry.Session {
+	return nil
+}
+
 func kill(sid string) int {
 	return 0
 }
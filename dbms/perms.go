@@ -0,0 +1,125 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package dbms
+
+import (
+	"log"
+	"slices"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+)
+
+// Permissions restrict what a server connection authorized as a user can do.
+// They are defined by two tables:
+//
+//	roles (user, role) key(user, role)
+//	grants (role, privilege, table) key(role, privilege, table)
+//
+// privilege is one of read, write, admin, kill, or eval.
+// For read and write, table is a table name or "*" for all tables.
+// write includes read.
+// For admin (create, ensure, alter, rename, drop, view, sview, Use, Unuse,
+// Check, Final, DoWithoutTriggers (DisableTrigger and EnableTrigger),
+// and server side builtins like Database.Dump, Backup, Load, and Analyze,
+// which clients run with Exec but do not require eval, see adminExec),
+// kill (Database.Kill), and eval (ServerEval and Exec) the table is ignored.
+// Schema requires read for the table. Connections and Transactions require
+// read for server_sessions and server_transactions.
+//
+// Users without any roles (or if there is no roles table) are not restricted.
+// Permissions are loaded when a connection is authorized,
+// changes apply to subsequent connections.
+
+type perms struct {
+	user   string
+	grants map[string][]string // privilege => tables
+}
+
+// restrict returns a DbmsRestricted if the user has roles, otherwise dbms
+func restrict(dbms *DbmsLocal, user string) IDbms {
+	if p := loadPerms(dbms.db, user); p != nil {
+		return &DbmsRestricted{DbmsLocal: dbms, perms: p}
+	}
+	return dbms
+}
+
+// loadPerms returns the permissions for a user, or nil if unrestricted
+func loadPerms(db *db19.Database, user string) *perms {
+	rt := db.NewReadTran()
+	roles := map[string]bool{}
+	scanTable(rt, "roles", []string{"user", "role"}, func(vals []string) {
+		if vals[0] == user {
+			roles[vals[1]] = true
+		}
+	})
+	if len(roles) == 0 {
+		return nil
+	}
+	p := &perms{user: user, grants: map[string][]string{}}
+	scanTable(rt, "grants", []string{"role", "privilege", "table"},
+		func(vals []string) {
+			if roles[vals[0]] {
+				p.grants[vals[1]] = append(p.grants[vals[1]], vals[2])
+			}
+		})
+	return p
+}
+
+// scanTable calls fn with the string values of cols for each record.
+// It does nothing if the table does not exist.
+func scanTable(rt *db19.ReadTran, table string, cols []string,
+	fn func(vals []string)) {
+	if rt.GetInfo(table) == nil {
+		return
+	}
+	flds := make([]int, len(cols))
+	for i, col := range cols {
+		if flds[i] = rt.ColToFld(table, col); flds[i] < 0 {
+			log.Println("ERROR: permissions:", table, "is missing", col)
+		}
+	}
+	vals := make([]string, len(cols))
+	iter := rt.IndexIter(table, 0)
+	for iter.Next(rt); !iter.Eof(); iter.Next(rt) {
+		rec := rt.GetRecord(iter.CurOff())
+		for i, fld := range flds {
+			vals[i] = ""
+			if fld >= 0 {
+				vals[i] = ToStrOrString(Unpack(rec.GetRaw(fld)))
+			}
+		}
+		fn(vals)
+	}
+}
+
+// allowed returns whether the privilege is granted for the table.
+// table is "" for privileges that do not apply to tables.
+func (p *perms) allowed(priv, table string) bool {
+	tables := p.grants[priv]
+	if table == "" {
+		return len(tables) > 0
+	}
+	if slices.Contains(tables, table) || slices.Contains(tables, "*") {
+		return true
+	}
+	return priv == "read" && p.allowed("write", table)
+}
+
+// check panics if the privilege is not granted
+func (p *perms) check(priv, table string) {
+	if !p.allowed(priv, table) {
+		s := "permission denied: " + p.user + " cannot " + priv
+		if table != "" {
+			s += " " + table
+		}
+		panic(s)
+	}
+}
+
+func (p *perms) checkTables(priv string, tables []string) {
+	for _, table := range tables {
+		p.check(priv, table)
+	}
+}
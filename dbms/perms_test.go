@@ -0,0 +1,135 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+//go:build !gui
+
+package dbms
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
+	"github.com/apmckinlay/gsuneido/util/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestPermissions(t *testing.T) {
+	originalLimiter := authLimiter
+	defer func() { authLimiter = originalLimiter }()
+	authLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	db19.MakeSuTran = func(ut *db19.UpdateTran) *SuTran {
+		return NewSuTran(nil, true)
+	}
+	admin := func(s string) { qry.DoAdmin(db, s, nil) }
+	admin("create roles (user, role) key(user, role)")
+	admin("create grants (role, privilege, table) key(role, privilege, table)")
+	admin("create public (a) key(a)")
+	admin("create secret (a) key(a)")
+	admin("create log (a) key(a)")
+	admin("view hidden = secret")
+	ut := db.NewUpdateTran()
+	for _, act := range []string{
+		"insert { user: 'audit', role: 'auditor' } into roles",
+		"insert { user: 'audit', role: 'logger' } into roles",
+		"insert { role: 'auditor', privilege: 'read', table: 'public' } into grants",
+		"insert { role: 'logger', privilege: 'write', table: 'log' } into grants",
+		"insert { role: 'ops', privilege: 'kill' } into grants",
+		"insert { a: 1 } into public",
+		"insert { a: 1 } into secret",
+	} {
+		qry.DoAction(nil, ut, act)
+	}
+	ut.Commit()
+	dbms := NewDbmsLocal(db)
+	th := &Thread{}
+
+	// users without roles are not restricted
+	_, ok := restrict(dbms, "admin").(*DbmsLocal)
+	assert.T(t).That(ok)
+
+	dr := restrict(dbms, "audit").(*DbmsRestricted)
+	get := func(query string) Row {
+		row, _, _ := dr.Get(th, SuObjectOf(SuStr(query)), Only)
+		return row
+	}
+	assert.T(t).That(get("public") != nil)
+	assert.T(t).This(func() { get("secret") }).
+		Panics("permission denied: audit cannot read secret")
+	assert.T(t).This(func() { get("hidden") }).
+		Panics("permission denied: audit cannot read secret")
+	assert.T(t).This(func() { get("public join secret") }).
+		Panics("cannot read secret")
+	assert.T(t).That(get("log") == nil) // write includes read
+	assert.T(t).This(func() { dr.Cursor("secret", nil, nil) }).
+		Panics("cannot read secret")
+	c := dr.Cursor("public", nil, nil)
+	rt := dr.Transaction(false)
+	row, _ := c.Get(th, rt, Next)
+	assert.T(t).That(row != nil)
+	rt.Complete()
+	c.Close()
+	assert.T(t).This(func() { get("server_sessions") }).
+		Panics("permission denied: audit cannot read server_sessions")
+	assert.T(t).This(func() { get("public times server_info") }).
+		Panics("cannot read server_info")
+
+	tran := dr.Transaction(true)
+	assert.T(t).This(func() { tran.Query("secret", nil, nil) }).
+		Panics("cannot read secret")
+	q := tran.Query("public", nil, nil)
+	assert.T(t).This(func() { q.Output(th, Record("")) }).
+		Panics("cannot write public")
+	assert.T(t).This(func() { tran.Action(th, "insert { a: 2 } into public") }).
+		Panics("cannot write public")
+	assert.T(t).This(func() { tran.Action(th, "insert secret into log") }).
+		Panics("cannot read secret")
+	assert.T(t).This(func() { tran.Action(th, "delete public") }).
+		Panics("cannot write public")
+	assert.T(t).This(func() { tran.Update(th, "public", 0, Record("")) }).
+		Panics("cannot write public")
+	assert.T(t).This(tran.Action(th, "insert public into log")).Is(1)
+	assert.T(t).This(tran.Action(th, "insert { a: 2 } into log")).Is(1)
+	tran.Complete()
+
+	assert.T(t).This(func() { dr.Admin("create x (a) key(a)", nil) }).
+		Panics("permission denied: audit cannot admin")
+	assert.T(t).This(func() { dr.Use("public") }).Panics("cannot admin")
+	assert.T(t).This(func() { dr.DisableTrigger("public") }).
+		Panics("cannot admin")
+	assert.T(t).This(func() { dr.Check(false) }).Panics("cannot admin")
+	assert.T(t).This(func() { dr.Final() }).Panics("cannot admin")
+	assert.T(t).That(dr.Schema("public") != "")
+	assert.T(t).This(func() { dr.Schema("secret") }).
+		Panics("cannot read secret")
+	assert.T(t).This(func() { dr.Connections() }).
+		Panics("cannot read server_sessions")
+	assert.T(t).This(func() { dr.Transactions() }).
+		Panics("cannot read server_transactions")
+	assert.T(t).This(func() { dr.Kill("127.0.0.1") }).
+		Panics("permission denied: audit cannot kill")
+	assert.T(t).This(func() { dr.Exec(th, SuObjectOf(SuStr("Print"))) }).
+		Panics("permission denied: audit cannot eval")
+	// admin builtins don't require eval (they check admin themselves)
+	// the builtins aren't available in this package
+	assert.T(t).This(func() { dr.Exec(th, SuObjectOf(SuStr("Database.Dump"))) }).
+		Panics("can't find Database")
+	assert.T(t).This(func() { dr.Run(th, "Print()") }).
+		Panics("permission denied: audit cannot eval")
+	assert.T(t).This(Local(dr, "") == dbms).Is(true)
+	assert.T(t).This(func() { Local(dr, "admin") }).
+		Panics("permission denied: audit cannot admin")
+
+	// tokens keep the same permissions
+	user, ok := authToken(dr.Token())
+	assert.T(t).That(ok)
+	assert.T(t).This(user).Is("audit")
+	_, ok = restrict(dbms, user).(*DbmsRestricted)
+	assert.T(t).That(ok)
+}
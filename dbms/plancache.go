@@ -0,0 +1,123 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package dbms
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19/meta"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
+	"github.com/apmckinlay/gsuneido/util/lrucache"
+)
+
+// plans caches the optimization of parameterized queries (see qry.Plan)
+// so repeating a query can skip optimizing.
+//
+// Entries are keyed by the kind of use (including the mode),
+// the normalized query text, and the parameter names.
+// The parameter values are not part of the key.
+// Each use parses the query with its own values
+// and then applies the plan with qry.SetupPlan.
+// If the values change the structure of the query
+// (e.g. a where that conflicts) the plan does not apply,
+// the query is optimized normally, and the new plan replaces the old one.
+//
+// An entry is only used if the transaction has the same schema
+// (tables, indexes, and views) as when the plan was built.
+// Queries that are parsed with session views are not cached.
+var plans = planCache{lru: lrucache.New[SuStr, *planEntry](planCacheSize)}
+
+const planCacheSize = 200 // max number of different queries
+
+type planCache struct {
+	lru  *lrucache.Cache[SuStr, *planEntry]
+	lock sync.Mutex
+}
+
+type planEntry struct {
+	plan *qry.Plan
+	// meta is the schema the plan was built with
+	meta *meta.Meta
+}
+
+var planHits, planMisses atomic.Int64
+var _ = AddInfo("query.plancache.hits", &planHits)
+var _ = AddInfo("query.plancache.misses", &planMisses)
+
+// planKey returns the cache key for a query and its parameter names.
+// It returns "" if the query should not be cached.
+func planKey(kind string, query string, params *SuObject, sv *Sviews) SuStr {
+	if params == nil || !sv.Empty() {
+		return ""
+	}
+	names := make([]string, 0, params.NamedSize())
+	iter := params.Iter2(false, true)
+	for k, v := iter(); v != nil; k, v = iter() {
+		names = append(names, ToStr(k))
+	}
+	slices.Sort(names)
+	var sb strings.Builder
+	sb.WriteString(kind)
+	sb.WriteByte(0)
+	sb.WriteString(qry.NormalizeQuery(query))
+	for _, name := range names {
+		sb.WriteByte(0)
+		sb.WriteString(name)
+	}
+	return SuStr(sb.String())
+}
+
+// setup is like qry.Setup (or qry.Setup1 if one is true).
+// If key is not "" it uses and updates the cached plan for the key.
+func (pc *planCache) setup(key SuStr, q qry.Query, params qry.PlanParams,
+	mode qry.Mode, one bool, tran qry.QueryTran) (qry.Query, qry.Cost, qry.Cost) {
+	m := tranMeta(tran)
+	if key == "" || m == nil {
+		if one {
+			return qry.Setup1(q, mode, tran)
+		}
+		return qry.Setup(q, mode, tran)
+	}
+	p := pc.get(key, m)
+	q, fixcost, varcost, p2 := qry.SetupPlan(q, params, p, mode, one, tran)
+	if p2 == p {
+		planHits.Add(1)
+	} else {
+		planMisses.Add(1)
+		pc.put(key, m, p2)
+	}
+	return q, fixcost, varcost
+}
+
+// get returns the cached plan for the key, or nil
+func (pc *planCache) get(key SuStr, m *meta.Meta) *qry.Plan {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+	if e, ok := pc.lru.Get(key); ok && e.meta.SameSchemaAs(m) {
+		return e.plan
+	}
+	return nil
+}
+
+// put adds or replaces the cached plan for the key
+func (pc *planCache) put(key SuStr, m *meta.Meta, p *qry.Plan) {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+	if e, ok := pc.lru.Get(key); ok {
+		e.plan, e.meta = p, m
+		return
+	}
+	pc.lru.Put(key, &planEntry{plan: p, meta: m})
+}
+
+func tranMeta(tran qry.QueryTran) *meta.Meta {
+	if t, ok := tran.(interface{ GetMeta() *meta.Meta }); ok {
+		return t.GetMeta()
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+//go:build !gui
+
+package dbms
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestPlanKey(t *testing.T) {
+	params := func(args ...Value) *SuObject {
+		ob := &SuObject{}
+		for i := 0; i < len(args); i += 2 {
+			ob.Set(args[i], args[i+1])
+		}
+		return ob
+	}
+	assert := assert.T(t)
+	assert.This(planKey("query", "tmp", nil, nil)).Is(SuStr(""))
+	k1 := planKey("query", "tmp where a is :a",
+		params(SuStr("a"), One, SuStr("b"), Zero), nil)
+	assert.That(k1 != "")
+	k2 := planKey("query", "tmp  where a is :a // comment",
+		params(SuStr("b"), Zero, SuStr("a"), One), nil)
+	assert.This(k2).Is(k1)
+	assert.That(planKey("get1", "tmp where a is :a",
+		params(SuStr("a"), One, SuStr("b"), Zero), nil) != k1)
+	assert.That(planKey("query", "tmp where a is :a",
+		params(SuStr("a"), IntVal(2), SuStr("b"), Zero), nil) == k1)
+	assert.That(planKey("query", "tmp where a is :a",
+		params(SuStr("a"), One), nil) != k1)
+	sv := &Sviews{}
+	sv.AddSview("v", "tmp")
+	assert.This(planKey("query", "tmp where a is :a",
+		params(SuStr("a"), One), sv)).Is(SuStr(""))
+}
+
+func TestPlanCache(t *testing.T) {
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	db19.MakeSuTran = func(ut *db19.UpdateTran) *SuTran {
+		return NewSuTran(nil, true)
+	}
+	qry.DoAdmin(db, "create tmp (a, b) key(a)", nil)
+	ut := db.NewUpdateTran()
+	for i := range 10 {
+		qry.DoAction(nil, ut, "insert { a: "+strconv.Itoa(i)+
+			", b: "+strconv.Itoa(i%3)+" } into tmp")
+	}
+	ut.Commit()
+	dbms := NewDbmsLocal(db)
+	th := &Thread{}
+	params := func(a int) *SuObject {
+		ob := &SuObject{}
+		ob.Set(SuStr("a"), IntVal(a))
+		return ob
+	}
+	get := func(a int) Value {
+		args := &SuObject{}
+		args.Add(SuStr("tmp where a is :a"))
+		args.Set(SuStr("params"), params(a))
+		row, hdr, _ := dbms.Get(th, args, Only)
+		if row == nil {
+			return nil
+		}
+		return row.GetVal(hdr, "b", nil, nil)
+	}
+	hits := planHits.Load()
+	assert.T(t).This(get(5)).Is(IntVal(2))
+	assert.T(t).This(planHits.Load()).Is(hits)
+	assert.T(t).This(get(5)).Is(IntVal(2))
+	assert.T(t).This(planHits.Load()).Is(hits + 1)
+	assert.T(t).This(get(4)).Is(One)
+	assert.T(t).This(planHits.Load()).Is(hits + 2)
+	assert.T(t).This(get(99)).Is(nil)
+	assert.T(t).This(planHits.Load()).Is(hits + 3)
+
+	count := func() int {
+		q := dbms.Transaction(false).Query("tmp where b is :a sort a",
+			params(1), nil)
+		defer q.Close()
+		n := 0
+		for row, _ := q.Get(th, Next); row != nil; row, _ = q.Get(th, Next) {
+			n++
+		}
+		return n
+	}
+	hits = planHits.Load()
+	assert.T(t).This(count()).Is(3)
+	assert.T(t).This(count()).Is(3)
+	assert.T(t).This(planHits.Load()).Is(hits + 1)
+
+	// schema change invalidates plans
+	dbms.Admin("ensure tmp index(b)", nil)
+	hits = planHits.Load()
+	assert.T(t).This(count()).Is(3)
+	assert.T(t).This(planHits.Load()).Is(hits)
+	assert.T(t).This(get(5)).Is(IntVal(2))
+	assert.T(t).This(planHits.Load()).Is(hits)
+}
@@ -16,6 +16,21 @@ func DoAction(th *Thread, ut *db19.UpdateTran, action string) int {
 	return a.execute(th, ut)
 }
 
+// ActionTables returns the tables read and the tables written by an action
+func ActionTables(a Action) (read, write []string) {
+	switch a := a.(type) {
+	case *insertRecordAction:
+		return nil, SourceTables(a.query)
+	case *insertQueryAction:
+		return SourceTables(a.query), []string{a.table}
+	case *updateAction:
+		return nil, SourceTables(a.query)
+	case *deleteAction:
+		return nil, SourceTables(a.query)
+	}
+	panic("ActionTables: unknown action")
+}
+
 //-------------------------------------------------------------------
 
 type insertRecordAction struct {
@@ -26,37 +26,52 @@ func isSystemTable(table string) bool {
 	case "tables", "columns", "indexes", "views":
 		return true
 	}
-	return false
+	return newSysTable(table) != nil
 }
 
 //-------------------------------------------------------------------
 
 type createAdmin struct {
 	Schema
+	history bool
 }
 
 func (a *createAdmin) String() string {
-	return "create " + a.Schema.String()
+	return "create " + a.Schema.String() + historyStr(a.history)
 }
 
 func (a *createAdmin) execute(db *db19.Database, _ *Sviews) {
 	checkForSystemTable(a.Table)
 	db.Create(&a.Schema)
+	if a.history {
+		db.CreateHistory(a.Table)
+	}
 }
 
 //-------------------------------------------------------------------
 
 type ensureAdmin struct {
 	Schema
+	history bool
 }
 
 func (a *ensureAdmin) String() string {
-	return "ensure " + a.Schema.String()
+	return "ensure " + a.Schema.String() + historyStr(a.history)
 }
 
 func (a *ensureAdmin) execute(db *db19.Database, _ *Sviews) {
 	checkForSystemTable(a.Table)
 	db.Ensure(&a.Schema)
+	if a.history {
+		db.CreateHistory(a.Table)
+	}
+}
+
+func historyStr(history bool) string {
+	if history {
+		return " history"
+	}
+	return ""
 }
 
 //-------------------------------------------------------------------
@@ -91,6 +106,11 @@ func (a *alterCreateAdmin) String() string {
 func (a *alterCreateAdmin) execute(db *db19.Database, _ *Sviews) {
 	checkForSystemTable(a.Table)
 	db.AlterCreate(&a.Schema)
+	if len(a.Columns) > 0 && db.HasHistory(a.Table) {
+		// add the new columns to the history table
+		db.AlterCreate(&Schema{Table: a.Table + db19.HistorySuffix,
+			Columns: a.Columns})
+	}
 }
 
 //-------------------------------------------------------------------
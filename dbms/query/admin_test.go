@@ -273,6 +273,63 @@ func TestAdminDrop(t *testing.T) {
 	db.MustCheck()
 }
 
+func TestAdminChecks(t *testing.T) {
+	assert := assert.T(t)
+	db := createTestDb()
+	defer db.Close()
+	act(db, "insert { a: 1, b: 2 } into tmp")
+	xact := func(action, err string) {
+		t.Helper()
+		ut := db.NewUpdateTran()
+		defer ut.Abort()
+		assert.This(func() { DoAction(nil, ut, action) }).Panics(err)
+	}
+
+	doAdmin(db, "create tmp2 (a, b, c) key(a) required(a) check(b > 0)")
+	assert.This(db.Schema("tmp2")).
+		Is("tmp2 (a,b,c) key(a) required(a) check(b > 0)")
+	xact("insert { b: 1 } into tmp2", "empty required column: a in tmp2")
+	xact("insert { a: 1, b: 0 } into tmp2", "failed check: b > 0 in tmp2")
+	act(db, "insert { a: 1, b: 5 } into tmp2")
+	xact("update tmp2 set b = -1", "failed check: b > 0 in tmp2")
+	xact("update tmp2 set a = ''", "empty required column: a in tmp2")
+	assert.This(func() { doAdmin(db, "create tmp3 (a) key(a) required(x)") }).
+		Panics("required: nonexistent column: x")
+	assert.This(func() { doAdmin(db, "create tmp3 (a) key(a) check(x > 0)") }).
+		Panics("check: nonexistent column: x")
+	assert.This(func() { doAdmin(db, "create tmp3 (a) key(a) check(a >)") }).
+		Panics("syntax error")
+
+	// existing data must satisfy new checks
+	assert.This(func() { doAdmin(db, "ensure tmp required(c)") }).
+		Panics("empty required column: c in tmp")
+	assert.This(func() { doAdmin(db, "alter tmp create check(b > 5)") }).
+		Panics("failed check: b > 5 in tmp")
+	doAdmin(db, "ensure tmp required(a) check(b < 5)")
+	doAdmin(db, "ensure tmp required(a) check(b < 5)") // nothing to do
+	doAdmin(db, "alter tmp create (x) check(x is '' or x > b)")
+	assert.This(db.Schema("tmp")).Is("tmp (a,b,c,d,x) key(a) index(b,c) " +
+		"required(a) check(b < 5) check(x is '' or x > b)")
+	xact("update tmp set x = 1", "failed check: x is '' or x > b in tmp")
+	act(db, "update tmp set x = 3")
+
+	assert.This(func() { doAdmin(db, "alter tmp rename x to y") }).
+		Panics("can't rename column used by check: x")
+	assert.This(func() { doAdmin(db, "alter tmp drop (b)") }).
+		Panics("can't drop column used by check: b")
+	assert.This(func() { doAdmin(db, "alter tmp drop check(b > 0)") }).
+		Panics("can't drop nonexistent check: b > 0")
+	doAdmin(db, "alter tmp drop check(b < 5)")
+	doAdmin(db, "alter tmp rename a to z")
+	doAdmin(db, "rename tmp to tmp4")
+	assert.This(db.Schema("tmp4")).Is("tmp4 (z,b,c,d,x) key(z) index(b,c) " +
+		"required(z) check(x is '' or x > b)")
+	doAdmin(db, "drop tmp4")
+	doAdmin(db, "create tmp4 (a) key(a)")
+	assert.This(db.Schema("tmp4")).Is("tmp4 (a) key(a)")
+	db.MustCheck()
+}
+
 func TestView(t *testing.T) {
 	db := createTestDb()
 	defer db.Close()
@@ -0,0 +1,104 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/core/trace"
+	"github.com/apmckinlay/gsuneido/util/tsc"
+)
+
+// Analyze reads all the rows from an optimized query (i.e. after Setup)
+// and returns its strategy annotated with what actually happened.
+// Each operation shows its estimates (like Strategy) followed by:
+//
+//	rows - the number of rows it produced (ngets)
+//	read - the number of rows it examined i.e. got from its sources
+//	sels - the number of Select's (index ranges) done on it
+//	looks - the number of Lookup's (index keys) done on it
+//	time - the elapsed time in Get including its sources
+//	self - the elapsed time in Get excluding its sources
+func Analyze(th *Thread, q Query) string {
+	t := time.Now()
+	c := tsc.Read()
+	q.Rewind()
+	for q.Get(th, Next) != nil {
+	}
+	elapsed := time.Since(t)
+	CalcSelf(q)
+	// tget is in TSC cycles, convert it to time using the overall ratio
+	perCycle := 0.0
+	if cycles := tsc.Read() - c; cycles > 0 {
+		perCycle = float64(elapsed) / float64(cycles)
+	}
+	return analyze(q, 0, perCycle) + "\nelapsed " + elapsed.String()
+}
+
+func analyze(q Query, indent int, perCycle float64) string { // recursive
+	in := strings.Repeat(indent1, indent)
+	nrows, pop := q.Nrows()
+	m := q.Metrics()
+	s := "{"
+	if m.frac != 1 {
+		s += fmt.Sprintf("%.3fx ", m.frac)
+	}
+	s += trace.Number(nrows)
+	if nrows != pop {
+		s += "/" + trace.Number(pop)
+	}
+	s += " " + trace.Number(m.fixcost) + "+" + trace.Number(m.varcost)
+	s += "} [rows " + trace.Number(m.ngets) +
+		" read " + trace.Number(Examined(q)) +
+		" sels " + trace.Number(m.nsels) +
+		" looks " + trace.Number(m.nlooks) +
+		" time " + cyclesToTime(m.tget, perCycle)
+	if m.tget != m.tgetself {
+		s += " self " + cyclesToTime(m.tgetself, perCycle)
+	}
+	s += "] "
+	switch q := q.(type) {
+	case *Sort:
+		if q.String() == "" {
+			return analyze(q.Source(), indent, perCycle)
+		}
+		return analyze(q.Source(), indent, perCycle) + "\n" +
+			in + s + q.String()
+	case q2i:
+		return analyze(q.Source(), indent+1, perCycle) + "\n" +
+			in + s + q.String() + "\n" +
+			analyze(q.Source2(), indent+1, perCycle)
+	case q1i:
+		return analyze(q.Source(), indent, perCycle) + "\n" +
+			in + s + q.String()
+	default:
+		return in + s + q.String()
+	}
+}
+
+func cyclesToTime(cycles uint64, perCycle float64) string {
+	d := time.Duration(float64(cycles) * perCycle)
+	return d.Round(time.Microsecond).String()
+}
+
+// Examined returns the number of rows an operation got from its sources.
+// For a leaf (e.g. a Table) it is the number of rows it read.
+func Examined(q Query) int {
+	switch q := q.(type) {
+	case q2i:
+		return rowsFrom(q.Source()) + rowsFrom(q.Source2())
+	case q1i:
+		return rowsFrom(q.Source())
+	default:
+		return rowsFrom(q)
+	}
+}
+
+func rowsFrom(q Query) int {
+	m := q.Metrics()
+	return int(m.ngets + m.nlooks)
+}
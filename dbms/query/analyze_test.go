@@ -0,0 +1,43 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	defer db.Close()
+	MakeSuTran = func(qt QueryTran) *SuTran { return nil }
+	doAdmin(db, "create test (a, b) key(a)")
+	act(db, "insert { a: 1, b: 1 } into test")
+	act(db, "insert { a: 2, b: 1 } into test")
+	act(db, "insert { a: 3, b: 2 } into test")
+	act(db, "insert { a: 4, b: 2 } into test")
+
+	tran := sizeTran{db.NewReadTran()}
+	q := ParseQuery("test where b is 1", tran, nil)
+	q, _, _ = Setup(q, ReadMode, tran)
+	s := Analyze(nil, q)
+	lines := strings.Split(s, "\n")
+	assert.T(t).This(len(lines)).Is(3)
+	assert.T(t).That(strings.Contains(lines[0], "[rows 4 read 4 sels 0 looks 0"))
+	assert.T(t).That(strings.HasSuffix(lines[0], "test^(a)"))
+	assert.T(t).That(strings.Contains(lines[1], "[rows 2 read 4 sels 0 looks 0"))
+	assert.T(t).That(strings.Contains(lines[1], " self "))
+	assert.T(t).That(strings.HasPrefix(lines[2], "elapsed "))
+
+	w := q.(*Where)
+	assert.T(t).This(Examined(w)).Is(4)
+	assert.T(t).This(Examined(w.source)).Is(4)
+}
@@ -0,0 +1,53 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"testing"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestAsof(t *testing.T) {
+	assert := assert.T(t)
+	db19.StartTimestamps()
+	db := createTestDb()
+	defer db.Close()
+	act(db, "insert { a: 1, b: 'one' } into tmp")
+	doAdmin(db, "ensure tmp (e) history")
+	assert.This(db.Schema("tmp")).Is("tmp (a,b,c,d,e) key(a) index(b,c) history")
+	t0 := db19.Timestamp()
+	act(db, "insert { a: 2, b: 'two' } into tmp")
+	act(db, "update tmp where a is 1 set b = 'uno'")
+	t1 := db19.Timestamp()
+	act(db, "delete tmp where a is 2")
+	doAdmin(db, "alter tmp create (f)")
+	act(db, "update tmp where a is 1 set f = 'new'")
+	t2 := db19.Timestamp()
+
+	asof := func(ts SuDate) string {
+		return queryAll(db, "tmp asof "+ts.String()+" sort a")
+	}
+	assert.This(asof(t0)).Is(`a=1 b=one`)
+	assert.This(asof(t1)).Is(`a=1 b=uno | a=2 b=two`)
+	assert.This(asof(t2)).Is(`a=1 b=uno f=new`)
+	assert.This(queryAll(db, "tmp_history where a is 1 project a, b sort b")).
+		Is(`a=1 b=one | a=1 b=uno`)
+
+	tran := sizeTran{db.NewReadTran()}
+	params := &SuObject{}
+	params.Set(SuStr("t"), t1)
+	q := ParseParamQuery("tmp asof :t where a is 2", params, tran, nil)
+	q, _, _ = Setup(q, ReadMode, tran)
+	assert.This(queryAll2(q)).Is(`a=2 b=two`)
+
+	assert.This(func() { queryAll(db, "tmp2 asof #20260101") }).
+		Panics("tmp2 does not have history")
+	assert.This(func() { queryAll(db, "tmp asof Date()") }).
+		Panics("asof requires a constant")
+	assert.This(Format(tran, "tmp asof #20260101 where a is 1")).
+		Is("tmp asof #20260101\nwhere a is 1")
+}
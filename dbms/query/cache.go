@@ -49,6 +49,11 @@ func (c *cache) cacheGet(req Require) (fixcost, varcost Cost, approach any) {
 	return -1, -1, nil
 }
 
+// cacheEntries returns the entries, it is used by SetupPlan
+func (c *cache) cacheEntries() []cacheEntry {
+	return c.entries
+}
+
 func (c *cache) cacheClear() {
 	c.entries = nil
 }
@@ -9,7 +9,7 @@ import (
 )
 
 func Format(t QueryTran, query string) string {
-	q := parseQuery(query, t, nil, nil, true)
+	q := parseQuery(query, t, nil, nil, true, nil)
 	return format(0, q, 0)
 }
 
@@ -721,13 +721,15 @@ func TestFuzzJoin(t *testing.T) {
 	start1nCount := join1nCount.Load()
 	startn1Count := joinn1Count.Load()
 	startnnCount := joinnnCount.Load()
+	startHashCount := joinHashCount.Load()
 
 	fuzzJoinRunner.Test(t)
 
 	fmt.Println("11:", join11Count.Load()-start11Count,
 		"1n:", join1nCount.Load()-start1nCount,
 		"n1:", joinn1Count.Load()-startn1Count,
-		"nn:", joinnnCount.Load()-startnnCount)
+		"nn:", joinnnCount.Load()-startnnCount,
+		"hash:", joinHashCount.Load()-startHashCount)
 	assert.T(t).This(join11Count.Load() - start11Count).Isnt(0)
 	assert.T(t).This(join1nCount.Load() - start1nCount).Isnt(0)
 	assert.T(t).This(joinn1Count.Load() - startn1Count).Isnt(0)
@@ -806,8 +808,10 @@ func newFuzzJoin(ft *FT) (Query, Query, []string) {
 				b2.data[i] = append(b2.data[i], "j"+strconv.Itoa(rnd.IntN(span)))
 			}
 		}
-		b1.indexes = append(b1.indexes, by)
-		b2.indexes = append(b2.indexes, by)
+		if rnd.IntN(4) != 0 { // otherwise no indexes to allow hash join
+			b1.indexes = append(b1.indexes, by)
+			b2.indexes = append(b2.indexes, by)
+		}
 	}
 	return b1.finish(), b2.finish(), by
 }
@@ -0,0 +1,169 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	. "github.com/apmckinlay/gsuneido/core"
+)
+
+// hashTable is the build side of a hash join (Join, LeftJoin, SemiJoin).
+// It is used when neither source has a usable index on the by columns.
+// The source is read once (in order) into a map from the by values
+// to the rows with those values.
+// Like Project and Summarize map, it is only used in ReadMode.
+type hashTable struct {
+	rows map[string][]Row
+	hdr  *Header
+	by   []string
+}
+
+// hashThreshold is the maximum number of build rows for optimize
+const hashThreshold = 100_000 // ???
+
+// hashCost is the per row cost of building or probing
+const hashCost = mapCost
+
+// hashopt returns the cost of a hash join with src1 as the probe side
+// (in the order required by req) and src2 as the build side.
+// It is only an option if neither source has an index on the by columns,
+// otherwise a nested loop join is used.
+// The build reads all of src2 so it is added to fixcost.
+func hashopt(src1, src2 Query, mode Mode, req Require, by []string) joinCost2 {
+	nrows2, _ := src2.Nrows()
+	if mode != ReadMode || nrows2 > hashThreshold ||
+		hasIndexOn(src1, by) || hasIndexOn(src2, by) {
+		return joinCost2{fixcost: impossible, varcost: impossible}
+	}
+	fixcost1, varcost1 := Optimize(src1, mode, req)
+	if fixcost1+varcost1 >= impossible {
+		return joinCost2{fixcost: impossible, varcost: impossible}
+	}
+	req2 := NoneReq(1)
+	fixcost2, varcost2 := Optimize(src2, mode, req2)
+	if fixcost2+varcost2 >= impossible {
+		return joinCost2{fixcost: impossible, varcost: impossible}
+	}
+	nrows1, _ := src1.Nrows()
+	build := Cost(nrows2) * hashCost
+	probe := Cost(float64(nrows1) * float64(req.frac) * hashCost)
+	return joinCost2{req1: req, req2: req2,
+		fixcost: fixcost1 + fixcost2 + varcost2 + build,
+		varcost: varcost1 + probe,
+	}
+}
+
+// hasIndexOn returns whether q has an index that groups by cols
+func hasIndexOn(q Query, cols []string) bool {
+	fixed := q.Fixed()
+	nColsUnfixed := countUnfixed(cols, fixed)
+	if nColsUnfixed == 0 {
+		return true
+	}
+	for _, idx := range q.Indexes() {
+		if grouped(idx, cols, nColsUnfixed, fixed) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildHash reads all of src into a hashTable
+func buildHash(th *Thread, st *SuTran, src Query, by []string,
+	which string) *hashTable {
+	ht := &hashTable{rows: make(map[string][]Row), hdr: src.Header(), by: by}
+	nrows := 0
+	derived := 0
+	derivedWarned := false
+	for {
+		row := src.Get(th, Next)
+		if row == nil {
+			break
+		}
+		key := hashKey(th, st, ht.hdr, by, row)
+		ht.rows[key] = append(ht.rows[key], row)
+		nrows++
+		if nrows == mapWarn {
+			Warning(which+"-hash large >", mapWarn)
+		}
+		derived += row.Derived()
+		if derived > derivedWarn && !derivedWarned {
+			Warning(which+"-hash derived large >", derivedWarn,
+				"average", derived/nrows)
+			derivedWarned = true
+		}
+	}
+	src.Rewind()
+	return ht
+}
+
+func hashKey(th *Thread, st *SuTran, hdr *Header, by []string, row Row) string {
+	if len(by) == 1 {
+		return row.GetRawVal(hdr, by[0], th, st)
+	}
+	key := ""
+	for _, col := range by {
+		key += row.GetRawVal(hdr, col, th, st) + "\x00"
+	}
+	return key
+}
+
+// probe returns the build rows that match the by values of row1
+// and the sels (on build side columns).
+// The rows are in the order they were read from the build source.
+func (ht *hashTable) probe(th *Thread, st *SuTran, hdr1 *Header, row1 Row,
+	sels Sels) []Row {
+	key := hashKey(th, st, hdr1, ht.by, row1)
+	rows := ht.rows[key]
+	if len(ht.by) == 1 && len(sels) == 0 {
+		return rows
+	}
+	var result []Row
+outer:
+	for _, row2 := range rows {
+		// check by in case of key collision
+		for _, col := range ht.by {
+			if row1.GetRawVal(hdr1, col, th, st) !=
+				row2.GetRawVal(ht.hdr, col, th, st) {
+				continue outer
+			}
+		}
+		for _, sel := range sels {
+			if row2.GetRawVal(ht.hdr, sel.col, th, st) != sel.val {
+				continue outer
+			}
+		}
+		result = append(result, row2)
+	}
+	return result
+}
+
+// hashIter iterates through the matching build rows for one probe row,
+// in either direction
+type hashIter struct {
+	rows []Row
+	pos  int
+}
+
+func (hi *hashIter) start(rows []Row, dir Dir) {
+	hi.rows = rows
+	if dir == Next {
+		hi.pos = -1
+	} else {
+		hi.pos = len(rows)
+	}
+}
+
+// next returns the next matching row or nil when there are no more
+func (hi *hashIter) next(dir Dir) Row {
+	if dir == Next {
+		hi.pos++
+	} else {
+		hi.pos--
+	}
+	if hi.pos < 0 || hi.pos >= len(hi.rows) {
+		hi.rows = nil
+		return nil
+	}
+	return hi.rows[hi.pos]
+}
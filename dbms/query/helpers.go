@@ -127,3 +127,27 @@ func JustTable(query string) string {
 		}
 	}
 }
+
+// NormalizeQuery returns the query with comments removed
+// and whitespace reduced to single spaces.
+// It is used as a cache key so queries that only differ in layout match.
+func NormalizeQuery(query string) string {
+	lxr := lexer.NewQueryLexer(query)
+	var sb strings.Builder
+	sep := ""
+	for {
+		item := lxr.Next()
+		switch item.Token {
+		case tok.Eof:
+			return sb.String()
+		case tok.Whitespace, tok.Newline, tok.Comment:
+			if sb.Len() > 0 {
+				sep = " "
+			}
+		default:
+			sb.WriteString(sep)
+			sep = ""
+			sb.WriteString(query[item.Pos:lxr.Position()])
+		}
+	}
+}
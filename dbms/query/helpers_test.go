@@ -73,3 +73,17 @@ func TestJustTable(t *testing.T) {
 	test("123", "")
 	test("customer.field", "")
 }
+
+func TestNormalizeQuery(t *testing.T) {
+	test := func(query string, expected string) {
+		t.Helper()
+		assert.T(t).This(NormalizeQuery(query)).Is(expected)
+	}
+	test("", "")
+	test("customer", "customer")
+	test("  customer  ", "customer")
+	test("customer   where  id is :id", "customer where id is :id")
+	test("/* comment */ customer // comment", "customer")
+	test("customer\n\twhere id is :id", "customer where id is :id")
+	test("customer where name is 'a  b'", "customer where name is 'a  b'")
+}
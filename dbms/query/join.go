@@ -47,6 +47,9 @@ type joinBase struct {
 	lookupRow   Row
 	joinType    joinType
 	optimized   bool
+	hash        bool // use a hash table for source2 instead of Select/Lookup
+	ht          *hashTable
+	hi          hashIter
 }
 
 type Join struct {
@@ -62,6 +65,7 @@ type lookupInfo struct {
 
 type joinApproach struct {
 	reverse bool
+	hash    bool
 	req1    Require
 	req2    Require
 }
@@ -109,6 +113,8 @@ var (
 	leftJoin1nCount     atomic.Int64
 	leftJoinn1Count     atomic.Int64
 	leftJoinnnCount     atomic.Int64
+	joinHashCount       atomic.Int64
+	leftJoinHashCount   atomic.Int64
 )
 
 var _ = AddInfo("query.join.cacheProbes", &joinCacheProbes)
@@ -123,6 +129,8 @@ var _ = AddInfo("query.leftjoin.11", &leftJoin11Count)
 var _ = AddInfo("query.leftjoin.1n", &leftJoin1nCount)
 var _ = AddInfo("query.leftjoin.n1", &leftJoinn1Count)
 var _ = AddInfo("query.leftjoin.nn", &leftJoinnnCount)
+var _ = AddInfo("query.join.hash", &joinHashCount)
+var _ = AddInfo("query.leftjoin.hash", &leftJoinHashCount)
 
 func NewJoin(src1, src2 Query, by []string, t QueryTran) Query {
 	return newJoin(src1, src2, by, t, nil, nil)
@@ -199,6 +207,9 @@ func (jn *Join) String() string {
 }
 
 func (jb *joinBase) String(op string) string {
+	if jb.hash {
+		op += "-hash"
+	}
 	if jb.optimized {
 		op += " " + jb.joinType.String()
 	} else if jb.joinType == many_to_many {
@@ -211,6 +222,7 @@ func (jb *joinBase) SetTran(qt QueryTran) {
 	jb.qt = qt
 	jb.st = MakeSuTran(qt)
 	jb.lookupCache.Reset()
+	jb.ht = nil
 	jb.Query2.SetTran(qt)
 }
 
@@ -315,25 +327,33 @@ func (jn *Join) optimize(mode Mode, req Require) (Cost, Cost, any) {
 	rev := joinopt2(jn.source2, jn.source1, jn.Nrows, jn.joinType.reverse(),
 		mode, req, jn.by)
 	rev.fixcost += outOfOrder + joinRev
+	hfwd := hashopt(jn.source1, jn.source2, mode, req, jn.by)
+	hrev := hashopt(jn.source2, jn.source1, mode, req, jn.by)
+	hrev.fixcost += outOfOrder + joinRev
 	if trace.JoinOpt.On() {
 		trace.JoinOpt.Println(mode, req)
 		trace.Println("    fwd req1", fwd.req1, "req2", fwd.req2,
 			"=", fwd.fixcost, fwd.varcost)
 		trace.Println("    rev req1", rev.req1, "req2", rev.req2,
 			"=", rev.fixcost, rev.varcost)
+		trace.Println("    hash fwd =", hfwd.fixcost, hfwd.varcost,
+			"rev =", hrev.fixcost, hrev.varcost)
 		trace.Println(strategy(jn, 1))
 	}
-	approach := &joinApproach{}
-	if rev.fixcost+rev.varcost < fwd.fixcost+fwd.varcost {
-		fwd = rev
-		approach.reverse = true
-	}
-	if fwd.fixcost == impossible {
+	best := newBest[*joinApproach]()
+	best.update(fwd.fixcost, fwd.varcost,
+		&joinApproach{req1: fwd.req1, req2: fwd.req2})
+	best.update(rev.fixcost, rev.varcost,
+		&joinApproach{reverse: true, req1: rev.req1, req2: rev.req2})
+	best.update(hfwd.fixcost, hfwd.varcost,
+		&joinApproach{hash: true, req1: hfwd.req1, req2: hfwd.req2})
+	best.update(hrev.fixcost, hrev.varcost,
+		&joinApproach{reverse: true, hash: true,
+			req1: hrev.req1, req2: hrev.req2})
+	if best.none() {
 		return impossible, impossible, nil
 	}
-	approach.req1 = fwd.req1
-	approach.req2 = fwd.req2
-	return fwd.fixcost, fwd.varcost, approach
+	return best.fixcost, best.varcost, best.data
 }
 
 func joinopt2(src1, src2 Query, nrows func() (int, int), jt joinType,
@@ -371,6 +391,10 @@ func (jn *Join) setApproach(req Require, approach any, tran QueryTran) {
 		jn.source1, jn.source2 = jn.source2, jn.source1
 		jn.joinType = jn.joinType.reverse()
 	}
+	jn.hash = ap.hash
+	if jn.hash {
+		joinHashCount.Add(1)
+	}
 	switch jn.joinType {
 	case one_to_one:
 		join11Count.Add(1)
@@ -389,6 +413,11 @@ func (jn *Join) setApproach(req Require, approach any, tran QueryTran) {
 func (jn *Join) getNrows() (int, int) {
 	n1, p1 := jn.source1.Nrows()
 	n2, p2 := jn.source2.Nrows()
+	if jn.joinType == many_to_many {
+		if n, ok := statsJoinNrows(jn.source1, jn.source2, jn.by, n1, n2); ok {
+			return n, jn.pop(p1, p2)
+		}
+	}
 	return jn.nrows(n1, p1, n2, p2), jn.pop(p1, p2)
 }
 
@@ -443,6 +472,9 @@ func (jb *joinBase) rewind() {
 
 func (jn *Join) Get(th *Thread, dir Dir) Row {
 	defer func(t uint64) { jn.tget += tsc.Read() - t }(tsc.Read())
+	if jn.hash {
+		return jn.getHash(th, dir)
+	}
 	for {
 		if jn.row2 == nil && !jn.nextRow1(th, dir) {
 			return nil
@@ -461,6 +493,36 @@ func (jn *Join) Get(th *Thread, dir Dir) Row {
 	}
 }
 
+func (jn *Join) getHash(th *Thread, dir Dir) Row {
+	for {
+		if jn.row2 == nil {
+			if !jn.nextHash1(th, dir) {
+				return nil
+			}
+			jn.hi.start(jn.probe(th, jn.row1, jn.sel2), dir)
+		}
+		jn.row2 = jn.hi.next(dir)
+		if jn.row2 != nil {
+			jn.ngets++
+			return JoinRows(jn.row1, jn.row2)
+		}
+	}
+}
+
+func (jb *joinBase) nextHash1(th *Thread, dir Dir) bool {
+	jb.row1 = jb.source1.Get(th, dir)
+	return jb.row1 != nil
+}
+
+// probe returns the source2 rows matching row1 (and sels) from the hash table,
+// building it if necessary
+func (jb *joinBase) probe(th *Thread, row1 Row, sels Sels) []Row {
+	if jb.ht == nil {
+		jb.ht = buildHash(th, jb.st, jb.source2, jb.by, "join")
+	}
+	return jb.ht.probe(th, jb.st, jb.source1.Header(), row1, sels)
+}
+
 func (jn *Join) nextRow1(th *Thread, dir Dir) bool {
 	jn.row1 = jn.source1.Get(th, dir)
 	if jn.row1 == nil {
@@ -538,6 +600,12 @@ func (jn *Join) Lookup(th *Thread, sels Sels) Row {
 		return nil
 	}
 	var row2 Row
+	if jn.hash {
+		if rows := jn.probe(th, row1, sel2); len(rows) > 0 {
+			return JoinRows(row1, rows[0])
+		}
+		return nil
+	}
 	sel2 = append(sel2, jn.projectRow1(th, row1)...)
 	if jn.joinType == many_to_one {
 		row2 = jn.cachedLookup(th, sel2)
@@ -718,15 +786,24 @@ func fixedConflict(fixed1, fixed2 Fixed) bool {
 func (lj *LeftJoin) optimize(mode Mode, req Require) (Cost, Cost, any) {
 	jc := joinopt2(lj.source1, lj.source2, lj.Nrows, lj.joinType,
 		mode, req, lj.by)
-	if jc.fixcost == impossible {
+	hc := hashopt(lj.source1, lj.source2, mode, req, lj.by)
+	best := newBest[*joinApproach]()
+	best.update(jc.fixcost, jc.varcost,
+		&joinApproach{req1: jc.req1, req2: jc.req2})
+	best.update(hc.fixcost, hc.varcost,
+		&joinApproach{hash: true, req1: hc.req1, req2: hc.req2})
+	if best.none() {
 		return impossible, impossible, nil
 	}
-	return jc.fixcost, jc.varcost,
-		&joinApproach{req1: jc.req1, req2: jc.req2}
+	return best.fixcost, best.varcost, best.data
 }
 
 func (lj *LeftJoin) setApproach(req Require, approach any, tran QueryTran) {
 	ap := approach.(*joinApproach)
+	lj.hash = ap.hash
+	if lj.hash {
+		leftJoinHashCount.Add(1)
+	}
 	switch lj.joinType {
 	case one_to_one:
 		leftJoin11Count.Add(1)
@@ -746,6 +823,11 @@ func (lj *LeftJoin) setApproach(req Require, approach any, tran QueryTran) {
 func (lj *LeftJoin) getNrows() (int, int) {
 	n1, p1 := lj.source1.Nrows()
 	n2, p2 := lj.source2.Nrows()
+	if lj.joinType == many_to_many {
+		if n, ok := statsJoinNrows(lj.source1, lj.source2, lj.by, n1, n2); ok {
+			return max(n1, n), lj.pop(p1, p2)
+		}
+	}
 	return lj.nrows(n1, p1, n2, p2), lj.pop(p1, p2)
 }
 
@@ -784,6 +866,9 @@ func (lj *LeftJoin) pop(n1, n2 int) int {
 
 func (lj *LeftJoin) Get(th *Thread, dir Dir) (r Row) {
 	defer func(t uint64) { lj.tget += tsc.Read() - t }(tsc.Read())
+	if lj.hash {
+		return lj.getHash(th, dir)
+	}
 	row1out := true
 	for {
 		if lj.row2 == nil {
@@ -822,6 +907,32 @@ func (lj *LeftJoin) Get(th *Thread, dir Dir) (r Row) {
 	}
 }
 
+func (lj *LeftJoin) getHash(th *Thread, dir Dir) Row {
+	for {
+		if lj.row2 == nil {
+			if !lj.nextHash1(th, dir) {
+				return nil
+			}
+			lj.hi.start(lj.probe2(th, lj.row1), dir)
+		}
+		lj.row2 = lj.hi.next(dir)
+		if lj.row2 != nil && lj.filter2(lj.row2) {
+			lj.ngets++
+			return JoinRows(lj.row1, lj.row2)
+		}
+	}
+}
+
+// probe2 returns the matching source2 rows, or empty2 if there are none.
+// sel2 is not applied, that is handled by filter2
+func (lj *LeftJoin) probe2(th *Thread, row1 Row) []Row {
+	rows := lj.probe(th, row1, nil)
+	if len(rows) == 0 {
+		return []Row{lj.empty2}
+	}
+	return rows
+}
+
 func (lj *LeftJoin) filter2(row2 Row) bool {
 	// fmt.Println(lj.strategy(), "filter", lj.sel2cols, unpack(lj.sel2vals))
 	for _, sel := range lj.sel2 {
@@ -857,6 +968,14 @@ func (lj *LeftJoin) Lookup(th *Thread, sels Sels) Row {
 		return nil
 	}
 	var row2 Row
+	if lj.hash {
+		for _, row2 := range lj.probe2(th, row1) {
+			if lj.filter2(row2) {
+				return JoinRows(row1, row2)
+			}
+		}
+		return nil
+	}
 	sel := lj.projectRow1(th, row1)
 	if lj.joinType.toOne() {
 		row2 = lj.cachedLookup(th, sel)
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	. "github.com/apmckinlay/gsuneido/core"
 	"github.com/apmckinlay/gsuneido/util/assert"
 )
 
@@ -136,3 +137,62 @@ func TestJoin_LookupBug(t *testing.T) {
 // 	}
 // 	return sb.String()
 // }
+
+func TestHashJoin(t *testing.T) {
+	db := heapDb()
+	db.adm("create hja (ka, x) key(ka)")
+	db.act("insert { ka: 1, x: 1 } into hja")
+	db.act("insert { ka: 2, x: 2 } into hja")
+	db.act("insert { ka: 3, x: 2 } into hja")
+	db.act("insert { ka: 4, x: 3 } into hja")
+	db.adm("create hjb (kb, x, y) key(kb)")
+	db.act("insert { kb: 1, x: 2, y: 'a' } into hjb")
+	db.act("insert { kb: 2, x: 2, y: 'b' } into hjb")
+	db.act("insert { kb: 3, x: 3, y: 'c' } into hjb")
+	db.act("insert { kb: 4, x: 5, y: 'd' } into hjb")
+	tran := sizeTran{db.NewReadTran()}
+	test := func(query, strategy, expected string) {
+		t.Helper()
+		q := ParseQuery(query, tran, nil)
+		q, _, _ = Setup(q, ReadMode, tran)
+		assert.T(t).This(String(q)).Is(strategy)
+		assert.T(t).This(queryAll2(q)).Is(expected)
+		// Prev is the reverse of Next
+		hdr := q.Header()
+		var rows []string
+		q.Rewind()
+		for row := q.Get(nil, Prev); row != nil; row = q.Get(nil, Prev) {
+			rows = append([]string{row2str(hdr, row)}, rows...)
+		}
+		assert.T(t).This(strings.Join(rows, " | ")).Is(expected)
+	}
+	test("hja join by(x) hjb",
+		"hja^(ka) join-hash n:n by(x) hjb^(kb)",
+		"ka=2 kb=1 x=2 y=a | ka=2 kb=2 x=2 y=b | "+
+			"ka=3 kb=1 x=2 y=a | ka=3 kb=2 x=2 y=b | ka=4 kb=3 x=3 y=c")
+	test("hja leftjoin by(x) hjb",
+		"hja^(ka) leftjoin-hash n:n by(x) hjb^(kb)",
+		"ka=1 x=1 | ka=2 kb=1 x=2 y=a | ka=2 kb=2 x=2 y=b | "+
+			"ka=3 kb=1 x=2 y=a | ka=3 kb=2 x=2 y=b | ka=4 kb=3 x=3 y=c")
+	test("hja semijoin by(x) hjb",
+		"hja^(ka) semijoin-hash n:n by(x) hjb^(kb)",
+		"ka=2 x=2 | ka=3 x=2 | ka=4 x=3")
+
+	// Select and Lookup
+	q := ParseQuery("hja join by(x) hjb", tran, nil)
+	q = SetupIdx(q, ReadMode, tran, []string{"ka"})
+	q.Select(Sels{{"ka", Pack(IntVal(2))}, {"y", Pack(SuStr("b"))}})
+	assert.T(t).This(queryAll2(q)).Is("ka=2 kb=2 x=2 y=b")
+	q.Select(nil)
+	row := q.Lookup(nil, Sels{{"ka", Pack(IntVal(4))}, {"kb", Pack(IntVal(3))}})
+	assert.T(t).This(row2str(q.Header(), row)).Is("ka=4 kb=3 x=3 y=c")
+	q = ParseQuery("hja leftjoin by(x) hjb", tran, nil)
+	q = SetupIdx(q, ReadMode, tran, []string{"ka"})
+	row = q.Lookup(nil, Sels{{"ka", Pack(IntVal(1))}})
+	assert.T(t).This(row2str(q.Header(), row)).Is("ka=1 x=1")
+
+	// not used in update mode
+	q = ParseQuery("hja join by(x) hjb", tran, nil)
+	q, _, _ = Setup(q, UpdateMode, tran)
+	assert.T(t).That(!strings.Contains(String(q), "hash"))
+}
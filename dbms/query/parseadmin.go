@@ -53,9 +53,11 @@ func ParseAdmin(src string) Admin {
 func (p *adminParser) admin() Admin {
 	switch {
 	case p.MatchIf(tok.Create):
-		return &createAdmin{p.schema()}
+		sch := p.schema()
+		return &createAdmin{Schema: sch, history: p.MatchIf(tok.History)}
 	case p.MatchIf(tok.Ensure):
-		return &ensureAdmin{p.schema()}
+		sch := p.schema()
+		return &ensureAdmin{Schema: sch, history: p.MatchIf(tok.History)}
 	case p.MatchIf(tok.Rename):
 		from, to := p.rename1()
 		return &renameAdmin{from: from, to: to}
@@ -123,7 +125,58 @@ func (p *adminParser) schema() Schema {
 func (p *adminParser) schema2(table string) Schema {
 	columns, derived := p.columns()
 	indexes := p.indexes()
-	return Schema{Table: table, Columns: columns, Derived: derived, Indexes: indexes}
+	required, checks := p.constraints()
+	return Schema{Table: table, Columns: columns, Derived: derived,
+		Indexes: indexes, Required: required, Checks: checks}
+}
+
+// constraints parses required(columns) and check(expression)
+func (p *adminParser) constraints() (required, checks []string) {
+	for p.Token == tok.Identifier {
+		switch p.Text {
+		case "required":
+			p.Next()
+			for _, col := range p.indexColumns() {
+				required = p.appendUnique(required, col)
+			}
+		case "check":
+			p.Next()
+			checks = append(checks, p.check())
+		default:
+			return
+		}
+	}
+	return
+}
+
+// check returns the source of a parenthesized expression.
+// The expression is compiled and validated by the database.
+func (p *adminParser) check() string {
+	if p.Token != tok.LParen {
+		p.Error("expected ( after check")
+	}
+	org := int(p.Pos) + 1
+	nest := 0
+	for {
+		switch p.Token {
+		case tok.LParen:
+			nest++
+		case tok.RParen:
+			nest--
+		case tok.Eof:
+			p.Error("missing ) in check")
+		}
+		if nest == 0 {
+			break
+		}
+		p.Next()
+	}
+	src := strings.TrimSpace(p.Lxr.Source()[org:p.Pos])
+	if src == "" {
+		p.Error("empty check")
+	}
+	p.Next()
+	return src
 }
 
 func (p *adminParser) columns() (columns, derived []string) {
@@ -228,6 +281,16 @@ func (p *adminParser) foreignKey() (fk schema.Fkey) {
 			fk.Mode = schema.CascadeUpdates
 		}
 	}
+	if p.MatchIf(tok.Set) {
+		if p.Text != "empty" {
+			p.Error("expected empty after set")
+		}
+		p.Next()
+		if fk.Mode&schema.CascadeDeletes != 0 {
+			p.Error("can't have both cascade (deletes) and set empty")
+		}
+		fk.Mode |= schema.SetEmpty
+	}
 	fk.IIndex = -1 // to ensure it gets set later
 	return fk
 }
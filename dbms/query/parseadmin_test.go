@@ -33,13 +33,28 @@ func TestParseAdmin(t *testing.T) {
 	test("ensure mytable (one,two,three) index(two) in other(six)")
 	test("ensure mytable (one,two,three) index(two) in other(six) cascade")
 	test("ensure mytable (one,two,three) index(two) in other(six) cascade update")
+	test("ensure mytable (one,two,three) index(two) in other set empty")
+	test("ensure mytable (one,two,three) index(two) in other(six) cascade update set empty")
 
 	test("create mytable (one,Two,Three) key(one)")
 	test("create mytable (one,two,two_lower!) key(two_lower!)")
+	test("create mytable (one,two,three) key(one) history")
+	test("ensure mytable (one,two,three) key(one) history")
 
 	test("alter mytable drop (one,two,three) index(two)")
 	test("alter mytable create (one,two,three) index(two)")
 	test("alter mytable rename one to two, three to four")
 
+	test("create mytable (one,two,three) key(one) required(one,two)")
+	test("create mytable (one,two,three) key(one) check(two > 0)")
+	test("ensure mytable (one,two,three) key(one) required(one) " +
+		"check(two > 0 and (three is '' or three =~ '^[a-z]+$')) check(one isnt two)")
+	test("alter mytable create check(f(two) and g((1)))")
+	test("alter mytable drop required(one) check(two > 0)")
+	assert.T(t).This(func() { ParseAdmin("create mytable (a) key(a) check(a > 0") }).
+		Panics("missing ) in check")
+	assert.T(t).This(func() { ParseAdmin("create mytable (a) key(a) check()") }).
+		Panics("empty check")
+
 	test("view tc = tables join columns")
 }
@@ -58,6 +58,12 @@ func TestParseQuery(t *testing.T) {
 		"table summarize count, total a, max b")
 	test("table summarize a, b, count",
 		"table summarize a, b, count")
+	test("table summarize a, median b, percentile 90 c, countdistinct b",
+		"table summarize a, median b, countdistinct b, percentile 90 c")
+	test("table summarize n = stddev b, variance c")
+	test("table window sort (a) row_number")
+	test("table window by (a) sort (b, c) rank, r = total c, average c 3")
+	test("table window by (a) sort (b) prev = lag c 2, lead c")
 
 	test("(table union table2) join table2",
 		"(table union /*NOT DISJOINT*/ table2) join by(c,d,e) table2")
@@ -84,6 +90,14 @@ func TestParseQuery(t *testing.T) {
 	xtest("cus semijoin by() task", "invalid empty join by")
 	xtest("table summarize a, b", "expecting Comma")
 	xtest("table summarize total", "expecting identifier")
+	xtest("table summarize percentile b", "percentile requires a number")
+	xtest("table summarize percentile 101 b", "percentile requires a number")
+	xtest("table window row_number", "expecting Sort")
+	xtest("table window sort (a) total", "expecting identifier")
+	xtest("table window sort (a) foo c", "window: invalid operation: foo")
+	xtest("table window sort (a) average c 0", "window: invalid count")
+	xtest("table window sort (x) rank", "window: nonexistent columns: x")
+	xtest("table window sort (a) a = rank", "window: column(s) already exist")
 
 	xtest("cus extend x = y = 1",
 		"assignment operators are not allowed")
@@ -120,3 +134,22 @@ func TestParseQueryView(t *testing.T) {
 	assert.T(t).This(String(q)).
 		Is("table union /*NOT DISJOINT*/ (cus join by(cnum) task)")
 }
+
+func TestParseParamQuery(t *testing.T) {
+	test := func(s string, params core.Value, expected string) {
+		t.Helper()
+		q := ParseParamQuery(s, params, testTran{}, nil)
+		assert.T(t).This(String(q)).Is(expected)
+	}
+	ob := &core.SuObject{}
+	ob.Set(core.SuStr("a"), core.IntVal(123))
+	ob.Set(core.SuStr("b"), core.SuStr("hello"))
+	test("table where a is :a", ob, "table where a is 123")
+	test("table where a is :a and b is :b", ob,
+		`table where a is 123 and b is "hello"`)
+	test("table extend x = :b", ob, `table extend x = "hello"`)
+	assert.T(t).This(func() { ParseParamQuery("table where a is :c", ob, testTran{}, nil) }).
+		Panics("missing parameter :c")
+	assert.T(t).This(func() { ParseQuery("table where a is :a", testTran{}, nil) }).
+		Panics("missing parameter :a")
+}
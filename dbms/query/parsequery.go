@@ -5,12 +5,14 @@ package query
 
 import (
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/apmckinlay/gsuneido/compile"
 	"github.com/apmckinlay/gsuneido/compile/ast"
 	tok "github.com/apmckinlay/gsuneido/compile/tokens"
 	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
 	"github.com/apmckinlay/gsuneido/util/str"
 )
 
@@ -19,30 +21,65 @@ type queryParser struct {
 	sviews    *Sviews
 	viewNest  []string
 	wrapViews bool
+	// params are the :name parameters, used by ParseParams
+	params PlanParams
 	compile.Parser
 }
 
 func NewQueryParser(src string, t QueryTran, sv *Sviews) *queryParser {
-	return &queryParser{Parser: *compile.QueryParser(src), t: t, sviews: sv}
+	p := &queryParser{Parser: *compile.QueryParser(src), t: t, sviews: sv}
+	p.Param = noParams
+	return p
 }
 
 func ParseQuery(src string, t QueryTran, sv *Sviews) Query {
-	return parseQuery(src, t, sv, nil, false)
+	return parseQuery(src, t, sv, nil, false, nil)
+}
+
+// ParseParamQuery is like ParseQuery but binds :name parameters
+// to the named members of params.
+// The values are used directly, they are never converted to query text.
+func ParseParamQuery(src string, params Value, t QueryTran, sv *Sviews) Query {
+	return parseQuery(src, t, sv, nil, false, params)
+}
+
+// ParseParams is like ParseParamQuery
+// but it also returns the parameters for SetupPlan
+func ParseParams(src string, params Value, t QueryTran, sv *Sviews) (Query, PlanParams) {
+	return parseParams(src, t, sv, nil, false, params)
 }
 
 func JustParse(t QueryTran, query string) Query {
-	return parseQuery(query, t, nil, nil, true)
+	return parseQuery(query, t, nil, nil, true, nil)
+}
+
+func parseQuery(src string, t QueryTran, sv *Sviews, viewNest []string,
+	wrapViews bool, params Value) Query {
+	q, _ := parseParams(src, t, sv, viewNest, wrapViews, params)
+	return q
 }
 
-func parseQuery(src string, t QueryTran, sv *Sviews, viewNest []string, wrapViews bool) Query {
+func parseParams(src string, t QueryTran, sv *Sviews, viewNest []string,
+	wrapViews bool, params Value) (Query, PlanParams) {
 	p := NewQueryParser(src, t, sv)
+	if params != nil {
+		p.Param = func(name string) ast.Expr {
+			x := params.Get(nil, SuStr(name))
+			if x == nil {
+				return noParams(name)
+			}
+			c := &ast.Constant{Val: x}
+			p.params = append(p.params, param{name: name, c: c})
+			return c
+		}
+	}
 	p.viewNest = viewNest
 	p.wrapViews = wrapViews
 	result := p.sort()
 	if p.Token != tok.Eof {
 		p.Error("did not parse all input")
 	}
-	return result
+	return result, p.params
 }
 
 func (p *queryParser) sort() Query {
@@ -76,10 +113,13 @@ func (p *queryParser) source() Query {
 
 func (p *queryParser) table() Query {
 	table := p.MatchIdent()
+	if p.Token == tok.Identifier && p.Text == "asof" {
+		return p.asof(table)
+	}
 	if !slices.Contains(p.viewNest, table) {
 		if def := p.getView(table); def != "" {
 			q := parseQuery(def, p.t, p.sviews, append(p.viewNest, table),
-				p.wrapViews)
+				p.wrapViews, nil)
 			if p.wrapViews {
 				q = NewView(table, q)
 			}
@@ -89,6 +129,36 @@ func (p *queryParser) table() Query {
 	return NewTable(p.t, table)
 }
 
+// asof reads the history table (see db19/history.go)
+// giving the versions of the records that were current as of a time.
+// The time must be a constant (or a parameter).
+func (p *queryParser) asof(table string) Query {
+	p.Next()
+	c, ok := p.Expression().(*ast.Constant)
+	if !ok {
+		p.Error("asof requires a constant")
+	}
+	hist := table + db19.HistorySuffix
+	if p.t.GetInfo(hist) == nil {
+		p.Error("asof: " + table + " does not have history")
+	}
+	params := &SuObject{}
+	params.Set(SuStr("asof"), c.Val)
+	q := parseQuery("("+hist+" where valid_from <= :asof and "+
+		"(valid_to is '' or valid_to > :asof)) remove valid_from, valid_to",
+		p.t, p.sviews, p.viewNest, false, params)
+	if p.wrapViews {
+		v := NewView(table, q)
+		v.display = table + " asof " + c.Val.String()
+		q = v
+	}
+	return q
+}
+
+func noParams(name string) ast.Expr {
+	panic("query: missing parameter :" + name)
+}
+
 func (p *queryParser) getView(name string) string {
 	def := ""
 	if p.sviews != nil { // should only be nil from tests
@@ -128,6 +198,8 @@ func (p *queryParser) operation(pq *Query) bool {
 		*pq = p.union(*pq)
 	case p.MatchIf(tok.Where):
 		*pq = p.where(*pq)
+	case p.MatchIf(tok.Window):
+		*pq = p.window(*pq)
 	default:
 		return false
 	}
@@ -229,7 +301,7 @@ func (p *queryParser) summarize(q Query) Query {
 func (p *queryParser) sumBy() []string {
 	var by []string
 	for p.Token.IsIdent() &&
-		!isSumOp(p.Token) &&
+		!p.isSumOp() &&
 		p.Lxr.Ahead(1).Token != tok.Eq {
 		by = append(by, p.MatchIdent())
 		p.Match(tok.Comma)
@@ -244,10 +316,14 @@ func (p *queryParser) sumOps() (cols, ops, ons []string) {
 			col = p.MatchIdent()
 			p.Match(tok.Eq)
 		}
-		if !isSumOp(p.Token) {
-			p.Error("expected count, total, min, max, or list")
+		if !p.isSumOp() {
+			p.Error("expected count, total, average, min, max, list, " +
+				"median, percentile, stddev, variance, or countdistinct")
 		}
 		op = str.ToLower(p.MatchIdent())
+		if op == "percentile" {
+			op += " " + p.percent()
+		}
 		if op != "count" {
 			on = p.MatchIdent()
 		}
@@ -261,8 +337,33 @@ func (p *queryParser) sumOps() (cols, ops, ons []string) {
 	return
 }
 
-func isSumOp(t tok.Token) bool {
-	return tok.SummarizeStart < t && t < tok.SummarizeEnd
+// isSumOp handles the original operations which are keywords,
+// and the later ones which are not (so they can still be column names).
+// The later ones all require a column, so they can't be followed by a comma.
+func (p *queryParser) isSumOp() bool {
+	if tok.SummarizeStart < p.Token && p.Token < tok.SummarizeEnd {
+		return true
+	}
+	return p.Token == tok.Identifier &&
+		slices.Contains(sumOps2, str.ToLower(p.Text)) &&
+		p.Lxr.AheadSkip(0).Token != tok.Comma
+}
+
+var sumOps2 = []string{
+	"median", "percentile", "stddev", "variance", "countdistinct"}
+
+// percent parses the percentile, a number from 0 to 100
+func (p *queryParser) percent() string {
+	s := p.Text
+	if p.Token == tok.Number {
+		if n := NumFromString(s); n != nil &&
+			Zero.Compare(n) <= 0 && n.Compare(IntVal(100)) <= 0 {
+			p.Next()
+			return n.String()
+		}
+	}
+	p.Error("percentile requires a number from 0 to 100")
+	return ""
 }
 
 func (p *queryParser) times(q Query) Query {
@@ -282,6 +383,45 @@ func (p *queryParser) where(q Query) Query {
 	return NewWhere(q, expr, p.t)
 }
 
+func (p *queryParser) window(q Query) Query {
+	var by []string
+	if p.MatchIf(tok.By) {
+		by = p.parenList()
+	}
+	p.Match(tok.Sort)
+	order := p.parenList()
+	var cols, ops, ons []string
+	var ns []int
+	for {
+		var col, on string
+		n := 0
+		if p.Lxr.Ahead(1).Token == tok.Eq {
+			col = p.MatchIdent()
+			p.Match(tok.Eq)
+		}
+		op := str.ToLower(p.MatchIdent())
+		if op != "row_number" && op != "rank" {
+			on = p.MatchIdent()
+			if p.Token == tok.Number {
+				var err error
+				n, err = strconv.Atoi(p.Text)
+				if err != nil || n <= 0 {
+					p.Error("window: invalid count: " + p.Text)
+				}
+				p.Next()
+			}
+		}
+		cols = append(cols, col)
+		ops = append(ops, op)
+		ons = append(ons, on)
+		ns = append(ns, n)
+		if !p.MatchIf(tok.Comma) {
+			break
+		}
+	}
+	return NewWindow(q, by, order, cols, ops, ons, ns)
+}
+
 func (p *queryParser) parenList() []string {
 	p.Match(tok.LParen)
 	if p.MatchIf(tok.RParen) {
@@ -0,0 +1,180 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/apmckinlay/gsuneido/compile/ast"
+	. "github.com/apmckinlay/gsuneido/core"
+)
+
+// PlanParams are the :name parameters of a query from ParseParams
+type PlanParams []param
+
+type param struct {
+	name string
+	c    *ast.Constant
+}
+
+// Plan is the result of optimizing a parameterized query.
+// It can be applied to the same query parsed with different parameter values
+// so that it does not need to be optimized again (see dbms/plancache.go).
+// A Plan is not modified after it is created so it can be shared.
+//
+// A plan only applies if the query has the same signature,
+// i.e. the values did not change the structure of the query
+// e.g. by folding expressions or causing a where to conflict.
+// Since the query is parsed with the new values,
+// it is only the approaches from the plan that are reused.
+type Plan struct {
+	sig string
+	req Require
+	// caches are the optimize cache entries for each node in tree order
+	caches [][]cacheEntry
+}
+
+// SetupPlan is like Setup (or Setup1 if one is true)
+// for a query (and its parameters) from ParseParams.
+// If plan is not nil and it applies to the query, it is used
+// instead of optimizing, and the same plan is returned.
+// Otherwise the query is optimized and a new plan is returned.
+func SetupPlan(q Query, params PlanParams, plan *Plan, mode Mode, one bool,
+	t QueryTran) (Query, Cost, Cost, *Plan) {
+	q = q.Transform()
+	sig := planSig(q, params)
+	var req Require
+	if plan != nil && plan.sig == sig && plan.bind(q) {
+		req = plan.req
+	} else {
+		frac := 1.0
+		if one {
+			nrows, _ := q.Nrows()
+			frac = 1 / float64(max(1, nrows))
+		}
+		req = NoneReq(float32(frac))
+		plan = nil
+	}
+	fixcost, varcost := Optimize(q, mode, req)
+	if fixcost+varcost >= impossible {
+		panic("invalid query: " + String(q))
+	}
+	if plan == nil {
+		plan = &Plan{sig: sig, req: req}
+		for _, q := range planNodes(q) {
+			plan.caches = append(plan.caches, slices.Clone(q.cacheEntries()))
+		}
+	}
+	q = SetApproach(q, req, t)
+	if mode == CursorMode {
+		setCursorMode(q)
+	}
+	return q, fixcost, varcost, plan
+}
+
+// planSig returns String(q), with the parameter values replaced by their names,
+// plus the things that the approaches depend on
+// that are not shown by String e.g. fixed
+func planSig(q Query, params PlanParams) string {
+	for _, p := range params {
+		val := p.c.Val
+		p.c.Val = SuStr(":" + p.name)
+		defer func() { p.c.Val = val }()
+	}
+	var sb strings.Builder
+	sb.WriteString(String(q))
+	for i, q := range planNodes(q) {
+		sb.WriteString("\n")
+		sb.WriteString(strconv.Itoa(i))
+		for _, fix := range q.Fixed() {
+			sb.WriteString(" " + fix.col + "=" + strconv.Itoa(len(fix.values)))
+		}
+		switch q := q.(type) {
+		case *Where:
+			q.optInit()
+			sb.WriteString(" " + strconv.FormatBool(q.conflict) +
+				" " + strconv.FormatBool(q.singleton))
+		case *Join:
+			sb.WriteString(" " + strconv.FormatBool(q.conflict))
+		case *Intersect:
+			sb.WriteString(" " + strconv.FormatBool(q.conflict) +
+				" " + q.disjoint)
+		case *Union:
+			sb.WriteString(" " + q.disjoint)
+		case *Minus:
+			sb.WriteString(" " + q.disjoint)
+		}
+	}
+	return sb.String()
+}
+
+// bind adds the cache entries from the plan to the nodes of q
+// so that Optimize and SetApproach will use them.
+// It returns false if the plan does not apply.
+func (plan *Plan) bind(q Query) bool {
+	nodes := planNodes(q)
+	if len(nodes) != len(plan.caches) {
+		return false
+	}
+	for i, node := range nodes {
+		for _, e := range plan.caches[i] {
+			app, ok := bindApproach(node, e.approach)
+			if !ok {
+				for _, node := range nodes {
+					node.cacheClear()
+				}
+				return false
+			}
+			node.cacheAdd(e.Require, e.fixcost, e.varcost, app)
+		}
+	}
+	return true
+}
+
+// bindApproach converts an approach from the plan to one for q.
+// Most approaches do not depend on the parameter values,
+// but a Where index selection does.
+func bindApproach(q Query, approach any) (any, bool) {
+	switch app := approach.(type) {
+	case *tempIndex:
+		srcapp, ok := bindApproach(q, app.srcapp)
+		if !ok {
+			return nil, false
+		}
+		ti := *app
+		ti.srcapp = srcapp
+		return &ti, true
+	case *whereApproach:
+		if app.idxSel == nil {
+			return app, true
+		}
+		isel := q.(*Where).getIdxSel(app.index)
+		if isel == nil {
+			return nil, false
+		}
+		return &whereApproach{index: app.index, idxSel: isel, cost: app.cost},
+			true
+	}
+	return approach, true
+}
+
+// planNodes returns the nodes of the query tree in a consistent order
+func planNodes(q Query) []Query {
+	var nodes []Query
+	var walk func(q Query)
+	walk = func(q Query) {
+		nodes = append(nodes, q)
+		switch q := q.(type) {
+		case q2i:
+			walk(q.Source())
+			walk(q.Source2())
+		case q1i:
+			walk(q.Source())
+		}
+	}
+	walk(q)
+	return nodes
+}
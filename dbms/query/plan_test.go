@@ -0,0 +1,60 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"testing"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestSetupPlan(t *testing.T) {
+	MakeSuTran = func(qt QueryTran) *SuTran { return nil }
+	setup := func(query string, plan *Plan, vals ...Value) (Query, *Plan) {
+		t.Helper()
+		params := &SuObject{}
+		for i, v := range vals {
+			params.Set(SuStr(string(rune('x'+i))), v)
+		}
+		q, ps := ParseParams(query, params, testTran{}, nil)
+		q, _, _, plan = SetupPlan(q, ps, plan, ReadMode, false, testTran{})
+		return q, plan
+	}
+	// test that a plan is reused with different values
+	// and that it gives the same strategy as optimizing normally
+	test := func(query string, x1, x2 Value) {
+		t.Helper()
+		_, plan := setup(query, nil, x1)
+		q, plan2 := setup(query, plan, x2)
+		assert.T(t).Msg(query).That(plan2 == plan)
+		q2, _ := setup(query, nil, x2)
+		assert.T(t).Msg(query).This(Strategy(q)).Is(Strategy(q2))
+	}
+	test("table where a is :x", One, IntVal(2))
+	test("supplier where city is :x sort name", SuStr("a"), SuStr("b"))
+	test("trans where item is :x and date > 5", SuStr("a"), SuStr("b"))
+	test("(supplier where city is :x) join by(supplier) (trans extend supplier)",
+		SuStr("a"), SuStr("b"))
+	test("table extend x = :x where b is 1 sort c", One, Zero)
+
+	_, plan := setup("comp where a is :x", nil, One)
+	q, plan2 := setup("comp where a is :x", plan, IntVal(2))
+	assert.T(t).That(plan2 == plan)
+	assert.T(t).This(String(q)).Is("comp^(a,b,c) where a is 2")
+
+	// values that change the structure don't use the plan
+	noPlan := func(query string, v1, v2 []Value) {
+		t.Helper()
+		_, plan := setup(query, nil, v1...)
+		_, plan2 := setup(query, plan, v2...)
+		assert.T(t).Msg(query).That(plan2 != plan)
+	}
+	noPlan("table where a > :x and a < :y",
+		[]Value{One, IntVal(5)}, []Value{IntVal(5), One}) // conflict
+	noPlan("comp where a is :x and a is :y",
+		[]Value{One, One}, []Value{One, IntVal(2)}) // conflict
+	noPlan("table where a is :x + 1",
+		[]Value{One}, []Value{IntVal(2)}) // folded
+}
@@ -22,6 +22,7 @@
 			TempIndex
 			Where
 			View
+			Window
 		Query2
 			Compatible
 				Union
@@ -181,6 +182,7 @@ type Query interface {
 	cacheAdd(req Require, fixcost, varcost Cost, approach any)
 	cacheGet(req Require) (fixcost, varcost Cost, approach any)
 	cacheClear()
+	cacheEntries() []cacheEntry
 
 	// optimize determines the minimum cost strategy based on estimates.
 	//
@@ -366,6 +368,7 @@ type QueryTran interface {
 	GetView(string) string
 	GetStore() *stor.Stor
 	RangeFrac(table string, iIndex int, org, end string) float64
+	GetStats(table string) *meta.Stats
 	Lookup(table string, iIndex int, key string) *DbRec
 	Output(th *Thread, table string, rec Record)
 	GetIndexI(table string, iIndex int) *index.Overlay
@@ -827,6 +830,28 @@ func (q2 *Query2) Source2() Query {
 
 // ------------------------------------------------------------------
 
+// SourceTables returns the tables used by a query, including system tables.
+// Views must have been expanded by the parser (not wrapped).
+func SourceTables(q Query) []string {
+	var tables []string
+	var walk func(q Query)
+	walk = func(q Query) {
+		switch q := q.(type) {
+		case *Table:
+			tables = append(tables, q.name)
+		case *Tables, *Columns, *Indexes, *Views, *History, *sysTable:
+			tables = append(tables, q.String())
+		case q2i:
+			walk(q.Source())
+			walk(q.Source2())
+		case q1i:
+			walk(q.Source())
+		}
+	}
+	walk(q)
+	return tables
+}
+
 // String prints the full query, including child sources
 // whereas query.String only shows that operation
 func String(q Query) string {
@@ -125,6 +125,48 @@ func TestForeignKeyDeleteCascade(t *testing.T) {
 	assert.T(t).This(queryAll(db, "lin")).Is("")
 }
 
+func TestForeignKeySetEmpty(t *testing.T) {
+	assert := assert.T(t)
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	defer db.Close()
+	MakeSuTran = func(qt QueryTran) *SuTran { return nil }
+	doAdmin(db, "create hdr (a, b) key(a)")
+	doAdmin(db, "create lin (d, e, f) key(e) index(d,f) in hdr(a) set empty")
+	assert.This(db.Schema("lin")).
+		Is("lin (d,e,f) key(e) index(d,f) in hdr(a) set empty")
+	act(db, "insert { a: 1, b: 'b1' } into hdr")
+	act(db, "insert { a: 2, b: 'b2' } into hdr")
+	act(db, "insert { d: 1, e: 'e1', f: 'f1' } into lin")
+	act(db, "insert { d: 2, e: 'e2', f: 'f2' } into lin")
+
+	// update (hdr) block
+	assert.This(func() { act(db, "update hdr where a is 1 set a = 11") }).
+		Panics("blocked by foreign key")
+
+	// delete (hdr) set empty
+	act(db, "delete hdr where a is 1")
+	assert.This(queryAll(db, "lin sort e")).
+		Is("e=e1 f=f1 | d=2 e=e2 f=f2")
+
+	// with cascade update
+	doAdmin(db, "create hdr2 (a) key(a)")
+	doAdmin(db, "create lin2 (g, h) key(g) index(h) in hdr2(a) cascade update set empty")
+	assert.This(db.Schema("lin2")).
+		Is("lin2 (g,h) key(g) index(h) in hdr2(a) cascade update set empty")
+	act(db, "insert { a: 2 } into hdr2")
+	act(db, "insert { g: 1, h: 2 } into lin2")
+	act(db, "update hdr2 set a = 22")
+	assert.This(queryAll(db, "lin2")).Is("g=1 h=22")
+	act(db, "delete hdr2")
+	assert.This(queryAll(db, "lin2")).Is("g=1")
+
+	assert.This(func() {
+		doAdmin(db, "create lin3 (g, h) key(g) index(h) in hdr2(a) cascade set empty")
+	}).Panics("can't have both cascade (deletes) and set empty")
+	db.MustCheck()
+}
+
 func queryAll(db *db19.Database, query string) string {
 	tran := sizeTran{db.NewReadTran()}
 	q := ParseQuery(query, tran, nil)
@@ -71,6 +71,8 @@ func qryBase(q Query, key Value) Value {
 		return IntVal(int(q.Metrics().nsels))
 	case SuStr("nlooks"):
 		return IntVal(int(q.Metrics().nlooks))
+	case SuStr("examined"):
+		return IntVal(Examined(q))
 	}
 	return nil
 }
@@ -145,6 +147,16 @@ func (his *History) ValueGet(key Value) Value {
 	return qryBase(his, key)
 }
 
+func (st *sysTable) ValueGet(key Value) Value {
+	switch key {
+	case SuStr("type"):
+		return SuStr("table")
+	case SuStr("name"):
+		return SuStr(st.name)
+	}
+	return qryBase(st, key)
+}
+
 func (no *Nothing) ValueGet(key Value) Value {
 	switch key {
 	case SuStr("type"):
@@ -231,6 +243,14 @@ func (w *Where) ValueGet(key Value) Value {
 	return query1(w, key)
 }
 
+func (w *Window) ValueGet(key Value) Value {
+	switch key {
+	case SuStr("type"):
+		return SuStr("window")
+	}
+	return query1(w, key)
+}
+
 func (v *View) ValueGet(key Value) Value {
 	switch key {
 	case SuStr("type"):
@@ -26,6 +26,8 @@ type SemiJoin struct {
 	joinType    joinType
 	optimized   bool
 	reverse     bool
+	hash        bool // use a hash table for source2 instead of Select/Lookup
+	ht          *hashTable
 	sels1       Sels // from incoming Select, added to source1 probe in reverse mode
 
 	revState      state
@@ -41,6 +43,7 @@ type SemiJoin struct {
 
 type semiJoinApproach struct {
 	reverse bool
+	hash    bool
 	req1    Require
 	req2    Require
 }
@@ -52,6 +55,7 @@ var (
 	semiJoin1nCount     atomic.Int64
 	semiJoinn1Count     atomic.Int64
 	semiJoinnnCount     atomic.Int64
+	semiJoinHashCount   atomic.Int64
 )
 
 var _ = AddInfo("query.semijoin.cacheProbes", &semiJoinCacheProbes)
@@ -60,6 +64,7 @@ var _ = AddInfo("query.semijoin.11", &semiJoin11Count)
 var _ = AddInfo("query.semijoin.1n", &semiJoin1nCount)
 var _ = AddInfo("query.semijoin.n1", &semiJoinn1Count)
 var _ = AddInfo("query.semijoin.nn", &semiJoinnnCount)
+var _ = AddInfo("query.semijoin.hash", &semiJoinHashCount)
 
 func NewSemiJoin(src1, src2 Query, by []string, t QueryTran) *SemiJoin {
 	b := set.Intersect(src1.Columns(), src2.Columns())
@@ -98,6 +103,9 @@ func (sj *SemiJoin) String() string {
 		if sj.reverse {
 			op += "-rev"
 		}
+		if sj.hash {
+			op += "-hash"
+		}
 		op += " " + sj.joinType.String()
 	} else if sj.joinType == many_to_many {
 		op += " /*MANY TO MANY*/"
@@ -109,6 +117,7 @@ func (sj *SemiJoin) SetTran(t QueryTran) {
 	sj.qt = t
 	sj.st = MakeSuTran(t)
 	sj.lookupCache.Reset()
+	sj.ht = nil
 	sj.Query2.SetTran(t)
 	// don't need to clear dedup since it's only used in ReadMode
 	// which doesn't use SetTran
@@ -152,10 +161,16 @@ func (sj *SemiJoin) Transform() Query {
 func (sj *SemiJoin) optimize(mode Mode, req Require) (Cost, Cost, any) {
 	fwdFix, fwdVar, fwdApp := sj.optimizeForward(mode, req)
 	revFix, revVar, revApp := sj.optimizeReverse(mode, req)
-	if revFix+revVar < fwdFix+fwdVar {
-		return revFix, revVar, revApp
+	hc := hashopt(sj.source1, sj.source2, mode, req, sj.by)
+	best := newBest[any]()
+	best.update(fwdFix, fwdVar, fwdApp)
+	best.update(revFix, revVar, revApp)
+	best.update(hc.fixcost, hc.varcost,
+		&semiJoinApproach{hash: true, req2: hc.req2})
+	if best.none() {
+		return impossible, impossible, nil
 	}
-	return fwdFix, fwdVar, fwdApp
+	return best.fixcost, best.varcost, best.data
 }
 
 func (sj *SemiJoin) optimizeForward(mode Mode, req Require) (Cost, Cost, any) {
@@ -235,6 +250,10 @@ func (sj *SemiJoin) optimizeReverse(mode Mode, req Require) (Cost, Cost, any) {
 func (sj *SemiJoin) setApproach(req Require, approach any, tran QueryTran) {
 	ap := approach.(*semiJoinApproach)
 	sj.reverse = ap.reverse
+	sj.hash = ap.hash
+	if sj.hash {
+		semiJoinHashCount.Add(1)
+	}
 	switch sj.joinType {
 	case one_to_one:
 		semiJoin11Count.Add(1)
@@ -419,6 +438,12 @@ func (sj *SemiJoin) projectRow2(th *Thread, row Row) Sels {
 }
 
 func (sj *SemiJoin) source2Has(th *Thread, row Row, dir Dir) bool {
+	if sj.hash {
+		if sj.ht == nil {
+			sj.ht = buildHash(th, sj.st, sj.source2, sj.by, "semijoin")
+		}
+		return len(sj.ht.probe(th, sj.st, sj.source1.Header(), row, nil)) > 0
+	}
 	sels := make(Sels, len(sj.by))
 	for i, col := range sj.by {
 		sels[i] = Sel{col, row.GetRawVal(sj.source1.Header(), col, th, sj.st)}
@@ -467,6 +492,13 @@ func (sj *SemiJoin) Lookup(th *Thread, sels Sels) Row {
 	if sj.reverse {
 		return lookupViaSelectGet(sj, th, sels)
 	}
+	if sj.hash {
+		row := sj.source1.Lookup(th, sels)
+		if row == nil || !sj.source2Has(th, row, Next) {
+			return nil
+		}
+		return row
+	}
 	if sj.joinType == one_to_one || sj.joinType == many_to_one {
 		row := sj.source1.Lookup(th, sels)
 		if row == nil {
@@ -0,0 +1,137 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+)
+
+// SlowQuery is an entry in the slow query log (see dbms/slowquery.go)
+type SlowQuery struct {
+	Seq      int // unique, assigned by LogSlowQuery
+	Time     SuDate
+	Session  string
+	Query    string
+	Strategy string
+	RowsRead int
+	Elapsed  time.Duration
+}
+
+// SlowQueryFile is the slow query log file.
+// When it exceeds slowQueryFileLimit it is renamed with a ".1" suffix
+// (replacing any previous one) and a new file is started.
+var SlowQueryFile = "slowquery.log"
+
+const slowQueryFileLimit = 10 * 1024 * 1024
+
+// slowQueryKeep is the number of recent slow queries kept in memory
+// for the server_slowqueries system table
+const slowQueryKeep = 1000
+
+var slowQueries struct {
+	lock sync.Mutex
+	list []SlowQuery // ring buffer
+	next int         // number of entries ever added
+}
+
+// LogSlowQuery records a slow query in memory and in SlowQueryFile
+func LogSlowQuery(sq SlowQuery) {
+	slowQueries.lock.Lock()
+	defer slowQueries.lock.Unlock()
+	sq.Seq = slowQueries.next
+	if len(slowQueries.list) < slowQueryKeep {
+		slowQueries.list = append(slowQueries.list, sq)
+	} else {
+		slowQueries.list[slowQueries.next%slowQueryKeep] = sq
+	}
+	slowQueries.next++
+	writeSlowQuery(&sq)
+}
+
+func writeSlowQuery(sq *SlowQuery) {
+	if fi, err := os.Stat(SlowQueryFile); err == nil &&
+		fi.Size() > slowQueryFileLimit {
+		os.Rename(SlowQueryFile, SlowQueryFile+".1")
+	}
+	f, err := os.OpenFile(SlowQueryFile,
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Println("ERROR: slow query log:", err)
+		return
+	}
+	defer f.Close()
+	oneLine := strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+	fmt.Fprintf(f, "%s %s elapsed %v read %d\n\tquery: %s\n\tstrategy: %s\n",
+		sq.Time.Format("yyyy-MM-dd HH:mm:ss"), sq.Session,
+		sq.Elapsed.Round(time.Millisecond), sq.RowsRead,
+		oneLine.Replace(sq.Query), oneLine.Replace(sq.Strategy))
+}
+
+// getSlowQueries returns a copy of the recent slow queries, oldest first
+func getSlowQueries() []SlowQuery {
+	slowQueries.lock.Lock()
+	defer slowQueries.lock.Unlock()
+	n := len(slowQueries.list)
+	list := make([]SlowQuery, 0, n)
+	start := 0
+	if n == slowQueryKeep {
+		start = slowQueries.next % slowQueryKeep
+	}
+	for i := range n {
+		list = append(list, slowQueries.list[(start+i)%n])
+	}
+	return list
+}
+
+// RowsRead returns the number of records read from the tables by a query
+func RowsRead(q Query) int {
+	n := 0
+	var walk func(q Query)
+	walk = func(q Query) {
+		switch q := q.(type) {
+		case *Table:
+			n += int(q.ngets + q.nlooks)
+		case q2i:
+			walk(q.Source())
+			walk(q.Source2())
+		case q1i:
+			walk(q.Source())
+		}
+	}
+	walk(q)
+	return n
+}
+
+//-------------------------------------------------------------------
+
+// server_slowqueries is a system table (see systables.go)
+// seq is the key since time, session, and query may not be unique
+
+var slowQueriesFields = [][]string{{"seq", "time", "session", "query",
+	"strategy", "rows_read", "elapsed_ms"}}
+
+func slowQueryRows() []Record {
+	list := getSlowQueries()
+	rows := make([]Record, 0, len(list))
+	for i := range list {
+		s := &list[i]
+		var rb RecordBuilder
+		rb.Add(IntVal(s.Seq))
+		rb.Add(s.Time)
+		rb.Add(SuStr(s.Session))
+		rb.Add(SuStr(s.Query))
+		rb.Add(SuStr(s.Strategy))
+		rb.Add(IntVal(s.RowsRead))
+		rb.Add(IntVal(int(s.Elapsed.Milliseconds())))
+		rows = append(rows, rb.Build())
+	}
+	return rows
+}
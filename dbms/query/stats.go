@@ -0,0 +1,103 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"maps"
+	"slices"
+)
+
+// This file has the optimizer's use of the column statistics
+// saved by Database.Analyze (see db19/meta/stats.go)
+// Without statistics, Where uses the btree structure (RangeFrac)
+// and Join estimates that many to many joins match half the rows.
+
+// statsFrac returns the estimated fraction of rows selected
+// by the spans on the given columns, assuming the columns are independent.
+// It returns false if there are no statistics for one of the columns.
+func (w *Where) statsFrac(cols []string, perCol map[string][]span) (float64, bool) {
+	st := w.tbl.stats
+	if st == nil || len(cols) == 0 {
+		return 0, false
+	}
+	frac := 1.0
+	for _, col := range cols {
+		cs := st.Col(col)
+		if cs == nil {
+			return 0, false
+		}
+		colFrac := 0.0
+		for _, sp := range perCol[col] {
+			if sp.isValue() {
+				colFrac += cs.EqFrac(sp.org.val, st.Nrows)
+				continue
+			}
+			colFrac += cs.RangeFrac(sp.org.val, sp.end.val, st.Nrows)
+			if sp.org.inc { // > instead of >=
+				colFrac -= cs.EqFrac(sp.org.val, st.Nrows)
+			}
+			if sp.end.inc { // <= instead of <
+				colFrac += cs.EqFrac(sp.end.val, st.Nrows)
+			}
+		}
+		frac *= min(max(colFrac, 0), 1)
+	}
+	if st.Nrows > 0 {
+		frac = max(frac, .5/float64(st.Nrows))
+	}
+	return frac, true
+}
+
+// statsWhereFrac returns the estimated selectivity of the whole where.
+// Expressions that are not spans (not in colSels) are estimated as unknownFrac.
+func (w *Where) statsWhereFrac() (float64, bool) {
+	cols := slices.Sorted(maps.Keys(w.colSels))
+	frac, ok := w.statsFrac(cols, w.colSels)
+	if !ok {
+		return 0, false
+	}
+	fields := w.source.Header().Physical()
+	for _, e := range w.expr.Exprs {
+		if _, spans := exprToSpans(e, fields); spans == nil {
+			frac *= unknownFrac
+			break
+		}
+	}
+	return frac, true
+}
+
+// statsDistinct returns the estimated number of distinct values of cols
+// for a Table or a Where on a Table, or 0 if unknown
+func statsDistinct(q Query, cols []string) int {
+	nrows, _ := q.Nrows() // also does Where optInit which sets tbl
+	var tbl *Table
+	switch q := q.(type) {
+	case *Table:
+		tbl = q
+	case *Where:
+		tbl = q.tbl
+	}
+	if tbl == nil || tbl.stats == nil {
+		return 0
+	}
+	d := 1
+	for _, col := range cols {
+		cs := tbl.stats.Col(col)
+		if cs == nil {
+			return 0
+		}
+		d = min(d*max(cs.Distinct, 1), max(tbl.stats.Nrows, 1))
+	}
+	return max(1, min(d, nrows))
+}
+
+// statsJoinNrows estimates the result size of a many to many join
+// as n1 * n2 / max(distinct1, distinct2)
+func statsJoinNrows(src1, src2 Query, by []string, n1, n2 int) (int, bool) {
+	d := max(statsDistinct(src1, by), statsDistinct(src2, by))
+	if d == 0 {
+		return 0, false
+	}
+	return n1 * n2 / d, true
+}
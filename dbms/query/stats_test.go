@@ -0,0 +1,66 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"testing"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestAnalyzeStats(t *testing.T) {
+	db := heapDb()
+	db.adm("create sk (k, a, b) key(k) index(a) index(b)")
+	ut := db.NewUpdateTran()
+	for i := range 1000 {
+		a := 0
+		if i%100 == 0 {
+			a = i // 991 rows with a = 0
+		}
+		ut.Output(nil, "sk", mkrec(i, a, i%100))
+	}
+	ut.Commit()
+	// the estimates are approximate (hll and kll)
+	test := func(query, strategy string, nrows int) {
+		t.Helper()
+		tran := db.NewReadTran()
+		q := ParseQuery(query, tran, nil)
+		q, _, _ = Setup(q, ReadMode, tran)
+		assert.T(t).This(String(q)).Is(strategy)
+		n, _ := q.Nrows()
+		assert.T(t).Msg(query, n).That(nrows*9/10 <= n && n <= nrows*11/10)
+	}
+	test("sk where a is 0 and b < 90", "sk^(b,k) where a is 0 and b < 90", 450)
+
+	st := db.Analyze("sk")
+	assert.T(t).This(st.Nrows).Is(1000)
+	cs := st.Col("a")
+	assert.T(t).This(cs.Mcv).Is([]string{Pack(IntVal(0))})
+	assert.T(t).This(cs.McvCount).Is([]int{991})
+	assert.T(t).That(cs.Distinct >= 9 && cs.Distinct <= 11)
+	assert.T(t).This(db.GetStats("sk")).Is(st)
+
+	test("sk where a is 0 and b < 90", "sk^(b,k) where a is 0 and b < 90", 890)
+	test("sk where a is 0 and b is 5", "sk^(b,k) where a is 0 and b is 5", 10)
+	test("sk where a is 500", "sk^(a,k) where a is 500", 1)
+	test("sk where a > 100", "sk^(a,k) where a > 100", 8)
+	test("sk join by(b) (sk rename k to k2, a to a2)",
+		"sk^(k) join n:n by(b) (sk^(b,k) tempindex(b) rename k to k2, a to a2)",
+		10000)
+
+	db.adm("rename sk to sk2")
+	assert.T(t).That(db.GetStats("sk") == nil)
+	assert.T(t).This(db.GetStats("sk2").Nrows).Is(1000)
+	db.adm("drop sk2")
+	assert.T(t).That(db.GetStats("sk2") == nil)
+}
+
+func mkrec(vals ...int) Record {
+	var b RecordBuilder
+	for _, v := range vals {
+		b.Add(IntVal(v))
+	}
+	return b.Build()
+}
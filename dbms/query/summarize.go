@@ -6,12 +6,17 @@ package query
 import (
 	"fmt"
 	"log"
+	"math"
+	"slices"
 	"sort"
 	"strings"
 	"sync/atomic"
 
 	. "github.com/apmckinlay/gsuneido/core"
 	"github.com/apmckinlay/gsuneido/util/assert"
+	"github.com/apmckinlay/gsuneido/util/dnum"
+	"github.com/apmckinlay/gsuneido/util/hll"
+	"github.com/apmckinlay/gsuneido/util/kll"
 	"github.com/apmckinlay/gsuneido/util/set"
 	"github.com/apmckinlay/gsuneido/util/shmap"
 	"github.com/apmckinlay/gsuneido/util/slc"
@@ -119,7 +124,9 @@ func defaultColName(op, on string) string {
 	if op == "count" {
 		return "count"
 	}
-	return op + "_" + on
+	// e.g. "percentile 90" => percentile90, "percentile 12.5" => percentile12_5
+	op = strings.ReplaceAll(op, " ", "")
+	return strings.ReplaceAll(op, ".", "_") + "_" + on
 }
 
 // Len / Less / Swap implement sort.Interface
@@ -644,12 +651,12 @@ func (su *Summarize) addToSums(sums []sumOp, row Row, th *Thread, st *SuTran) {
 			switch su.ops[i] {
 			case "count":
 				sums[i].add("", nil, row)
-			case "list", "min", "max":
+			case "list", "min", "max", "countdistinct":
 				if raw == "*uninit*" {
 					raw = row.GetRawVal(su.source.Header(), col, th, st)
 				}
 				sums[i].add(raw, nil, row)
-			default: // total, average
+			default: // total, average, median, percentile, stddev, variance
 				if val == nil {
 					val = row.GetVal(su.source.Header(), col, th, st)
 				}
@@ -762,6 +769,17 @@ func newSumOp(op string) sumOp {
 		return &sumMax{}
 	case "list":
 		return sumList(make(map[string]struct{}))
+	case "median":
+		return &sumPercentile{p: dnum.FromStr(".5")}
+	case "stddev":
+		return &sumVariance{stddev: true}
+	case "variance":
+		return &sumVariance{}
+	case "countdistinct":
+		return &sumDistinct{raws: make(map[string]struct{})}
+	}
+	if pct, ok := strings.CutPrefix(op, "percentile "); ok {
+		return &sumPercentile{p: dnum.Div(dnum.FromStr(pct), dnum.FromInt(100))}
 	}
 	panic(assert.ShouldNotReachHere())
 }
@@ -883,3 +901,128 @@ func (sum sumList) reset() {
 		delete(sum, k)
 	}
 }
+
+// sumExactLimit is the number of values that percentile (and median)
+// and countdistinct keep before switching to approximate sketches
+const sumExactLimit = 16384 // ???
+
+// sumPercentile ignores values that are not numbers (like total).
+// It interpolates between the closest values.
+type sumPercentile struct {
+	p    dnum.Dnum // 0 to 1
+	vals []dnum.Dnum
+	sk   *kll.Sketch[float64]
+}
+
+func (sum *sumPercentile) add(_ string, val Value, _ Row) {
+	dn, ok := val.ToDnum()
+	if !ok {
+		return
+	}
+	if sum.sk != nil {
+		sum.sk.Insert(dn.ToFloat())
+		return
+	}
+	sum.vals = append(sum.vals, dn)
+	if len(sum.vals) > sumExactLimit {
+		sum.sk = kll.New[float64]()
+		for _, v := range sum.vals {
+			sum.sk.Insert(v.ToFloat())
+		}
+		sum.vals = nil
+	}
+}
+func (sum *sumPercentile) result() (Value, Row) {
+	if sum.sk != nil {
+		return SuDnum{Dnum: dnum.FromFloat(sum.sk.Query(sum.p.ToFloat()))}, nil
+	}
+	if len(sum.vals) == 0 {
+		return EmptyStr, nil
+	}
+	vals := slices.Clone(sum.vals) // don't disturb add order
+	slices.SortFunc(vals, dnum.Compare)
+	pos := dnum.Mul(sum.p, dnum.FromInt(int64(len(vals)-1)))
+	i, _ := pos.Trunc().ToInt()
+	dn := vals[i]
+	if frac := pos.Frac(); !frac.IsZero() {
+		dn = dnum.Add(dn, dnum.Mul(dnum.Sub(vals[i+1], dn), frac))
+	}
+	return SuDnum{Dnum: dn}, nil
+}
+func (sum *sumPercentile) reset() {
+	sum.vals = sum.vals[:0]
+	sum.sk = nil
+}
+
+// sumVariance is the sample variance (or standard deviation).
+// It ignores values that are not numbers (like total).
+// It is "" if there are no values and 0 if there is only one.
+// It uses Welford's algorithm.
+type sumVariance struct {
+	n      int
+	mean   float64
+	m2     float64
+	stddev bool
+}
+
+func (sum *sumVariance) add(_ string, val Value, _ Row) {
+	dn, ok := val.ToDnum()
+	if !ok {
+		return
+	}
+	x := dn.ToFloat()
+	sum.n++
+	delta := x - sum.mean
+	sum.mean += delta / float64(sum.n)
+	sum.m2 += delta * (x - sum.mean)
+}
+func (sum *sumVariance) result() (Value, Row) {
+	if sum.n == 0 {
+		return EmptyStr, nil // like percentile
+	}
+	if sum.n == 1 {
+		return Zero, nil
+	}
+	v := sum.m2 / float64(sum.n-1)
+	if sum.stddev {
+		v = math.Sqrt(v)
+	}
+	return SuDnum{Dnum: dnum.FromFloat(v)}, nil
+}
+func (sum *sumVariance) reset() {
+	sum.n = 0
+	sum.mean = 0
+	sum.m2 = 0
+}
+
+// sumDistinct is exact up to sumExactLimit,
+// after that it switches to an approximate HyperLogLog
+type sumDistinct struct {
+	raws map[string]struct{}
+	hll  *hll.HLL
+}
+
+func (sum *sumDistinct) add(raw string, _ Value, _ Row) {
+	if sum.hll != nil {
+		sum.hll.Add(raw)
+		return
+	}
+	sum.raws[raw] = struct{}{}
+	if len(sum.raws) > sumExactLimit {
+		sum.hll = hll.New()
+		for raw := range sum.raws {
+			sum.hll.Add(raw)
+		}
+		clear(sum.raws)
+	}
+}
+func (sum *sumDistinct) result() (Value, Row) {
+	if sum.hll != nil {
+		return Int64Val(int64(sum.hll.Count())), nil
+	}
+	return IntVal(len(sum.raws)), nil
+}
+func (sum *sumDistinct) reset() {
+	clear(sum.raws)
+	sum.hll = nil
+}
@@ -4,6 +4,7 @@
 package query
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -68,3 +69,63 @@ func TestSummarize_Indexes(t *testing.T) {
 	idxs = projectIndexes(srcidxs, []string{})
 	assert.T(t).This(idxs).Is(nil)
 }
+
+func TestSummarizeStats(t *testing.T) {
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	defer db.Close()
+	MakeSuTran = func(qt QueryTran) *SuTran { return nil }
+	doAdmin(db, "create test (a, b, c) key(a)")
+	act(db, "insert { a: 1, b: 1, c: 10 } into test")
+	act(db, "insert { a: 2, b: 1, c: 20 } into test")
+	act(db, "insert { a: 3, b: 1, c: 20 } into test")
+	act(db, "insert { a: 4, b: 1, c: 50 } into test")
+	act(db, "insert { a: 5, b: 1, c: 'x' } into test")
+	act(db, "insert { a: 6, b: 2, c: 7 } into test")
+	act(db, "insert { a: 7, b: 3, c: 'y' } into test") // no numbers
+
+	tran := sizeTran{db.NewReadTran()}
+	q := ParseQuery("test summarize b, median c, percentile 90 c, "+
+		"stddev c, variance c, countdistinct c", tran, nil)
+	q, _, _ = Setup(q, ReadMode, tran)
+	hdr := q.Header()
+	assert.T(t).This(hdr.Columns).Is([]string{"b", "median_c",
+		"percentile90_c", "stddev_c", "variance_c", "countdistinct_c"})
+	get := func(col string) string {
+		return q.Get(nil, Next).GetVal(hdr, col, nil, nil).String()
+	}
+	test := func(col string, expected ...string) {
+		t.Helper()
+		q.Rewind()
+		for _, e := range expected {
+			assert.T(t).This(get(col)).Is(e)
+		}
+	}
+	test("median_c", "20", "7", `""`)
+	test("percentile90_c", "41", "7", `""`)
+	test("variance_c", "300", "0", `""`)
+	test("stddev_c", "17.32050807568877", "0", `""`)
+	test("countdistinct_c", "4", "1")
+
+	// non-integer percentile gives a valid column name
+	q = ParseQuery("test summarize b, percentile 12.5 c "+
+		"extend p = percentile12_5_c", tran, nil)
+	q, _, _ = Setup(q, ReadMode, tran)
+	hdr = q.Header()
+	assert.T(t).This(hdr.Columns).Is([]string{"b", "percentile12_5_c", "p"})
+}
+
+func TestSummarizeApprox(t *testing.T) {
+	const n = 3 * sumExactLimit
+	pct := newSumOp("median")
+	dist := newSumOp("countdistinct")
+	for i := range n {
+		pct.add("", IntVal(i), nil)
+		dist.add(Pack(IntVal(i%(2*sumExactLimit))), nil, nil)
+	}
+	med, _ := pct.result()
+	assert.T(t).That(math.Abs(ToDnum(med).ToFloat()-n/2) < n/10)
+	cnt, _ := dist.result()
+	assert.T(t).That(math.Abs(float64(ToInt(cnt))-2*sumExactLimit) <
+		2*sumExactLimit/10)
+}
@@ -0,0 +1,214 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"slices"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/util/str"
+	"github.com/apmckinlay/gsuneido/util/tsc"
+)
+
+// systables implements virtual tables for the live server state:
+// server_slowqueries, server_sessions, server_transactions,
+// server_exclusive, and server_info.
+// Unlike the schema tables they do not come from the database state
+// so they are not consistent with the transaction.
+
+// Session is an entry in the sessions system table
+type Session struct {
+	Connected SuDate
+	Session   string
+	User      string
+	Address   string
+}
+
+// These are set by dbms to supply the server state for the system tables
+var (
+	GetSessions   = func() []Session { return nil }
+	GetTranInfo   = func() []db19.TranInfo { return nil }
+	GetExclusive  = func() []string { return nil }
+	GetServerInfo = func() *SuObject { return &SuObject{} }
+)
+
+// sysTable is a system table that is a list of rows
+// that are built when the query is first read
+type sysTable struct {
+	schemaTable
+	name    string
+	fields  [][]string
+	keys    [][]string
+	getRows func() []Record
+	rows    []Record
+	i       int
+}
+
+// newSysTable returns nil if name is not a system table
+func newSysTable(name string) *sysTable {
+	st := &sysTable{name: name}
+	switch name {
+	case "server_slowqueries":
+		st.fields = slowQueriesFields
+		st.keys = [][]string{{"seq"}}
+		st.getRows = slowQueryRows
+	case "server_sessions":
+		st.fields = sessionsFields
+		st.keys = [][]string{{"session", "address", "connected"}}
+		st.getRows = sessionRows
+	case "server_transactions":
+		st.fields = transactionsFields
+		st.keys = [][]string{{"tran"}}
+		st.getRows = tranRows
+	case "server_exclusive":
+		st.fields = exclusiveFields
+		st.keys = [][]string{{"table"}}
+		st.getRows = exclusiveRows
+	case "server_info":
+		st.fields = infoFields
+		st.keys = [][]string{{"name"}}
+		st.getRows = infoRows
+	default:
+		return nil
+	}
+	return st
+}
+
+func (st *sysTable) String() string {
+	return st.name
+}
+
+func (st *sysTable) Transform() Query {
+	return st
+}
+
+func (st *sysTable) Keys() [][]string {
+	return st.keys
+}
+
+func (st *sysTable) Columns() []string {
+	return st.fields[0]
+}
+
+func (st *sysTable) Header() *Header {
+	return NewHeader(st.fields, st.fields[0])
+}
+
+func (st *sysTable) Nrows() (int, int) {
+	st.ensure()
+	return len(st.rows), len(st.rows)
+}
+
+func (st *sysTable) SetTran(tran QueryTran) {
+	st.tran = tran
+	st.rows = nil
+}
+
+func (st *sysTable) Rewind() {
+	st.state = rewound
+}
+
+func (st *sysTable) Get(_ *Thread, dir Dir) Row {
+	defer func(t uint64) { st.tget += tsc.Read() - t }(tsc.Read())
+	st.ensure()
+	if st.state == eof {
+		return nil
+	}
+	if dir == Next {
+		if st.state == rewound {
+			st.i = -1
+		}
+		st.i++
+	} else { // Prev
+		if st.state == rewound {
+			st.i = len(st.rows)
+		}
+		st.i--
+	}
+	if st.i < 0 || len(st.rows) <= st.i {
+		st.state = eof
+		return nil
+	}
+	st.state = within
+	st.ngets++
+	return Row{DbRec{Record: st.rows[st.i]}}
+}
+
+func (st *sysTable) ensure() {
+	if st.rows == nil {
+		st.rows = st.getRows()
+		if st.rows == nil {
+			st.rows = []Record{}
+		}
+	}
+}
+
+//-------------------------------------------------------------------
+
+var sessionsFields = [][]string{{"session", "user", "address", "connected"}}
+
+func sessionRows() []Record {
+	var rows []Record
+	for _, s := range GetSessions() {
+		var rb RecordBuilder
+		rb.Add(SuStr(s.Session))
+		rb.Add(SuStr(s.User))
+		rb.Add(SuStr(s.Address))
+		rb.Add(s.Connected)
+		rows = append(rows, rb.Build())
+	}
+	return rows
+}
+
+var transactionsFields = [][]string{{"tran", "age", "reads", "writes",
+	"tables"}}
+
+func tranRows() []Record {
+	var rows []Record
+	for _, t := range GetTranInfo() {
+		var rb RecordBuilder
+		rb.Add(IntVal(t.Tran))
+		rb.Add(IntVal(t.Age))
+		rb.Add(IntVal(t.Reads))
+		rb.Add(IntVal(t.Writes))
+		rb.Add(SuStr(str.Join(",", t.Tables)))
+		rows = append(rows, rb.Build())
+	}
+	return rows
+}
+
+var exclusiveFields = [][]string{{"table"}}
+
+func exclusiveRows() []Record {
+	var rows []Record
+	for _, table := range GetExclusive() {
+		var rb RecordBuilder
+		rb.Add(SuStr(table))
+		rows = append(rows, rb.Build())
+	}
+	return rows
+}
+
+var infoFields = [][]string{{"name", "value"}}
+
+func infoRows() []Record {
+	ob := GetServerInfo()
+	names := make([]string, 0, ob.NamedSize())
+	vals := make(map[string]Value, ob.NamedSize())
+	iter := ob.Iter2(false, true)
+	for k, v := iter(); k != nil; k, v = iter() {
+		names = append(names, ToStr(k))
+		vals[ToStr(k)] = v
+	}
+	slices.Sort(names)
+	rows := make([]Record, 0, len(names))
+	for _, name := range names {
+		var rb RecordBuilder
+		rb.Add(SuStr(name))
+		rb.Add(vals[name].(Packable))
+		rows = append(rows, rb.Build())
+	}
+	return rows
+}
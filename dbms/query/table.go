@@ -32,7 +32,11 @@ func NewTable(t QueryTran, name string) Query {
 	case "history":
 		tbl = &History{}
 	default:
-		tbl = &Table{name: name}
+		if st := newSysTable(name); st != nil {
+			tbl = st
+		} else {
+			tbl = &Table{name: name}
+		}
 	}
 	tbl.SetTran(t)
 	return tbl
@@ -43,6 +47,7 @@ type Table struct {
 	tran        QueryTran
 	iter        index.IndexIter
 	info        *meta.Info
+	stats       *meta.Stats // from Database.Analyze, may be nil
 	schema      *Schema
 	name        string
 	allKeys     [][]string
@@ -84,6 +89,7 @@ func (tbl *Table) SetTran(t QueryTran) {
 		panic("nonexistent table: " + tbl.name)
 	}
 	tbl.info = t.GetInfo(tbl.name)
+	tbl.stats = t.GetStats(tbl.name)
 	tbl.rowSiz.Set(tbl.getRowSize())
 
 	cols := make([]string, 0, len(tbl.schema.Columns)+len(tbl.schema.Derived))
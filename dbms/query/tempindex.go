@@ -337,6 +337,7 @@ func (it singleIter) get() Row {
 //-------------------------------------------------------------------
 // multi is when we have multiple records (not ti.source.SingleTable).
 // So we store the Row's in the sortlist.
+// If the rows exceed the memory budget they are spilled to disk (tempspill.go)
 
 type multiIter struct {
 	iter *sortlist.Iter[Row]
@@ -344,11 +345,17 @@ type multiIter struct {
 
 func (ti *TempIndex) multi() rowIter {
 	var th2 Thread // separate thread because sortlist runs in the background
-	b := sortlist.NewSorting(
-		func(row Row) bool { return row == nil },
-		func(xrow, yrow Row) bool {
-			return ti.less(&th2, xrow, yrow)
-		})
+	newBuilder := func() *sortlist.Builder[Row] {
+		return sortlist.NewSorting(
+			func(row Row) bool { return row == nil },
+			func(xrow, yrow Row) bool {
+				return ti.less(&th2, xrow, yrow)
+			})
+	}
+	b := newBuilder()
+	var spill *tempSpill
+	mem := 0
+	limit := spillLimit()
 	nrows := 0
 	warned := false
 	derived := 0
@@ -370,14 +377,27 @@ func (ti *TempIndex) multi() rowIter {
 			derivedWarned = true
 		}
 		b.Add(row)
+		if mem += rowMem(row); mem > limit {
+			// over the memory budget so write a sorted run to disk
+			if spill == nil {
+				spill = newTempSpill(ti)
+			}
+			spill.run(b.Finish())
+			b = newBuilder()
+			mem = 0
+		}
 	}
-	if nrows > 2*tempindexWarn {
+	if spill == nil && nrows > 2*tempindexWarn {
 		log.Println("temp index large =", nrows)
 	}
-	if derived > 2*derivedWarn {
+	if spill == nil && derived > 2*derivedWarn {
 		log.Println("temp index derived large =",
 			derived, "average", derived/nrows)
 	}
+	if spill != nil {
+		spill.run(b.Finish())
+		return spill.merge()
+	}
 	// NOTE: the closure captures ti not ti.th
 	lt := func(row Row, key []string) bool {
 		return ti.less2(ti.th, row, key)
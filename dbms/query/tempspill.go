@@ -0,0 +1,329 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"os"
+	"runtime"
+	"sync/atomic"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/options"
+	"github.com/apmckinlay/gsuneido/util/assert"
+	"github.com/apmckinlay/gsuneido/util/sortlist"
+)
+
+// tempSpill is used by TempIndex.multi when the rows exceed the memory budget
+// (options.TempIndexMB). It is an external merge sort.
+// Each time the budget is exceeded, the sorted rows are written
+// to a temp file as a run. At the end the runs are merged
+// into a second temp file which is read a block at a time by spillIter.
+//
+// Database records (Off != 0) are written as just their offset
+// and are read back with GetRecord.
+// Derived records (Off == 0) e.g. from extend or summarize, are written in full.
+type tempSpill struct {
+	ti     *TempIndex
+	file   *os.File
+	w      *bufio.Writer
+	pos    int64
+	starts []int64 // the file offset of each run
+	counts []int   // the number of rows in each run
+	buf    []byte
+}
+
+var spillCount atomic.Int64
+var _ = AddInfo("query.tempindex.spill", &spillCount)
+
+// spillBlock is the number of rows in a block of the merged file
+const spillBlock = 256
+
+// rowMem is an estimate of the memory used by a row in a TempIndex.
+// Database records are not counted since they are in the (mmap) database.
+func rowMem(row Row) int {
+	return 24 + 24*len(row) + row.Derived()
+}
+
+// spillLimit returns the memory budget in bytes (overridden by tests)
+var spillLimit = func() int {
+	return options.TempIndexMB << 20
+}
+
+func newTempSpill(ti *TempIndex) *tempSpill {
+	spillCount.Add(1)
+	f, err := os.CreateTemp("", "gs*.tmp")
+	ck(err)
+	// remove it now (and in the cleanup for Windows)
+	// so it does not leak if the query panics or is abandoned before merge
+	os.Remove(f.Name())
+	sp := &tempSpill{ti: ti, file: f, w: bufio.NewWriter(f)}
+	runtime.AddCleanup(sp, func(f *os.File) {
+		f.Close()
+		os.Remove(f.Name())
+	}, f)
+	return sp
+}
+
+// run writes a sorted list of rows as a run
+func (sp *tempSpill) run(list sortlist.List[Row]) {
+	sp.starts = append(sp.starts, sp.pos)
+	n := 0
+	it := list.Iter(nil)
+	for it.Next(); !it.Eof(); it.Next() {
+		sp.buf = appendRow(sp.buf[:0], it.Cur())
+		_, err := sp.w.Write(sp.buf)
+		ck(err)
+		sp.pos += int64(len(sp.buf))
+		n++
+	}
+	sp.counts = append(sp.counts, n)
+}
+
+func appendRow(buf []byte, row Row) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(row)))
+	for _, dbrec := range row {
+		buf = binary.AppendUvarint(buf, dbrec.Off)
+		if dbrec.Off == 0 {
+			buf = binary.AppendUvarint(buf, uint64(len(dbrec.Record)))
+			buf = append(buf, dbrec.Record...)
+		}
+	}
+	return buf
+}
+
+// merge merges the runs into a new temp file and returns an iterator for it.
+// The runs file is removed.
+func (sp *tempSpill) merge() *spillIter {
+	ck(sp.w.Flush())
+	defer func() {
+		sp.file.Close()
+		os.Remove(sp.file.Name())
+	}()
+	sp.starts = append(sp.starts, sp.pos)
+	mh := &mergeHeap{ti: sp.ti}
+	for i, n := range sp.counts {
+		if n == 0 {
+			continue
+		}
+		r := io.NewSectionReader(sp.file, sp.starts[i], sp.starts[i+1]-sp.starts[i])
+		src := &runReader{r: bufio.NewReader(r), n: n, ti: sp.ti}
+		src.next()
+		mh.runs = append(mh.runs, src)
+	}
+	heap.Init(mh)
+
+	f, err := os.CreateTemp("", "gs*.tmp")
+	ck(err)
+	// Removing an open file works on Unix but not on Windows,
+	// so the cleanup also tries to remove it.
+	os.Remove(f.Name())
+	it := &spillIter{ti: sp.ti, file: f, cacheBlock: -1}
+	runtime.AddCleanup(it, func(f *os.File) {
+		f.Close()
+		os.Remove(f.Name())
+	}, f)
+	w := bufio.NewWriter(f)
+	pos := int64(0)
+	for mh.Len() > 0 {
+		src := mh.runs[0]
+		if it.size%spillBlock == 0 {
+			it.blocks = append(it.blocks, pos)
+		}
+		sp.buf = appendRow(sp.buf[:0], src.row)
+		_, err := w.Write(sp.buf)
+		ck(err)
+		pos += int64(len(sp.buf))
+		it.size++
+		if src.next() {
+			heap.Fix(mh, 0)
+		} else {
+			heap.Pop(mh)
+		}
+	}
+	it.blocks = append(it.blocks, pos) // end of last block
+	ck(w.Flush())
+	return it
+}
+
+// runReader reads the rows of one run
+type runReader struct {
+	r   *bufio.Reader
+	ti  *TempIndex
+	row Row
+	n   int // the number of rows remaining
+}
+
+func (rr *runReader) next() bool {
+	if rr.n <= 0 {
+		rr.row = nil
+		return false
+	}
+	rr.n--
+	nrecs := readUvarint(rr.r)
+	rr.row = make(Row, nrecs)
+	for i := range rr.row {
+		off := readUvarint(rr.r)
+		if off != 0 {
+			rr.row[i] = DbRec{Record: rr.ti.tran.GetRecord(off), Off: off}
+			continue
+		}
+		buf := make([]byte, readUvarint(rr.r))
+		_, err := io.ReadFull(rr.r, buf)
+		ck(err)
+		rr.row[i] = DbRec{Record: Record(buf)}
+	}
+	return true
+}
+
+func readUvarint(r *bufio.Reader) uint64 {
+	n, err := binary.ReadUvarint(r)
+	ck(err)
+	return n
+}
+
+// mergeHeap implements heap.Interface for merging runs
+type mergeHeap struct {
+	ti   *TempIndex
+	runs []*runReader
+}
+
+func (mh *mergeHeap) Len() int {
+	return len(mh.runs)
+}
+
+func (mh *mergeHeap) Less(i, j int) bool {
+	return mh.ti.less(mh.ti.th, mh.runs[i].row, mh.runs[j].row)
+}
+
+func (mh *mergeHeap) Swap(i, j int) {
+	mh.runs[i], mh.runs[j] = mh.runs[j], mh.runs[i]
+}
+
+func (mh *mergeHeap) Push(x any) {
+	mh.runs = append(mh.runs, x.(*runReader))
+}
+
+func (mh *mergeHeap) Pop() any {
+	n := len(mh.runs)
+	x := mh.runs[n-1]
+	mh.runs = mh.runs[:n-1]
+	return x
+}
+
+//-------------------------------------------------------------------
+
+// spillIter is a rowIter over the merged temp file.
+// It has the same semantics as sortlist.Iter.
+// One block of rows is cached.
+type spillIter struct {
+	ti         *TempIndex
+	file       *os.File
+	blocks     []int64 // the file offset of each block, plus the end
+	size       int
+	i          int
+	cache      []Row
+	cacheBlock int
+	state
+}
+
+func (it *spillIter) Rewind() {
+	it.state = rewound
+	it.i = -1
+}
+
+func (it *spillIter) Get(dir Dir) Row {
+	switch it.state {
+	case rewound:
+		if dir == Next {
+			it.i = 0
+		} else {
+			it.i = it.size - 1
+		}
+		it.state = within
+	case eof:
+		return nil
+	default:
+		if dir == Next {
+			it.i++
+		} else {
+			it.i--
+		}
+	}
+	if it.i < 0 || it.i >= it.size {
+		it.state = eof
+		return nil
+	}
+	return it.get(it.i)
+}
+
+// Seek does a binary search to find the first row greater than or equal
+func (it *spillIter) Seek(key []string) Row {
+	first := 0
+	n := it.size
+	for n > 0 {
+		half := n >> 1
+		middle := first + half
+		if it.ti.less2(it.ti.th, it.get(middle), key) {
+			first = middle + 1
+			n -= half + 1
+		} else {
+			n = half
+		}
+	}
+	if first >= it.size {
+		it.state = eof
+		it.i = -1
+		return nil
+	}
+	it.i = first
+	it.state = within
+	return it.get(first)
+}
+
+func (it *spillIter) get(i int) Row {
+	b := i / spillBlock
+	if b != it.cacheBlock {
+		it.load(b)
+	}
+	return it.cache[i%spillBlock]
+}
+
+// load reads a block of rows into the cache
+func (it *spillIter) load(b int) {
+	buf := make([]byte, it.blocks[b+1]-it.blocks[b])
+	_, err := it.file.ReadAt(buf, it.blocks[b])
+	ck(err)
+	n := min(spillBlock, it.size-b*spillBlock)
+	it.cache = make([]Row, n)
+	for r := range n {
+		nrecs, buf2 := uvarint(buf)
+		row := make(Row, nrecs)
+		for j := range row {
+			var off uint64
+			off, buf2 = uvarint(buf2)
+			if off != 0 {
+				row[j] = DbRec{Record: it.ti.tran.GetRecord(off), Off: off}
+				continue
+			}
+			var size uint64
+			size, buf2 = uvarint(buf2)
+			row[j] = DbRec{Record: Record(buf2[:size])}
+			buf2 = buf2[size:]
+		}
+		it.cache[r] = row
+		buf = buf2
+	}
+	assert.That(len(buf) == 0)
+	it.cacheBlock = b
+}
+
+func uvarint(buf []byte) (uint64, []byte) {
+	n, k := binary.Uvarint(buf)
+	assert.That(k > 0)
+	return n, buf[k:]
+}
@@ -0,0 +1,112 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestTempIndexSpill(t *testing.T) {
+	defer func(f func() int) { spillLimit = f }(spillLimit)
+	spillLimit = func() int { return 2000 } // force multiple runs
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	defer db.Close()
+	MakeSuTran = func(qt QueryTran) *SuTran { return nil }
+	doAdmin(db, "create cus (ck, name) key(ck)")
+	doAdmin(db, "create ivc (ik, ck, amt) key(ik)")
+	for i := range 5 {
+		act(db, "insert { ck: "+strconv.Itoa(i)+", name: 'c"+
+			strconv.Itoa(i)+"' } into cus")
+	}
+	const n = 1000
+	amts := make([]int, n)
+	for i := range n {
+		amts[i] = rand.IntN(300)
+		act(db, "insert { ik: "+strconv.Itoa(i)+", ck: "+strconv.Itoa(i%5)+
+			", amt: "+strconv.Itoa(amts[i])+" } into ivc")
+	}
+	slices.Sort(amts)
+
+	rt := db.NewReadTran()
+	q := ParseQuery("ivc join cus extend x = amt sort x", rt, nil)
+	q, _, _ = Setup(q, ReadMode, rt)
+	assert.T(t).This(String(q)).
+		Is("(ivc^(ik) join n:1 by(ck) (cus^(ck) tempindex(ck))) " +
+			"extend x = amt tempindex(x)")
+	ti := q.(*Sort).source.(*TempIndex)
+	q = ti
+	hdr := q.Header()
+	amt := func(row Row) int {
+		return ToInt(row.GetVal(hdr, "amt", nil, nil))
+	}
+	get := func(dir Dir) []int {
+		var list []int
+		for row := q.Get(nil, dir); row != nil; row = q.Get(nil, dir) {
+			assert.This(row.GetVal(hdr, "x", nil, nil)).
+				Is(row.GetVal(hdr, "amt", nil, nil))
+			list = append(list, amt(row))
+		}
+		return list
+	}
+	assert.T(t).This(get(Next)).Is(amts)
+	_, ok := ti.iter.(*spillIter)
+	assert.T(t).That(ok)
+	q.Rewind()
+	rev := slices.Clone(amts)
+	slices.Reverse(rev)
+	assert.T(t).This(get(Prev)).Is(rev)
+
+	// change direction
+	q.Rewind()
+	for range 300 {
+		q.Get(nil, Next)
+	}
+	assert.T(t).This(amt(q.Get(nil, Prev))).Is(amts[298])
+	assert.T(t).This(amt(q.Get(nil, Next))).Is(amts[299])
+
+	// Select and Lookup use Seek
+	val := amts[n/2]
+	q.Select(Sels{{"x", Pack(IntVal(val))}})
+	count := 0
+	for row := q.Get(nil, Next); row != nil; row = q.Get(nil, Next) {
+		assert.T(t).This(amt(row)).Is(val)
+		count++
+	}
+	expected := 0
+	for _, a := range amts {
+		if a == val {
+			expected++
+		}
+	}
+	assert.T(t).This(count).Is(expected)
+	q.Select(Sels{{"x", Pack(IntVal(999))}})
+	assert.T(t).This(q.Get(nil, Next)).Is(nil)
+	q.Select(nil)
+	row := q.Lookup(nil, Sels{{"x", Pack(IntVal(val))}})
+	assert.T(t).This(amt(row)).Is(val)
+	assert.T(t).This(q.Lookup(nil, Sels{{"x", Pack(IntVal(999))}})).Is(nil)
+}
+
+func TestTempSpillRemoved(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("can't remove open files on Windows")
+	}
+	sp := newTempSpill(nil)
+	defer sp.file.Close()
+	// the runs file should not be left behind if merge is never called
+	_, err := os.Stat(sp.file.Name())
+	assert.T(t).That(os.IsNotExist(err))
+}
@@ -160,6 +160,10 @@ func (t testTran) GetStore() *stor.Stor {
 	return nil
 }
 
+func (testTran) GetStats(string) *meta.Stats {
+	return nil
+}
+
 func (t testTran) RangeFrac(table string, iIndex int, org, end string) float64 {
 	if len(end) == 1 && end[0] < 10 {
 		// Number?, String?, Date? ranges
@@ -16,6 +16,8 @@ import (
 type View struct {
 	Query1
 	name string
+	// display is used instead of name by asof
+	display string
 }
 
 var _ Query = (*View)(nil)
@@ -63,6 +65,9 @@ func (v *View) Lookup(*Thread, Sels) Row {
 }
 
 func (v *View) String() string {
+	if v.display != "" {
+		return v.display
+	}
 	return v.name + " /*view*/"
 }
 
@@ -76,6 +76,8 @@ type Where struct {
 	wFixed   Fixed
 
 	wfrac float64
+	// wfracStats is true if wfrac is from column statistics
+	wfracStats bool
 }
 
 type optInited byte
@@ -284,7 +286,7 @@ func (w *Where) calcNrows() (int, int) {
 	if w.singleton {
 		return 1, srcPop
 	}
-	if len(w.idxSels) == 0 {
+	if len(w.idxSels) == 0 && !w.wfracStats {
 		return int(math.Round(float64(srcNrows) * unknownFrac)), srcPop
 	}
 	est := int(math.Round(w.wfrac * float64(srcNrows)))
@@ -361,6 +363,28 @@ func (w *Where) Transform() Query {
 		}
 		e := &ast.Nary{Tok: tok.And, Exprs: after}
 		return NewWhere(q, e, w.t).Transform()
+	case *Window:
+		// move where on the partition columns before window
+		// since it selects whole partitions
+		var before, after []ast.Expr
+		for _, e := range w.expr.Exprs {
+			if set.Subset(q.by, e.Columns()) {
+				before = append(before, e)
+			} else {
+				after = append(after, e)
+			}
+		}
+		if before == nil { // no split
+			return w.transform(src)
+		}
+		src := NewWhere(q.source,
+			&ast.Nary{Tok: tok.And, Exprs: before}, w.t)
+		q = NewWindow(src, q.by, q.order, q.cols, q.ops, q.ons, q.ns)
+		if after == nil {
+			return q.Transform()
+		}
+		e := &ast.Nary{Tok: tok.And, Exprs: after}
+		return NewWhere(q, e, w.t).Transform()
 	case *Intersect:
 		// distribute where over intersect
 		// no project because Intersect Columns are the intersection
@@ -41,8 +41,13 @@ func (w *Where) perIndex(perCol map[string][]span) []*idxSel {
 			idxSels = append(idxSels, isel)
 		}
 	}
+	statsFrac, hasStats := w.statsWhereFrac()
 	if len(idxSels) == 0 {
 		w.wfrac = unknownFrac
+		if hasStats {
+			w.wfrac = statsFrac
+			w.wfracStats = true
+		}
 		return nil
 	}
 
@@ -52,6 +57,10 @@ func (w *Where) perIndex(perCol map[string][]span) []*idxSel {
 	if !minPreFrac.OnlyPrefix() {
 		w.wfrac *= unknownFrac
 	}
+	if hasStats {
+		w.wfrac = statsFrac
+		w.wfracStats = true
+	}
 
 	for _, isel := range idxSels {
 		if isel.OnlyPrefix() || isel.prefixFrac < w.wfrac {
@@ -403,6 +412,12 @@ func (w *Where) moreFilters(index []string, isel *idxSel) (bool, bool) {
 //-------------------------------------------------------------------
 
 func (w *Where) prefixFrac(isel *idxSel) float64 {
+	if isel.prefixLen > 0 {
+		// column statistics (if any) handle skewed data better than RangeFrac
+		if frac, ok := w.statsFrac(isel.index[:isel.prefixLen], w.colSels); ok {
+			return frac
+		}
+	}
 	iIndex := w.tbl.indexi(isel.index)
 	npoints := 0
 	frac := 0.0
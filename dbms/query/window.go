@@ -0,0 +1,435 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/util/assert"
+	"github.com/apmckinlay/gsuneido/util/set"
+	"github.com/apmckinlay/gsuneido/util/slc"
+	"github.com/apmckinlay/gsuneido/util/str"
+	"github.com/apmckinlay/gsuneido/util/tsc"
+)
+
+// Window extends each row with values computed over its partition
+// (the rows with the same "by" values) in "sort" order e.g.
+//
+//	window by (acct) sort (date) balance = total amount
+//
+// Unlike Summarize, it does not collapse the partitions.
+// The source is required to be in by + sort order,
+// so an existing index will be used if there is one,
+// otherwise optimize will add a TempIndex.
+// Each partition is read into memory.
+// The values are always calculated in sort order,
+// regardless of the direction the rows are read.
+type Window struct {
+	Query1
+	st    *SuTran
+	by    []string
+	order []string
+	// cols, ops, ons, and ns are parallel
+	cols []string
+	ops  []string
+	ons  []string
+	ns   []int
+	sels Sels
+	part []Row    // the current partition, in order
+	vals []Record // the window values for part
+	pos  int
+	// next is the first row of the following partition in srcDir
+	next   Row
+	srcDir Dir
+	state
+}
+
+var windowCount atomic.Int64
+var _ = AddInfo("query.window", &windowCount)
+
+// windowOps are the valid window operations
+var windowOps = []string{
+	"row_number", "rank", "total", "average", "lag", "lead"}
+
+func NewWindow(src Query, by, order, cols, ops, ons []string, ns []int) *Window {
+	srcCols := src.Columns()
+	if !set.Subset(srcCols, by) || !set.Subset(srcCols, order) {
+		panic("window: nonexistent columns: " +
+			str.Join(", ", set.Difference(set.Union(by, order), srcCols)))
+	}
+	if len(order) == 0 {
+		panic("window: sort required")
+	}
+	for i, op := range ops {
+		if !slices.Contains(windowOps, op) {
+			panic("window: invalid operation: " + op)
+		}
+		if (ons[i] == "") != (op == "row_number" || op == "rank") {
+			panic("window: " + op + " requires a column")
+		}
+		if ons[i] != "" && !slices.Contains(srcCols, ons[i]) {
+			panic("window: nonexistent column: " + ons[i])
+		}
+		if cols[i] == "" {
+			cols[i] = windowColName(op, ons[i])
+		}
+	}
+	if !set.Disjoint(cols, srcCols) || slc.HasDup(cols) {
+		panic("window: column(s) already exist")
+	}
+	w := &Window{by: by, order: order, cols: cols, ops: ops, ons: ons, ns: ns}
+	w.source = src
+	w.header = w.getHeader()
+	w.keys = src.Keys()
+	w.indexes = [][]string{w.index()}
+	w.fixed = src.Fixed()
+	w.setNrows(src.Nrows())
+	w.rowSiz.Set(src.rowSize() + len(cols)*8) // ???
+	w.fast1.Set(src.fastSingle())
+	w.singleTbl.Set(false)
+	return w
+}
+
+func windowColName(op, on string) string {
+	if on == "" {
+		return op
+	}
+	return op + "_" + on
+}
+
+// index returns the order the source is read in
+func (w *Window) index() []string {
+	return set.Union(w.by, w.order)
+}
+
+func (w *Window) getHeader() *Header {
+	srchdr := w.source.Header()
+	return NewHeader(slc.With(srchdr.Fields, w.cols),
+		slc.With(srchdr.Columns, w.cols...))
+}
+
+func (w *Window) SetTran(t QueryTran) {
+	w.st = MakeSuTran(t)
+	w.source.SetTran(t)
+}
+
+func (w *Window) String() string {
+	var s strings.Builder
+	s.WriteString("window")
+	if len(w.by) > 0 {
+		s.WriteString(" by (" + str.Join(", ", w.by) + ")")
+	}
+	s.WriteString(" sort (" + str.Join(", ", w.order) + ")")
+	sep := " "
+	for i := range w.cols {
+		s.WriteString(sep)
+		sep = ", "
+		if w.cols[i] != windowColName(w.ops[i], w.ons[i]) {
+			s.WriteString(w.cols[i])
+			s.WriteString(" = ")
+		}
+		s.WriteString(w.ops[i])
+		if w.ons[i] != "" {
+			s.WriteString(" ")
+			s.WriteString(w.ons[i])
+		}
+		if w.ns[i] != 0 {
+			s.WriteString(" ")
+			s.WriteString(strconv.Itoa(w.ns[i]))
+		}
+	}
+	return s.String()
+}
+
+func (w *Window) Updateable() string {
+	return ""
+}
+
+func (*Window) Output(*Thread, Record) {
+	panic("can't output to this query")
+}
+
+func (w *Window) knowExactNrows() bool {
+	return w.source.knowExactNrows()
+}
+
+func (w *Window) Transform() Query {
+	src := w.source.Transform()
+	if _, ok := src.(*Nothing); ok {
+		return NewNothing(w)
+	}
+	if src != w.source {
+		return NewWindow(src, w.by, w.order, w.cols, w.ops, w.ons, w.ns)
+	}
+	return w
+}
+
+func (w *Window) optimize(mode Mode, req Require) (Cost, Cost, any) {
+	srcReq, ok := w.srcReq(req)
+	if !ok {
+		return impossible, impossible, nil
+	}
+	fixcost, varcost := Optimize(w.source, mode, srcReq)
+	return fixcost, varcost, nil
+}
+
+// srcReq returns the source Require, which is always the window order.
+// Incoming order and group requirements must be satisfied by that order.
+// Lookup is only allowed if it determines the partition.
+func (w *Window) srcReq(req Require) (Require, bool) {
+	index := w.index()
+	fixed := w.source.Fixed()
+	frac := req.frac
+	switch req.use {
+	case ReqOrder, ReqGroup:
+		if !req.SatisfiedByWithFixed(index, fixed) {
+			return req, false
+		}
+	case ReqUnique:
+		if len(w.by) == 0 || !indexCovered(w.by, req.cols, fixed) {
+			return req, false
+		}
+		nrows, _ := w.source.Nrows()
+		frac = req.SelectFrac(nrows)
+	}
+	return OrderReq(index, frac), true
+}
+
+func (w *Window) setApproach(req Require, _ any, tran QueryTran) {
+	windowCount.Add(1)
+	srcReq, _ := w.srcReq(req)
+	w.source = SetApproach(w.source, srcReq, tran)
+	w.header = w.getHeader()
+	w.state = rewound
+}
+
+// execution --------------------------------------------------------
+
+func (w *Window) Rewind() {
+	w.source.Rewind()
+	w.state = rewound
+}
+
+func (w *Window) Get(th *Thread, dir Dir) Row {
+	defer func(t uint64) { w.tget += tsc.Read() - t }(tsc.Read())
+	if w.state == eof {
+		return nil
+	}
+	if w.state == rewound {
+		w.state = within
+		w.part = nil
+		w.srcDir = dir
+		w.next = w.source.Get(th, dir)
+	}
+	for {
+		if dir == Next {
+			w.pos++
+		} else {
+			w.pos--
+		}
+		if w.pos < 0 || len(w.part) <= w.pos {
+			if !w.nextPart(th, dir) {
+				w.state = eof
+				return nil
+			}
+			continue
+		}
+		row := slc.With(w.part[w.pos], DbRec{Record: w.vals[w.pos]})
+		if w.filter(th, row) {
+			w.ngets++
+			return row
+		}
+	}
+}
+
+// nextPart reads the adjacent partition in the given direction
+func (w *Window) nextPart(th *Thread, dir Dir) bool {
+	if dir != w.srcDir && w.part != nil {
+		// skip back over the current partition
+		if w.next == nil {
+			w.source.Rewind()
+		}
+		for {
+			w.next = w.source.Get(th, dir)
+			if w.next == nil || !w.samePart(th, w.next, w.part[0]) {
+				break
+			}
+		}
+	}
+	w.srcDir = dir
+	if w.next == nil {
+		return false
+	}
+	part := []Row{w.next}
+	for {
+		w.next = w.source.Get(th, dir)
+		if w.next == nil || !w.samePart(th, w.next, part[0]) {
+			break
+		}
+		part = append(part, w.next)
+		if len(part) == mapWarn {
+			Warning("window partition large >", mapWarn)
+		}
+	}
+	if dir == Prev {
+		slices.Reverse(part)
+	}
+	w.part = part
+	w.vals = w.calc(th, part)
+	if dir == Next {
+		w.pos = -1
+	} else {
+		w.pos = len(part)
+	}
+	return true
+}
+
+func (w *Window) samePart(th *Thread, row1, row2 Row) bool {
+	return equalRaw(th, w.st, w.source.Header(), w.by, row1, row2)
+}
+
+func equalRaw(th *Thread, st *SuTran, hdr *Header, cols []string,
+	row1, row2 Row) bool {
+	for _, col := range cols {
+		if row1.GetRawVal(hdr, col, th, st) != row2.GetRawVal(hdr, col, th, st) {
+			return false
+		}
+	}
+	return true
+}
+
+// calc returns the window values for a partition (in order)
+func (w *Window) calc(th *Thread, part []Row) []Record {
+	hdr := w.source.Header()
+	rbs := make([]RecordBuilder, len(part))
+	for c, op := range w.ops {
+		switch op {
+		case "row_number":
+			for i := range part {
+				rbs[i].Add(IntVal(i + 1))
+			}
+		case "rank":
+			rank := 0
+			for i := range part {
+				if i == 0 || !equalRaw(th, w.st, hdr, w.order, part[i-1], part[i]) {
+					rank = i + 1
+				}
+				rbs[i].Add(IntVal(rank))
+			}
+		case "lag", "lead":
+			n := max(1, w.ns[c])
+			if op == "lag" {
+				n = -n
+			}
+			for i := range part {
+				raw := ""
+				if j := i + n; 0 <= j && j < len(part) {
+					raw = part[j].GetRawVal(hdr, w.ons[c], th, w.st)
+				}
+				rbs[i].AddRaw(raw)
+			}
+		case "total", "average":
+			vals := make([]Value, len(part))
+			for i, row := range part {
+				vals[i] = row.GetVal(hdr, w.ons[c], th, w.st)
+			}
+			n := w.ns[c]
+			if op == "total" {
+				n = 0 // running total
+			}
+			var total Value = Zero
+			for i := range part {
+				total = windowAdd(total, vals[i])
+				from := 0
+				if n > 0 && i >= n {
+					from = i - n + 1
+					total = Zero
+					for _, v := range vals[from : i+1] {
+						total = windowAdd(total, v)
+					}
+				}
+				if op == "total" {
+					rbs[i].Add(total.(Packable))
+				} else {
+					rbs[i].Add(OpDiv(total, IntVal(i+1-from)).(Packable))
+				}
+			}
+		default:
+			assert.ShouldNotReachHere()
+		}
+	}
+	vals := make([]Record, len(part))
+	for i := range rbs {
+		vals[i] = rbs[i].Build()
+	}
+	return vals
+}
+
+// windowAdd ignores non-numeric values, like summarize total
+func windowAdd(total, val Value) (result Value) {
+	defer func() {
+		if recover() != nil {
+			result = total
+		}
+	}()
+	return OpAdd(total, val)
+}
+
+func (w *Window) Select(sels Sels) {
+	w.nsels++
+	// only sels on the partition columns can be passed to the source
+	// since the values depend on the whole partition.
+	// All the sels are applied by filter
+	isels, _ := Split(false, sels, w.by)
+	w.sels = sels
+	w.source.Select(isels)
+	w.state = rewound
+}
+
+func (w *Window) filter(th *Thread, row Row) bool {
+	for _, sel := range w.sels {
+		if row.GetRawVal(w.header, sel.col, th, w.st) != sel.val {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *Window) Lookup(th *Thread, sels Sels) Row {
+	w.nlooks++
+	return lookupViaSelectGet(w, th, sels)
+}
+
+func (w *Window) Simple(th *Thread) []Row {
+	w.header = w.getHeader()
+	rows := w.source.Simple(th)
+	hdr := w.source.Header()
+	index := w.index()
+	slices.SortStableFunc(rows, func(x, y Row) int {
+		for _, col := range index {
+			if c := strings.Compare(x.GetRawVal(hdr, col, th, nil),
+				y.GetRawVal(hdr, col, th, nil)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	result := make([]Row, 0, len(rows))
+	for len(rows) > 0 {
+		n := 1
+		for n < len(rows) && equalRaw(th, nil, hdr, w.by, rows[0], rows[n]) {
+			n++
+		}
+		vals := w.calc(th, rows[:n])
+		for i, row := range rows[:n] {
+			result = append(result, append(row, DbRec{Record: vals[i]}))
+		}
+		rows = rows[n:]
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package query
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestWindow(t *testing.T) {
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	defer db.Close()
+	MakeSuTran = func(qt QueryTran) *SuTran { return nil }
+	doAdmin(db, "create trans (id, acct, date, amt) key(id) index(acct, date)")
+	act(db, "insert { id: 1, acct: 'a', date: 1, amt: 10 } into trans")
+	act(db, "insert { id: 2, acct: 'a', date: 2, amt: 20 } into trans")
+	act(db, "insert { id: 3, acct: 'a', date: 2, amt: 30 } into trans")
+	act(db, "insert { id: 4, acct: 'a', date: 4, amt: 'x' } into trans")
+	act(db, "insert { id: 5, acct: 'b', date: 1, amt: 5 } into trans")
+	act(db, "insert { id: 6, acct: 'b', date: 3, amt: 7 } into trans")
+	const cols = "id, row_number, rank, total_amt, average_amt, lag_amt, lead_amt"
+	const query = "trans window by (acct) sort (date) " +
+		"row_number, rank, total amt, average amt 2, lag amt, lead amt 2"
+	expected := []string{
+		`1, 1, 1, 10, 10, "", 30`,
+		`2, 2, 2, 30, 15, 10, 'x'`,
+		`3, 3, 2, 60, 25, 20, ""`,
+		`4, 4, 4, 60, 15, 30, ""`,
+		`5, 1, 1, 5, 5, "", ""`,
+		`6, 2, 2, 12, 6, 5, ""`,
+	}
+	str := func(hdr *Header, row Row) string {
+		var sb strings.Builder
+		for i, col := range strings.Split(cols, ", ") {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(row.GetVal(hdr, col, nil, nil).String())
+		}
+		return sb.String()
+	}
+	rt := db.NewReadTran()
+	q := ParseQuery(query, rt, nil)
+	var simple []string
+	for _, row := range q.Simple(nil) {
+		simple = append(simple, str(q.Header(), row))
+	}
+	assert.T(t).This(simple).Is(expected)
+
+	q = ParseQuery(query, rt, nil)
+	q, _, _ = Setup(q, ReadMode, rt)
+	assert.T(t).This(String(q)).Is("trans^(acct,date,id) " + query[6:])
+	hdr := q.Header()
+	get := func(dir Dir, n int) []string {
+		var list []string
+		for range n {
+			row := q.Get(nil, dir)
+			if row == nil {
+				break
+			}
+			list = append(list, str(hdr, row))
+		}
+		return list
+	}
+	assert.T(t).This(get(Next, 10)).Is(expected)
+	q.Rewind()
+	rev := slices.Clone(expected)
+	slices.Reverse(rev)
+	assert.T(t).This(get(Prev, 10)).Is(rev)
+
+	// change direction across partitions
+	q.Rewind()
+	assert.T(t).This(get(Next, 5)).Is(expected[:5])
+	assert.T(t).This(get(Prev, 2)).Is([]string{expected[3], expected[2]})
+	assert.T(t).This(get(Next, 3)).Is(expected[3:])
+
+	// select on the partition plus a window column
+	q.Select(Sels{{"acct", Pack(SuStr("a"))}, {"rank", Pack(IntVal(2))}})
+	assert.T(t).This(get(Next, 10)).Is(expected[1:3])
+	q.Select(nil)
+
+	// where on the partition is moved before window
+	q = ParseQuery("trans window by (acct) sort (date) rank "+
+		"where acct = 'b' and rank > 1", rt, nil)
+	q, _, _ = Setup(q, ReadMode, rt)
+	assert.T(t).This(String(q)).Is("trans^(acct,date,id) where acct is 'b' " +
+		"window by (acct) sort (date) rank where rank > 1")
+
+	// no matching index so a temp index is added
+	q = ParseQuery("trans window sort (amt) row_number", rt, nil)
+	q, _, _ = Setup(q, ReadMode, rt)
+	assert.T(t).This(String(q)).
+		Is("trans^(id) tempindex(amt) window sort (amt) row_number")
+}
@@ -0,0 +1,73 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package dbms
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/dbms/commands"
+	"github.com/apmckinlay/gsuneido/util/hacks"
+)
+
+// A replica server follows a primary server,
+// applying the primary's database changes to a local read-only replica.
+// See also db19/replica.go
+//
+// If the primary has users, the replica authenticates with
+// SUNEIDO_REPLICA_USER and SUNEIDO_REPLICA_PASSWORD from the environment.
+
+const replicaPoll = time.Second
+
+// StartReplica connects to the primary and brings the replica up to date.
+// It then starts a goroutine to continue following the primary.
+// A lost connection to the primary is fatal (as with other clients).
+func StartReplica(db *db19.Database, addr, port string) {
+	conn := ConnectClient(addr, port)
+	ms := NewDbmsClient(conn).NewSession()
+	replicaAuth(ms)
+	for replicate(ms, db) {
+	}
+	log.Println("replica: following primary", addr+":"+port)
+	go func() {
+		for {
+			if !replicate(ms, db) {
+				time.Sleep(replicaPoll)
+			}
+		}
+	}()
+}
+
+func replicaAuth(ms *muxSession) {
+	user := os.Getenv("SUNEIDO_REPLICA_USER")
+	if user == "" {
+		return
+	}
+	password := os.Getenv("SUNEIDO_REPLICA_PASSWORD")
+	if !AuthPassword(nil, ms, user, password) {
+		log.Fatalln("FATAL: replica: primary authorization failed for", user)
+	}
+}
+
+// replicate requests and applies one chunk of data from the primary.
+// It returns false if there was no more data.
+func replicate(ms *muxSession, db *db19.Database) bool {
+	data := ms.Replicate(db)
+	if len(data) == 0 {
+		return false
+	}
+	db.ApplyReplica(data)
+	return true
+}
+
+// Replicate requests the primary's data following the end of the replica
+func (ms *muxSession) Replicate(db *db19.Database) []byte {
+	off := db.Store.Size()
+	crc := db19.ReplicaCrc(db.Store, off)
+	ms.PutCmd(commands.Replicate).PutInt64(int64(off)).PutInt64(int64(crc))
+	ms.Request()
+	return hacks.Stobs(ms.GetStr_())
+}
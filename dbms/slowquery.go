@@ -0,0 +1,65 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package dbms
+
+import (
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
+	"github.com/apmckinlay/gsuneido/options"
+)
+
+// Queries and cursors that take longer than -slowquery milliseconds
+// are logged by qry.LogSlowQuery (see dbms/query/slowquery.go)
+// The time is the time to optimize the query plus the time spent in Get,
+// not including time waiting for the client.
+// Queries and cursors are logged when they are closed
+// (or when their transaction ends on the server).
+// Query1, QueryFirst, and QueryLast (see get.go) are logged when they finish.
+
+type queryTimer struct {
+	query   string
+	session string
+	elapsed time.Duration
+	logged  bool
+}
+
+// newQueryTimer returns nil if the slow query log is disabled
+func newQueryTimer(query string, start time.Time) *queryTimer {
+	if options.SlowQueryMs <= 0 {
+		return nil
+	}
+	return &queryTimer{query: query, elapsed: time.Since(start)}
+}
+
+// add accumulates the time since start.
+// Usage: defer qt.add(th, time.Now())
+func (qt *queryTimer) add(th *Thread, start time.Time) {
+	if qt == nil {
+		return
+	}
+	qt.elapsed += time.Since(start)
+	if qt.session == "" {
+		qt.session = th.Session()
+	}
+}
+
+func (qt *queryTimer) close(q qry.Query) {
+	if qt == nil || qt.logged {
+		return
+	}
+	qt.logged = true
+	logSlowQuery(qt.session, qt.query, q, qt.elapsed)
+}
+
+func logSlowQuery(session, query string, q qry.Query, elapsed time.Duration) {
+	if options.SlowQueryMs <= 0 ||
+		elapsed < time.Duration(options.SlowQueryMs)*time.Millisecond {
+		return
+	}
+	qry.LogSlowQuery(qry.SlowQuery{Time: Now(), Session: session,
+		Query: query, Strategy: qry.String(q), RowsRead: qry.RowsRead(q),
+		Elapsed: elapsed})
+}
@@ -0,0 +1,83 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+//go:build !gui
+
+package dbms
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
+	"github.com/apmckinlay/gsuneido/options"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	defer func(file string) { qry.SlowQueryFile = file }(qry.SlowQueryFile)
+	qry.SlowQueryFile = filepath.Join(t.TempDir(), "slowquery.log")
+	defer func() { options.SlowQueryMs = 0 }()
+
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	db19.MakeSuTran = func(ut *db19.UpdateTran) *SuTran {
+		return NewSuTran(nil, true)
+	}
+	qry.DoAdmin(db, "create tmp (a, b) key(a)", nil)
+	ut := db.NewUpdateTran()
+	for i := range 10 {
+		qry.DoAction(nil, ut, "insert { a: "+strconv.Itoa(i)+" } into tmp")
+	}
+	ut.Commit()
+	dbms := NewDbmsLocal(db)
+	th := &Thread{}
+	th.SetSession("tester")
+
+	// disabled
+	q := dbms.Transaction(false).Query("tmp", nil, nil).(queryLocal)
+	assert.T(t).That(q.timer == nil)
+
+	options.SlowQueryMs = 100
+	query := "tmp where b is ''"
+	q = dbms.Transaction(false).Query(query, nil, nil).(queryLocal)
+	for row, _ := q.Get(th, Next); row != nil; row, _ = q.Get(th, Next) {
+	}
+	q.Close() // not slow
+	q = dbms.Transaction(false).Query(query, nil, nil).(queryLocal)
+	for row, _ := q.Get(th, Next); row != nil; row, _ = q.Get(th, Next) {
+	}
+	q.timer.elapsed += time.Second // simulate slow
+	q.Close()
+	q.Close() // only logged once
+
+	q = dbms.Transaction(false).Query("server_slowqueries", nil, nil).(queryLocal)
+	hdr := q.Header()
+	row, _ := q.Get(th, Prev)
+	seq := ToInt(row.GetVal(hdr, "seq", nil, nil))
+	assert.T(t).This(row.GetVal(hdr, "query", nil, nil)).Is(SuStr(query))
+	assert.T(t).This(row.GetVal(hdr, "session", nil, nil)).Is(SuStr("tester"))
+	assert.T(t).This(row.GetVal(hdr, "rows_read", nil, nil)).Is(IntVal(10))
+	assert.T(t).That(
+		ToInt(row.GetVal(hdr, "elapsed_ms", nil, nil)) >= 1000)
+	strategy := ToStr(row.GetVal(hdr, "strategy", nil, nil))
+	assert.T(t).That(strings.HasPrefix(strategy, "tmp^(a)"))
+	row, _ = q.Get(th, Prev)
+	assert.T(t).That(row == nil ||
+		row.GetVal(hdr, "query", nil, nil) != SuStr(query))
+	if row != nil {
+		assert.T(t).This(row.GetVal(hdr, "seq", nil, nil)).Is(IntVal(seq - 1))
+	}
+
+	data, err := os.ReadFile(qry.SlowQueryFile)
+	assert.T(t).That(err == nil)
+	assert.T(t).This(strings.Count(string(data), "query: "+query)).Is(1)
+	assert.T(t).That(strings.Contains(string(data), "tester elapsed"))
+}
@@ -0,0 +1,69 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+//go:build !gui
+
+package dbms
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	qry "github.com/apmckinlay/gsuneido/dbms/query"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestSystemTables(t *testing.T) {
+	assert := assert.T(t)
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	defer db.Close()
+	db19.MakeSuTran = func(ut *db19.UpdateTran) *SuTran {
+		return NewSuTran(nil, true)
+	}
+	qry.DoAdmin(db, "create tmp (a, b) key(a)", nil)
+	qry.DoAdmin(db, "create tmp2 (c) key(c)", nil)
+	dbms := NewDbmsLocal(db)
+	th := &Thread{}
+	query := func(query string) string {
+		q := dbms.Transaction(false).Query(query, nil, nil)
+		hdr := q.Header()
+		s := ""
+		for row, _ := q.Get(th, Next); row != nil; row, _ = q.Get(th, Next) {
+			if s != "" {
+				s += " | "
+			}
+			for _, col := range hdr.Columns {
+				s += col + "=" + Display(th, row.GetVal(hdr, col, nil, nil)) + " "
+			}
+		}
+		q.Close()
+		return s
+	}
+
+	assert.This(query("server_sessions where session is 'nonexistent'")).Is("")
+	assert.This(query("server_transactions")).Is("")
+	assert.This(query("server_exclusive")).Is("")
+	assert.This(query("server_info where name is 'currentSize' and value > 0 " +
+		"summarize count")).Is("count=1 ")
+
+	ut := db.NewUpdateTran()
+	qry.DoAction(th, ut, "insert { a: 1 } into tmp")
+	qry.DoAction(th, ut, "insert { a: 2 } into tmp")
+	ut.Read("tmp2", 0, "", "z")
+	assert.This(query("server_transactions where reads > 0 and writes is 2 and " +
+		"tables is 'tmp,tmp2' and age < 60 summarize count")).Is("count=1 ")
+	ut.Commit()
+	assert.This(query("server_transactions")).Is("")
+
+	db.AddExclusive("tmp2")
+	assert.This(query("server_exclusive")).Is(`table="tmp2" `)
+	db.EndExclusive("tmp2")
+	assert.This(query("server_exclusive")).Is("")
+
+	assert.This(func() { qry.DoAdmin(db, "create server_info (a) key(a)", nil) }).
+		Panics("can't modify system table: server_info")
+}
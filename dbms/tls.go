@@ -0,0 +1,116 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package dbms
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+
+	"github.com/apmckinlay/gsuneido/options"
+)
+
+// By default the server uses the embedded ServerCert and ServerKey
+// and the client verifies the server against ServerCert.
+// This encrypts the connection but every installation shares the same key.
+//
+// -tlscert and -tlskey give the server its own certificate and key.
+// The client then needs -tlsca (the CA that signed the server certificate)
+// to verify the server. The client verifies the server name against
+// -tlsservername if given, otherwise the address it connects to.
+//
+// With -tlsclientauth the server requires client certificates
+// signed by its -tlsca. If a client certificate subject common name (CN)
+// is a user in the users table, the connection is authorized as that user
+// without needing Database.Auth (see newServerConn).
+// Clients give their certificate with -tlscert and -tlskey.
+
+// serverTLSConfig returns the TLS configuration for the server
+func serverTLSConfig() (*tls.Config, error) {
+	cert, err := loadKeyPair(ServerCert, ServerKey)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if options.TLSClientAuth {
+		pool, err := loadCA(options.TLSCA)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// clientTLSConfig returns the TLS configuration for connecting to addr
+func clientTLSConfig(addr string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if options.TLSCA == "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ServerCert) {
+			return nil, errors.New("failed to append embedded cert to pool")
+		}
+		config.RootCAs = pool
+		config.ServerName = "localhost" // must match embedded CN or SAN
+	} else {
+		pool, err := loadCA(options.TLSCA)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+		config.ServerName = addr
+	}
+	if options.TLSServerName != "" {
+		config.ServerName = options.TLSServerName
+	}
+	if options.TLSCert != "" {
+		cert, err := loadKeyPair(nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// loadKeyPair loads options.TLSCert and TLSKey if specified,
+// otherwise it uses the given default PEM data
+func loadKeyPair(certPEM, keyPEM []byte) (tls.Certificate, error) {
+	if options.TLSCert != "" {
+		var err error
+		if certPEM, err = os.ReadFile(options.TLSCert); err != nil {
+			return tls.Certificate{}, err
+		}
+		if keyPEM, err = os.ReadFile(options.TLSKey); err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+func loadCA(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in " + file)
+	}
+	return pool, nil
+}
+
+// certUser returns the subject common name of a verified client certificate,
+// or "" if there isn't one
+func certUser(cs tls.ConnectionState) string {
+	if len(cs.VerifiedChains) == 0 || len(cs.PeerCertificates) == 0 {
+		return ""
+	}
+	return cs.PeerCertificates[0].Subject.CommonName
+}
@@ -0,0 +1,169 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+//go:build !gui
+
+package dbms
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/apmckinlay/gsuneido/core"
+	"github.com/apmckinlay/gsuneido/db19"
+	"github.com/apmckinlay/gsuneido/db19/meta/schema"
+	"github.com/apmckinlay/gsuneido/db19/stor"
+	"github.com/apmckinlay/gsuneido/dbms/mux"
+	"github.com/apmckinlay/gsuneido/options"
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := makeCert(t, dir, "ca", "Test CA", nil, nil)
+	makeCert(t, dir, "server", "localhost", ca, caKey)
+	makeCert(t, dir, "admin", "admin", ca, caKey)
+	makeCert(t, dir, "guest", "guest", ca, caKey)
+	other, otherKey := makeCert(t, dir, "other", "Other CA", nil, nil)
+	makeCert(t, dir, "stranger", "admin", other, otherKey)
+	file := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	defer func() {
+		options.TLSCert, options.TLSKey, options.TLSCA = "", "", ""
+		options.TLSClientAuth = false
+	}()
+
+	db := db19.CreateDb(stor.HeapStor(8192))
+	db19.StartConcur(db, 50*time.Millisecond)
+	db.Create(&schema.Schema{Table: "users",
+		Columns: []string{"user", "passhash"},
+		Indexes: []schema.Index{{Mode: 'k', Columns: []string{"user"}}}})
+	ut := db.NewUpdateTran()
+	var b RecordBuilder
+	ut.Output(nil, "users", b.Add(SuStr("admin")).Add(SuStr("")).Build())
+	ut.Commit()
+	dbmsLocal := NewDbmsLocal(db)
+	assert.T(t).That(dbmsLocal.userExists("admin"))
+	assert.T(t).That(!dbmsLocal.userExists("guest"))
+
+	configs := func(client string) (*tls.Config, *tls.Config) {
+		options.TLSCert, options.TLSKey = file("server.crt"), file("server.key")
+		options.TLSCA, options.TLSClientAuth = file("ca.crt"), true
+		serverConfig, err := serverTLSConfig()
+		assert.T(t).That(err == nil)
+		options.TLSClientAuth = false
+		options.TLSCert, options.TLSKey = file(client+".crt"), file(client+".key")
+		clientConfig, err := clientTLSConfig("localhost")
+		assert.T(t).That(err == nil)
+		return serverConfig, clientConfig
+	}
+	connect := func(client string) *dbmsClient {
+		serverConfig, clientConfig := configs(client)
+		options.BuiltDate = "Dec 29 2020 12:34"
+		p1, p2 := net.Pipe()
+		workers = mux.NewWorkers(doRequest)
+		go newServerConn(dbmsLocal, p1, serverConfig)
+		assert.T(t).This(checkHello(p2)).Is("")
+		p2.Write(hello())
+		tlsConn := tls.Client(p2, clientConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			t.Fatal(err)
+		}
+		return NewDbmsClient(tlsConn)
+	}
+	handshake := func(serverConfig, clientConfig *tls.Config) (error, error) {
+		p1, p2 := net.Pipe()
+		errc := make(chan error, 1)
+		go func() {
+			errc <- tls.Server(p1, serverConfig).Handshake()
+			p1.Close()
+		}()
+		err := tls.Client(p2, clientConfig).Handshake()
+		p2.Close() // so the server isn't blocked writing an alert
+		return <-errc, err
+	}
+	query := SuObjectOf(SuStr("tables sort table"))
+
+	// certificate for a user is authorized
+	ses := connect("admin").NewSession()
+	row, _, _ := ses.Get(nil, query, Next)
+	assert.T(t).That(row != nil)
+	ses.Close()
+
+	// certificate for a non-user is not authorized
+	ses = connect("guest").NewSession()
+	assert.T(t).This(func() { ses.Get(nil, query, Next) }).Panics("not authorized")
+
+	// certificate from a different CA is rejected
+	serverErr, _ := handshake(configs("stranger"))
+	assert.T(t).That(serverErr != nil)
+
+	// client verifies the server against the pinned CA
+	serverConfig, _ := configs("admin")
+	options.TLSCert, options.TLSKey = "", ""
+	options.TLSCA = file("other.crt")
+	clientConfig, err := clientTLSConfig("localhost")
+	assert.T(t).That(err == nil)
+	_, clientErr := handshake(serverConfig, clientConfig)
+	assert.T(t).That(clientErr != nil)
+}
+
+// makeCert writes name.crt and name.key to dir.
+// If parent is nil it makes a self-signed CA certificate.
+func makeCert(t *testing.T, dir, name, cn string,
+	parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (
+	*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames: []string{cn},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent,
+		&key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(file, typ string, der []byte) {
+		data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(name+".crt", "CERTIFICATE", der)
+	write(name+".key", "EC PRIVATE KEY", keyDer)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
@@ -10,6 +10,8 @@ import (
 	"io"
 	"io/fs"
 	"log"
+	"math"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -41,11 +43,18 @@ var help = `options:
 	-compact
 	-d[ump] [table]
 	-h[elp] or -?
-	-l[oad] [table] (or @filename)
+	-l[oad] [table] (or @filename or @base,incremental,...)
 	-p[ass]p[hrase]=string (for -load)
 	-p[ort][=#] (default 3147)
 	-repair
-	-s[erver]
+	-restore=archive [-asof=yyyymmdd.hhmmss]
+	-s[erver] [-archive=directory] [-replicate=address[:port]]
+	-slowquery=ms (log queries slower than this)
+	-tempindexmb=# (default 256)
+	-tlscert=file -tlskey=file (server or client certificate)
+	-tlsca=file (for -client to verify the server, or with -tlsclientauth)
+	-tlsclientauth (for -server, require client certificates)
+	-tlsservername=name (for -client, default the -client address)
 	-v[ersion]
 	-w[eb][=#] (default -port + 1)`
 
@@ -137,7 +146,9 @@ func main() {
 			}
 		}
 		if table == "" {
-			nTables, nViews, err := tools.LoadDatabase(from, "suneido.db",
+			// @base.su,incremental1.su,incremental2.su,...
+			froms := strings.Split(from, ",")
+			nTables, nViews, err := tools.LoadDatabaseChain(froms, "suneido.db",
 				privateKey, options.Passphrase)
 			ck(err)
 			Alert("loaded", nTables, "tables", nViews, "views in",
@@ -177,6 +188,16 @@ func main() {
 				"\nrepaired database in", time.Since(t).Round(time.Millisecond))
 		}
 		os.Exit(0)
+	case "restore":
+		t := time.Now()
+		asof, err := parseAsof(options.Asof)
+		ck(err)
+		restored, err := db19.RestoreArchive(options.Arg, "suneido.db", asof)
+		ck(err)
+		Alert("restored database as of",
+			time.UnixMilli(restored).Format("2006-01-02 15:04:05"),
+			"in", time.Since(t).Round(time.Millisecond))
+		os.Exit(0)
 	case "version":
 		Alert("gSuneido " + options.BuiltStr())
 		os.Exit(0)
@@ -303,7 +324,11 @@ func sendErrorLog(d IDbms, sessionId string) {
 
 // runServer does not return
 func runServer() {
-	openDbms()
+	if options.Replicate != "" {
+		openReplica()
+	} else {
+		openDbms()
+	}
 	startHttpStatus()
 	run("Init()")
 	options.DbStatus.Store("")
@@ -351,6 +376,11 @@ func openDbms() {
 		}
 		options.DbStatus.Store("starting")
 	}
+	if options.Archive != "" {
+		if err := db.StartArchive(options.Archive); err != nil {
+			Fatal(err)
+		}
+	}
 	db19.StartTimestamps()
 	db19.StartConcur(db, persistInterval())
 	dbmsLocal = dbms.NewDbmsLocal(db)
@@ -370,6 +400,46 @@ func openDbms() {
 	// go checkState()
 }
 
+// parseAsof converts the -asof option to unix milli.
+// It defaults to the latest state.
+func parseAsof(s string) (int64, error) {
+	if s == "" {
+		return math.MaxInt64, nil
+	}
+	for _, layout := range []string{"20060102.150405", "20060102.1504",
+		"20060102", "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.UnixMilli(), nil
+		}
+	}
+	return 0, errors.New("invalid asof time: " + s)
+}
+
+// openReplica opens a read-only replica database
+// and brings it up to date from the primary
+func openReplica() {
+	log.Println("start replica")
+	var err error
+	db, err = db19.OpenReplica("suneido.db")
+	if err != nil {
+		Fatal("replica:", err)
+	}
+	addr, port := options.Replicate, "3147"
+	if h, p, err := net.SplitHostPort(addr); err == nil {
+		addr, port = h, p
+	}
+	dbms.StartReplica(db, addr, port)
+	db19.StartTimestamps()
+	dbmsLocal = dbms.NewDbmsLocal(db)
+	DbmsAuth = true
+	GetDbms = getDbms
+	exit.Add("close database", func() {
+		exit.Progress("database closing")
+		db.CloseKeepMapped()
+		exit.Progress("database closed")
+	})
+}
+
 func heapInUse() uint64 {
 	sample := make([]metrics.Sample, 1)
 	sample[0].Name = "/gc/heap/live:bytes"
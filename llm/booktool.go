@@ -94,7 +94,7 @@ func bookText(th *core.Thread, tran core.ITran, st *core.SuTran,
 	dir, name := splitPath(path)
 	query := fmt.Sprintf("%s where path = %q and name = %q",
 		book, dir, name)
-	q := tran.Query(query, nil)
+	q := tran.Query(query, nil, nil)
 	hdr := q.Header()
 	row, _ := q.Get(th, core.Next)
 	if row == nil {
@@ -121,7 +121,7 @@ func bookChildren(th *core.Thread, tran core.ITran, st *core.SuTran,
 	children = []string{}
 	query := fmt.Sprintf("%s where path = %q sort order, name",
 		book, path)
-	q := tran.Query(query, nil)
+	q := tran.Query(query, nil, nil)
 	hdr := q.Header()
 	for row, _ := q.Get(th, core.Next); row != nil; row, _ = q.Get(th, core.Next) {
 		val := row.GetVal(hdr, "name", th, st)
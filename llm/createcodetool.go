@@ -82,7 +82,7 @@ func createCodeTool(ctx context.Context, library, path, name, code string) (resu
 	// Check if definition already exists
 	query := fmt.Sprintf("%s where group = -1 and name = %q", library, name)
 	rtran := th.Dbms().Transaction(false)
-	rq := rtran.Query(query, nil)
+	rq := rtran.Query(query, nil, nil)
 	row, _ := rq.Get(th, core.Next)
 	rtran.Complete()
 	if row != nil {
@@ -103,7 +103,7 @@ func createCodeTool(ctx context.Context, library, path, name, code string) (resu
 	// Get max num to assign a unique num to the new record
 	utran := th.Dbms().Transaction(true)
 	maxNum := maxLibNum(th, utran, library)
-	iq := utran.Query(library, nil)
+	iq := utran.Query(library, nil, nil)
 	ihdr := iq.Header()
 	rec := buildRecord(ihdr, map[string]string{
 		"name":         core.PackValue(core.SuStr(name)),
@@ -153,7 +153,7 @@ func ensurePathParent(th *core.Thread, library, path string) (int, error) {
 			continue
 		}
 
-		iq := utran.Query(library, nil)
+		iq := utran.Query(library, nil, nil)
 		ihdr := iq.Header()
 		rec := buildRecord(ihdr, map[string]string{
 			"name":         core.PackValue(core.SuStr(segment)),
@@ -196,7 +196,7 @@ func lookupFolder(th *core.Thread, tran core.ITran, library string, parent int,
 
 // maxLibNum returns the maximum num value in the library, or 0 if none.
 func maxLibNum(th *core.Thread, tran core.ITran, library string) int {
-	q := tran.Query(library+" summarize max num", nil)
+	q := tran.Query(library+" summarize max num", nil, nil)
 	hdr := q.Header()
 	st := core.NewSuTran(tran, false)
 	row, _ := q.Get(th, core.Next)
@@ -54,7 +54,7 @@ func TestCreateCodeTool(t *testing.T) {
 	// Verify create via direct query: num, parent, text, lib_modified
 	th0 := core.NewThread(core.MainThread)
 	tran0 := dbmsLocal.Transaction(false)
-	q0 := tran0.Query("stdlib where group = -1 and name = 'Foo'", nil)
+	q0 := tran0.Query("stdlib where group = -1 and name = 'Foo'", nil, nil)
 	hdr0 := q0.Header()
 	row0, _ := q0.Get(th0, core.Next)
 	assert.That(row0 != nil)
@@ -90,7 +90,7 @@ func TestCreateCodeTool(t *testing.T) {
 	tran3 := dbmsLocal.Transaction(false)
 	st3 := core.NewSuTran(tran3, false)
 
-	qf1 := tran3.Query("stdlib where group = 0 and name = 'A'", nil)
+	qf1 := tran3.Query("stdlib where group = 0 and name = 'A'", nil, nil)
 	hf1 := qf1.Header()
 	rf1, _ := qf1.Get(th3, core.Next)
 	assert.That(rf1 != nil)
@@ -98,7 +98,7 @@ func TestCreateCodeTool(t *testing.T) {
 	assert.This(core.ToStr(rf1.GetVal(hf1, "text", th3, st3))).Is("")
 	assert.This(rf1.GetVal(hf1, "group", th3, st3)).Is(core.IntVal(0))
 
-	qf2 := tran3.Query("stdlib where group = "+core.IntVal(f1num).String()+" and name = 'B'", nil)
+	qf2 := tran3.Query("stdlib where group = "+core.IntVal(f1num).String()+" and name = 'B'", nil, nil)
 	hf2 := qf2.Header()
 	rf2, _ := qf2.Get(th3, core.Next)
 	assert.That(rf2 != nil)
@@ -106,7 +106,7 @@ func TestCreateCodeTool(t *testing.T) {
 	assert.This(core.ToStr(rf2.GetVal(hf2, "text", th3, st3))).Is("")
 	assert.This(rf2.GetVal(hf2, "group", th3, st3)).Is(core.IntVal(f1num))
 
-	qb := tran3.Query("stdlib where group = -1 and name = 'Bar'", nil)
+	qb := tran3.Query("stdlib where group = -1 and name = 'Bar'", nil, nil)
 	hb := qb.Header()
 	rb, _ := qb.Get(th3, core.Next)
 	assert.That(rb != nil)
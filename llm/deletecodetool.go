@@ -56,7 +56,7 @@ func deleteCodeTool(ctx context.Context, library, name string) (deleteCodeOutput
 
 	query := fmt.Sprintf("%s where group = -1 and name = %q", library, name)
 	rtran := th.Dbms().Transaction(false)
-	rq := rtran.Query(query, nil)
+	rq := rtran.Query(query, nil, nil)
 	hdr := rq.Header()
 	row, _ := rq.Get(th, core.Next)
 	if row == nil {
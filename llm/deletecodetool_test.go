@@ -64,7 +64,7 @@ func TestDeleteCodeTool(t *testing.T) {
 	th2 := core.NewThread(core.MainThread)
 	defer th2.Close()
 	tran2 := dbmsLocal.Transaction(false)
-	q := tran2.Query("stdlib where group = -1 and name = 'Foo'", nil)
+	q := tran2.Query("stdlib where group = -1 and name = 'Foo'", nil, nil)
 	row, _ := q.Get(th2, core.Next)
 	assert.That(row == nil)
 	tran2.Complete()
@@ -104,11 +104,11 @@ func TestDeleteCodeTool_SoftDeleteCommitted(t *testing.T) {
 	defer th2.Close()
 	tran2 := dbmsLocal.Transaction(false)
 
-	q1 := tran2.Query("stdlib where group = -1 and name = 'Foo'", nil)
+	q1 := tran2.Query("stdlib where group = -1 and name = 'Foo'", nil, nil)
 	row1, _ := q1.Get(th2, core.Next)
 	assert.That(row1 == nil)
 
-	q2 := tran2.Query("stdlib where group = -2 and name = 'Foo'", nil)
+	q2 := tran2.Query("stdlib where group = -2 and name = 'Foo'", nil, nil)
 	hdr2 := q2.Header()
 	row2, _ := q2.Get(th2, core.Next)
 	assert.That(row2 != nil)
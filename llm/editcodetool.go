@@ -107,7 +107,7 @@ func editCodeTool(ctx context.Context, library, name, mode string, line, count i
 
 	query := fmt.Sprintf("%s where group = -1 and name = %q", library, name)
 	rtran := th.Dbms().Transaction(false)
-	rq := rtran.Query(query, nil)
+	rq := rtran.Query(query, nil, nil)
 	hdr := rq.Header()
 	row, _ := rq.Get(th, core.Next)
 	if row == nil {
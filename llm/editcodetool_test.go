@@ -116,7 +116,7 @@ func TestEditCodeTool(t *testing.T) {
 
 	th2 := core.NewThread(core.MainThread)
 	tran2 := dbmsLocal.Transaction(false)
-	q2 := tran2.Query("stdlib where group = -1 and name = 'Foo'", nil)
+	q2 := tran2.Query("stdlib where group = -1 and name = 'Foo'", nil, nil)
 	hdr2 := q2.Header()
 	row2, _ := q2.Get(th2, core.Next)
 	assert.That(row2 != nil)
@@ -142,7 +142,7 @@ func TestEditCodeTool(t *testing.T) {
 
 	th3 := core.NewThread(core.MainThread)
 	tran3 := dbmsLocal.Transaction(false)
-	q3 := tran3.Query("stdlib where group = -1 and name = 'Foo'", nil)
+	q3 := tran3.Query("stdlib where group = -1 and name = 'Foo'", nil, nil)
 	hdr3 := q3.Header()
 	row3, _ := q3.Get(th3, core.Next)
 	assert.That(row3 != nil)
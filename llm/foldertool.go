@@ -128,7 +128,7 @@ func codeFolderChildren(th *core.Thread, tran core.ITran, st *core.SuTran, libra
 	}()
 	children = []string{}
 	query := fmt.Sprintf("%s where parent = %d and group >= -1 sort name", library, parent)
-	q := tran.Query(query, nil)
+	q := tran.Query(query, nil, nil)
 	hdr := q.Header()
 	for row, _ := q.Get(th, core.Next); row != nil; row, _ = q.Get(th, core.Next) {
 		if len(children) >= codeFolderLimit {
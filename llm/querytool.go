@@ -53,7 +53,7 @@ func queryTool(query string) (result queryOutput, err error) {
 	defer th.Close()
 	tran := th.Dbms().Transaction(false)
 	defer tran.Complete()
-	q := tran.Query(query, nil)
+	q := tran.Query(query, nil, nil)
 	hdr := q.Header()
 	cols := hdr.Columns
 	var rows [][]core.Value
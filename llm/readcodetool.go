@@ -94,7 +94,7 @@ func readCodeTool(library, name string, startLine int, numLines int, plain bool)
 	query := fmt.Sprintf("%s where group = -1 and name = %q", library, name)
 	tran := th.Dbms().Transaction(false)
 	defer tran.Complete()
-	q := tran.Query(query, nil)
+	q := tran.Query(query, nil, nil)
 	hdr := q.Header()
 	row, _ := q.Get(th, core.Next)
 	if row == nil {
@@ -31,7 +31,7 @@ func TestCodeTool(t *testing.T) {
 
 	// Verify insert
 	rt := dbmsLocal.Transaction(false)
-	q := rt.Query("stdlib", nil)
+	q := rt.Query("stdlib", nil, nil)
 	row, _ := q.Get(th, core.Next)
 	assert.That(row != nil)
 	rt.Complete()
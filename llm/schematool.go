@@ -38,7 +38,7 @@ func getSchema(table string) (any, error) {
 
 	tran := dbms.Transaction(false)
 	defer tran.Complete()
-	q := tran.Query(fmt.Sprintf("views where view_name = %q", table), nil)
+	q := tran.Query(fmt.Sprintf("views where view_name = %q", table), nil, nil)
 	defer q.Close()
 	row, _ := q.Get(th, core.Next)
 	if row == nil {
@@ -90,7 +90,7 @@ func searchBook(book, pathRx, textRx string, caseSensitive bool) (searchBookOutp
 	}
 
 	query := buildBookQuery(book, pathRx, textRx)
-	q := tran.Query(query, nil)
+	q := tran.Query(query, nil, nil)
 	hdr := q.Header()
 
 	var matches []bookMatch
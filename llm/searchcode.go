@@ -103,7 +103,7 @@ func searchCode(library, nameRx, codeRx string, caseSensitive, modified bool) (r
 	var matches []codeMatch
 	for _, lib := range libs {
 		clear(folderCache)
-		q := tran.Query(lib+" "+nameQuery, nil)
+		q := tran.Query(lib+" "+nameQuery, nil, nil)
 		hdr := q.Header()
 		for row, _ := q.Get(th, core.Next); row != nil; row, _ = q.Get(th, core.Next) {
 			if len(matches) >= searchLimit {
@@ -46,7 +46,7 @@ func tablesTool(prefix string) (output tablesOutput, err error) {
 	tran := th.Dbms().Transaction(false)
 	defer tran.Complete()
 
-	q := tran.Query(tablesQuery(prefix), nil)
+	q := tran.Query(tablesQuery(prefix), nil, nil)
 	hdr := q.Header()
 	for row, _ := q.Get(th, core.Next); row != nil; row, _ = q.Get(th, core.Next) {
 		if len(output.Tables) >= queryLimit {
@@ -29,6 +29,16 @@ var (
 	WebPort        string
 	TimeoutMinutes = 2 * 60 // 2 hours
 	Passphrase     string   // used with -load
+	Archive        string   // directory for continuous backup
+	Asof           string   // used with -restore
+	Replicate      string   // primary address for a replica server
+	TempIndexMB    = 256    // memory budget for a temp index before spilling
+	SlowQueryMs    = 0      // log queries slower than this, 0 to disable
+	TLSCert        string   // server (or client) certificate file (PEM)
+	TLSKey         string   // private key file (PEM) for TLSCert
+	TLSCA          string   // CA bundle file (PEM) to verify the other side
+	TLSClientAuth  bool     // server requires and verifies client certificates
+	TLSServerName  string   // name the client expects in the server certificate
 )
 
 // StrictCompare determines whether comparisons between different types
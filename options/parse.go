@@ -38,6 +38,30 @@ loop:
 		case match(&args, "-load"), match(&args, "-l"):
 			setAction("load")
 			args = optionalArg(args, &Arg)
+		case match(&args, "-restore"):
+			setAction("restore")
+			args = optEqualArg(args, &Arg)
+			if Arg == "" {
+				error("archive directory required")
+			}
+		case match(&args, "-asof"):
+			if Action != "restore" {
+				error("asof only valid with -restore")
+			}
+			args = optEqualArg(args, &Asof)
+			if Asof == "" {
+				error("asof time required")
+			}
+		case match(&args, "-archive"):
+			args = optEqualArg(args, &Archive)
+			if Archive == "" {
+				error("archive directory required")
+			}
+		case match(&args, "-replicate"):
+			args = optEqualArg(args, &Replicate)
+			if Replicate == "" {
+				error("primary address required")
+			}
 		case match(&args, "-passphrase"), match(&args, "-pp"):
 			if Action != "load" {
 				error("passphrase only valid with -load")
@@ -73,6 +97,48 @@ loop:
 			} else {
 				error("invalid timeout value")
 			}
+		case match(&args, "-tempindexmb"):
+			mb := ""
+			args = optEqualArg(args, &mb)
+			if mb == "" {
+				error("tempindexmb value required")
+			} else if n, ok := atoui(mb); ok && n > 0 {
+				TempIndexMB = n
+			} else {
+				error("invalid tempindexmb value")
+			}
+		case match(&args, "-slowquery"):
+			ms := ""
+			args = optEqualArg(args, &ms)
+			if ms == "" {
+				error("slowquery value required")
+			} else if n, ok := atoui(ms); ok {
+				SlowQueryMs = n
+			} else {
+				error("invalid slowquery value")
+			}
+		case match(&args, "-tlscert"):
+			args = optEqualArg(args, &TLSCert)
+			if TLSCert == "" {
+				error("tlscert file required")
+			}
+		case match(&args, "-tlskey"):
+			args = optEqualArg(args, &TLSKey)
+			if TLSKey == "" {
+				error("tlskey file required")
+			}
+		case match(&args, "-tlsca"):
+			args = optEqualArg(args, &TLSCA)
+			if TLSCA == "" {
+				error("tlsca file required")
+			}
+		case match(&args, "-tlsclientauth"):
+			TLSClientAuth = true
+		case match(&args, "-tlsservername"):
+			args = optEqualArg(args, &TLSServerName)
+			if TLSServerName == "" {
+				error("tlsservername required")
+			}
 		case match(&args, "-web"), match(&args, "-w"):
 			WebServer = true
 			args = optEqualArg(args, &WebPort)
@@ -101,6 +167,18 @@ loop:
 	if Port == "" && (Action == "client" || Action == "server") {
 		Port = "3147"
 	}
+	if Replicate != "" && Action != "server" {
+		error("replicate is only valid with -server")
+	}
+	if (TLSCert == "") != (TLSKey == "") {
+		error("tlscert and tlskey must be specified together")
+	}
+	if TLSClientAuth && (Action != "server" || TLSCA == "") {
+		error("tlsclientauth requires -server and -tlsca")
+	}
+	if TLSServerName != "" && Action != "client" {
+		error("tlsservername is only valid with -client")
+	}
 	if WebServer && Action == "server" {
 		log.Println("ERROR: should not specify web port for server")
 	}
@@ -17,7 +17,12 @@ func TestParse(t *testing.T) {
 		args := strings.Fields(argstr)
 		Action, Arg, Port, CmdLine, Error = "", "", "", "", ""
 		TimeoutMinutes = 0
+		TempIndexMB = 0
+		SlowQueryMs = 0
 		WebServer, WebPort = false, ""
+		Archive, Asof, Replicate = "", "", ""
+		TLSCert, TLSKey, TLSCA, TLSServerName = "", "", "", ""
+		TLSClientAuth = false
 		Parse(args)
 		s := Action
 		if Arg != "" {
@@ -29,6 +34,24 @@ func TestParse(t *testing.T) {
 		if TimeoutMinutes != 0 {
 			s += " timeout=" + strconv.Itoa(TimeoutMinutes)
 		}
+		if TempIndexMB != 0 {
+			s += " tempindexmb=" + strconv.Itoa(TempIndexMB)
+		}
+		if SlowQueryMs != 0 {
+			s += " slowquery=" + strconv.Itoa(SlowQueryMs)
+		}
+		if TLSCert != "" {
+			s += " cert=" + TLSCert + " key=" + TLSKey
+		}
+		if TLSCA != "" {
+			s += " ca=" + TLSCA
+		}
+		if TLSClientAuth {
+			s += " clientauth"
+		}
+		if TLSServerName != "" {
+			s += " servername=" + TLSServerName
+		}
 		if WebServer {
 			s += " web"
 			if WebPort != "" {
@@ -93,9 +116,36 @@ func TestParse(t *testing.T) {
 	test("-server", "server")
 	test("-repair", "repair")
 
+	test("-restore=archive", "restore archive")
+	test("-restore", "error archive directory required")
+	test("-restore=archive -asof=20260102.030405", "restore archive")
+	test("-asof=20260102.030405", "error asof only valid with -restore")
+	test("-s -archive", "error archive directory required")
+	test("-s -archive=archive", "server")
+	test("-s -replicate=1.2.3.4", "server")
+	test("-s -replicate", "error primary address required")
+	test("-replicate=1.2.3.4", "error replicate is only valid with -server")
+
 	test("-to=44", "timeout=44")
 	test("-to", "error timeout value required")
 	test("-to=1.2", "error invalid timeout value")
+	test("-tempindexmb=64", "tempindexmb=64")
+	test("-tempindexmb", "error tempindexmb value required")
+	test("-tempindexmb=0", "error invalid tempindexmb value")
+	test("-s -slowquery=500", "server slowquery=500")
+	test("-slowquery", "error slowquery value required")
+	test("-slowquery=x", "error invalid slowquery value")
+
+	test("-s -tlscert=s.crt -tlskey=s.key", "server cert=s.crt key=s.key")
+	test("-s -tlscert=s.crt", "error tlscert and tlskey must be specified together")
+	test("-s -tlskey", "error tlskey file required")
+	test("-s -tlsca=ca.crt -tlsclientauth", "server ca=ca.crt clientauth")
+	test("-s -tlsclientauth", "error tlsclientauth requires -server and -tlsca")
+	test("-c -tlsca=ca.crt -tlsclientauth",
+		"error tlsclientauth requires -server and -tlsca")
+	test("-c -tlsca=ca.crt -tlsservername=db.example.com",
+		"client 127.0.0.1 ca=ca.crt servername=db.example.com")
+	test("-s -tlsservername=foo", "error tlsservername is only valid with -client")
 
 	test("-v", "version")
 	test("-version", "version")
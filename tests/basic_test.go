@@ -501,3 +501,14 @@ var _ = Register("Display", `
 "Display(Adler32())", "'adler32'"
 "Display(Md5())", "'md5'"
 `)
+
+var _ = Register("date zones", `
+"#20240115.1200.ToZone('America/Toronto', from: 'UTC')", "#20240115.0700"
+"#20240715.0800.ToZone('UTC', from: 'America/Toronto')", "#20240715.1200"
+"#20240115.1234.FormatEn('HH:mm XX', zone: 'Asia/Kolkata')", "'12:34 +05:30'"
+"#20240115.1234.FormatEn('yyyy-MM-ddTHH:mmZ')", "'2024-01-15T12:34Z'"
+"Date('2024-01-15T12:34:56Z', zone: 'America/Toronto')", "#20240115.073456"
+"#20240310.0130.Plus(hours: 1, zone: 'America/Toronto')", "#20240310.0330"
+"#20240310.0130.Plus(hours: 1)", "#20240310.0230"
+"Type(Date(zone: 'UTC'))", "'Date'"
+`)
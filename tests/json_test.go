@@ -0,0 +1,15 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package tests
+
+var _ = Register("JsonEncode", `
+'JsonEncode(#(1, (2, 3)))', '"[1,[2,3]]"'
+'JsonDecode(JsonEncode(#(1, (2, 3), a: true)))', '#("0": 1, "1": (2, 3), a: true)'
+'JsonDecode("[1, [2, 3], false]")', '#(1, (2, 3), false)'
+'JsonDecode(JsonEncode(.1 + .2))', .3
+'JsonDecode(JsonEncode(#20240115.1234), dates:)', '#20240115.1234'
+'JsonEncode(JsonEncode)' throws "can't convert"
+'JsonEncode(JsonEncode, other: "null")', '"null"'
+'JsonDecode("[1,]")' throws "Invalid Json format"
+`)
@@ -88,6 +88,7 @@ var _ = Register("strings string.Match", `
 '"big bigger biggest".Match("big", 10, prev:)'	"#(#(4,3))"
 
 '"now is the time".Match("(\w+) (\w+) (\w+)")'	"#((0,10),(0,3),(4,2),(7,3))"
+'"now is the time".Match("(?<first>\w+) (\w+)")'	"#((0,6),(0,3),(4,2),first:(0,3))"
 `)
 
 var _ = Register("strings string.Extract", `
@@ -97,6 +98,9 @@ var _ = Register("strings string.Extract", `
 '"hello world".Extract("(\w+) (\w+)", 0)', '"hello world"'
 '"hello world".Extract("(\w+) (\w+)", 1)', '"hello"'
 '"hello world".Extract("(\w+) (\w+)", 2)', '"world"'
+'"hello world".Extract("(\w+) (?<second>\w+)", "second")', '"world"'
+'"hello world".Extract("(?<a>x)?world", "a")', '""'
+'"hello world".Extract("w\w{4}")', '"world"'
 `)
 
 var _ = Register("strings string.Eval", `
@@ -122,3 +126,25 @@ var _ = Register("strings calling a string", `
 '#Upper(@#(abc))', '"ABC"'
 '#Upper(@+1#(abc,def))', '"DEF"'
 `)
+
+var _ = Register("strings utf8", `
+"'Jos\xc3\xa9'.Size()", 5
+"'Jos\xc3\xa9'.Utf8Size()", 4
+"'Jose\xcc\x81'.Utf8Size()", 4
+"'stra\xc3\x9fe'.Utf8Upper()", "'STRASSE'"
+"'\xc3\x89COLE'.Utf8Lower()", "'\xc3\xa9cole'"
+"'hello'.Utf8Upper()", "'HELLO'"
+"'Jose\xcc\x81!'.Utf8Substr(3, 1)", "'e\xcc\x81'"
+"'Jose\xcc\x81!'.Utf8Substr(-2)", "'e\xcc\x81!'"
+"'hello'.Utf8Substr(1, 3)", "'ell'"
+"'ae\xcc\x81'.Utf8Reverse()", "'e\xcc\x81a'"
+"'e\xcc\x81'.Utf8Normalize()", "'\xc3\xa9'"
+"'\xc3\xa9'.Utf8Normalize('NFD')", "'e\xcc\x81'"
+"'\xc3\xa9'.Utf8Normalize('X')" throws "invalid normalization form"
+"'\xc3\xa9'.Utf8Compare('f')", -1
+"'\xc3\xa9' < 'f'", false
+"'\xc3\xa5'.Utf8Compare('z', 'sv')", 1
+"'\xc3\xa9'.Utf8SortKey() < 'f'.Utf8SortKey()", true
+"'\xc3\xa9'.Utf8Valid?()", true
+"'\xe9'.Utf8Valid?()", false
+`)
@@ -4,6 +4,7 @@
 package regex
 
 import (
+	"math"
 	"strings"
 
 	"slices"
@@ -39,10 +40,23 @@ element		:	^					opLineStart
 			|	simple +?			simple SplitNext
 			|	simple *			SplitNext simple SplitJump
 			|	simple *?			SplitJump simple Jump
+			|	simple {n}			simple ... (n times)
+			|	simple {n,}			simple ... (n times) simple*
+			|	simple {n,m}		simple ... (n times) simple? ... (m-n times)
+			|	simple {...}?		non-greedy versions of the above
 
 simple		:	.					opAny
 			|	char	 			Char c
 			|	( regex )			Save # ... Save #+1
+			|	(?<name> regex )	Save # ... Save #+1 (see names.go)
+			|	(?P<name> regex )	Save # ... Save #+1 (see names.go)
+			|	(?: regex )			regex (non-capturing)
+			|	(?= regex )			Lookahead len Pattern
+			|	(?! regex )			NegLookahead len Pattern
+			|	(?<= regex )		Lookbehind len Pattern
+			|	(?<! regex )		NegLookbehind len Pattern
+			|	\1 to \9			Backref #
+			|	\k<name>			Backref #
 			|	[ charmatch+ ]		character class
 			|	[^ charmatch+ ]		character class
 			|	shortcut			character class
@@ -80,19 +94,40 @@ If the pattern can be executed by onePass, it is compiled to:
 
 If the pattern has a literal prefix it will be compiled to:
 	opPrefix len characters ...
+
+If the pattern has backreferences it starts with opCaptures
+so match will track the captures even if the caller doesn't want them.
+Patterns with backreferences or lookarounds are not one pass.
+
+The pattern for a lookaround is compiled separately (with compile2)
+and embedded. A lookbehind pattern has an opStrEnd appended
+and is matched against the string up to the current position.
+Groups inside lookarounds are numbered but do not capture.
+
+{n,m} is compiled by repeating the compiled code for the simple element.
 */
 
 type compiler struct {
 	src         string
 	prog        []byte
+	names       []groupName
 	si          int
 	sn          int
 	leftCount   int
 	ignoreCase  bool
 	multiLine   bool
 	rightAnchor bool
+	// backrefs is set if the pattern has backreferences
+	backrefs bool
+	// notOnePass is set if the pattern has backreferences or lookarounds
+	notOnePass bool
+	// lookaround is set when compiling a lookaround pattern
+	lookaround bool
 }
 
+// maxRepeat is the limit on the counts for {n,m}
+const maxRepeat = 1000
+
 // Compile converts a regular expression string to a Pattern
 func Compile(rx string) Pattern {
 	co := compile(rx)
@@ -133,7 +168,7 @@ func (co *compiler) compile2() Pattern {
 		// replace prog with literal
 		co.prog = slices.Insert(literal, 0, byte(op))
 	} else {
-		if co.onePass() {
+		if !co.notOnePass && co.onePass() {
 			co.prog = slices.Insert(co.prog, 0, byte(opOnePass))
 		}
 		if len(literal) > 0 && !leftAnchor {
@@ -144,6 +179,13 @@ func (co *compiler) compile2() Pattern {
 				byte(len(literal)))
 			co.prog = slices.Insert(co.prog, 2, literal...)
 		}
+		if co.backrefs {
+			co.prog = slices.Insert(co.prog, 0, byte(opCaptures))
+		}
+		co.prog = co.appendNames(co.prog)
+	}
+	if len(co.prog) > math.MaxInt16 {
+		panic("regex: too large")
 	}
 	return Pattern(hacks.BStoS(co.prog))
 }
@@ -254,7 +296,80 @@ func (co *compiler) element() {
 			co.emitOff(opSplitJump, -pn)
 			co.insert(start, opSplitNext, pn+6)
 			co.rightAnchor = false
+		} else if lo, hi, ok := co.repeat(); ok {
+			co.repetition(start, lo, hi, co.match("?"))
+			co.rightAnchor = false
+		}
+	}
+}
+
+// repeat matches {n}, {n,}, or {n,m}, returning hi -1 for {n,}
+// If it does not match, the { is treated as a literal
+func (co *compiler) repeat() (lo, hi int, ok bool) {
+	i := co.si
+	number := func() int {
+		n := -1
+		for ; i < co.sn && ascii.IsDigit(co.src[i]); i++ {
+			n = max(n, 0)*10 + int(co.src[i]-'0')
+			if n > maxRepeat {
+				panic("regex: repeat count too large")
+			}
+		}
+		return n
+	}
+	if i >= co.sn || co.src[i] != '{' {
+		return
+	}
+	i++
+	if lo = number(); lo == -1 {
+		return
+	}
+	hi = lo
+	if i < co.sn && co.src[i] == ',' {
+		i++
+		hi = number()
+	}
+	if i >= co.sn || co.src[i] != '}' {
+		return
+	}
+	if hi != -1 && hi < lo {
+		panic("regex: invalid repeat count")
+	}
+	co.si = i + 1
+	return lo, hi, true
+}
+
+// repetition replaces the code from start with lo required copies
+// followed by hi-lo optional copies, or x* if hi is -1
+func (co *compiler) repetition(start, lo, hi int, lazy bool) {
+	frag := slices.Clone(co.prog[start:])
+	pn := len(frag)
+	if pn*(max(lo, hi)+1) > math.MaxInt16 {
+		panic("regex: too large")
+	}
+	co.prog = co.prog[:start]
+	for range lo {
+		co.prog = append(co.prog, frag...)
+	}
+	if hi == -1 {
+		start = len(co.prog)
+		co.prog = append(co.prog, frag...)
+		if lazy {
+			co.emitOff(opJump, -pn-3)
+			co.insert(start, opSplitJump, pn+6)
+		} else {
+			co.emitOff(opSplitJump, -pn)
+			co.insert(start, opSplitNext, pn+6)
 		}
+		return
+	}
+	op := opSplitNext
+	if lazy {
+		op = opSplitJump
+	}
+	for range hi - lo {
+		co.emitOff(op, pn+3)
+		co.prog = append(co.prog, frag...)
 	}
 }
 
@@ -293,8 +408,16 @@ func (co *compiler) simple() {
 			co.emitCC(space)
 		case 'S':
 			co.emitCC(notSpace)
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			panic("regex: backreferences not supported")
+		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			co.backref(int(c - '0'))
+		case '0':
+			panic("regex: invalid backreference")
+		case 'k':
+			if co.match("<") {
+				co.backref(co.nameGroup(co.groupName()))
+			} else {
+				co.emitChar(c)
+			}
 		default:
 			co.emitChar(c)
 		}
@@ -304,13 +427,33 @@ func (co *compiler) simple() {
 		if co.match(")") {
 			panic("regex: empty parenthesis not allowed")
 		}
+		if co.match("?:") {
+			co.regex() // RECURSE
+			co.mustMatch(")")
+			return
+		} else if co.match("?=") {
+			co.lookaroundGroup(opLookahead)
+			return
+		} else if co.match("?!") {
+			co.lookaroundGroup(opNegLookahead)
+			return
+		} else if co.match("?<=") {
+			co.lookaroundGroup(opLookbehind)
+			return
+		} else if co.match("?<!") {
+			co.lookaroundGroup(opNegLookbehind)
+			return
+		}
 		co.leftCount++
 		leftCount := co.leftCount
-		if leftCount < 10 {
+		if co.match("?<") || co.match("?P<") {
+			co.addName(co.groupName(), leftCount)
+		}
+		if leftCount < 10 && !co.lookaround {
 			co.emit(opSave, 2*byte(leftCount))
 		}
 		co.regex() // RECURSE
-		if leftCount < 10 {
+		if leftCount < 10 && !co.lookaround {
 			co.emit(opSave, 2*byte(leftCount)+1)
 		}
 		co.mustMatch(")")
@@ -319,6 +462,44 @@ func (co *compiler) simple() {
 	}
 }
 
+// backref emits a reference to the text matched by a previous group
+func (co *compiler) backref(group int) {
+	if co.lookaround {
+		panic("regex: backreference not supported in lookaround")
+	}
+	if group > co.leftCount {
+		panic("regex: invalid backreference")
+	}
+	co.backrefs = true
+	co.notOnePass = true
+	if co.ignoreCase {
+		co.emit(opBackrefIgnoreCase, byte(group))
+	} else {
+		co.emit(opBackref, byte(group))
+	}
+}
+
+// lookaroundGroup compiles the pattern for a lookaround
+// and embeds it in the program
+func (co *compiler) lookaroundGroup(op opType) {
+	sub := compiler{src: co.src, si: co.si, sn: co.sn,
+		prog:      make([]byte, 0, 16),
+		leftCount: co.leftCount, ignoreCase: co.ignoreCase,
+		multiLine: co.multiLine, lookaround: true}
+	sub.regex() // RECURSE
+	co.si, co.leftCount = sub.si, sub.leftCount
+	co.mustMatch(")")
+	if op == opLookbehind || op == opNegLookbehind {
+		sub.emit(opStrEnd)
+		sub.rightAnchor = true // only used if literal
+	}
+	sub.emit(opDoneSave1)
+	pat := sub.compile2()
+	co.notOnePass = true
+	co.emitOff(op, len(pat))
+	co.prog = append(co.prog, pat...)
+}
+
 func (co *compiler) charClass() {
 	negate := co.match("^")
 	var cc = cclass{}
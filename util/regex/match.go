@@ -41,6 +41,9 @@ func (pat Pattern) LastMatch(s string, i int, cap *Captures) bool {
 
 func (pat Pattern) leftAnchored() bool {
 	piStart := int16(0)
+	if opType(pat[piStart]) == opCaptures {
+		piStart++
+	}
 	switch op := opType(pat[piStart]); op {
 	case opPrefix:
 		n := int16(pat[piStart+1])
@@ -65,6 +68,8 @@ func (pat Pattern) All(s string) iter.Seq[*Captures] {
 type state struct {
 	cap Captures
 	pi  int16
+	// n is the number of characters matched so far by a backreference
+	n int32
 }
 
 // FirstMatch finds the first match at or after position i
@@ -85,6 +90,15 @@ func (pat Pattern) match(s string, start int, cap *Captures, fixed bool) bool {
 		*cap = newCapture
 	}
 	piStart := int16(0)
+	wantCap := cap != nil
+	if opType(pat[piStart]) == opCaptures {
+		// backreferences need the captures
+		if cap == nil {
+			cap = &Captures{}
+			*cap = newCapture
+		}
+		piStart++
+	}
 	prefix := ""
 	switch op := opType(pat[piStart]); op {
 	case opPrefix:
@@ -106,7 +120,10 @@ func (pat Pattern) match(s string, start int, cap *Captures, fixed bool) bool {
 	cap2 := dup(cap)
 	var cur = make([]state, 0, 4)
 	var next = make([]state, 0, 4)
-	var live = &BitSet{}
+	var live = &liveSet{}
+	if opType(pat[0]) == opCaptures {
+		live.caps = make(map[liveKey]struct{})
+	}
 	matched := false
 	for si := start; si <= len(s); si++ {
 		if si < len(s) {
@@ -173,8 +190,22 @@ func (pat Pattern) match(s string, start int, cap *Captures, fixed bool) bool {
 				if si < len(s) && matchFullSet(pat[pi+1:], s[si]) {
 					add = pi + 1 + 32
 				}
+			case opBackref, opBackrefIgnoreCase:
+				c := &cur[ci]
+				ref := 2 * int(pat[pi+1])
+				j := int(c.cap[ref] + c.n)
+				if si < len(s) && (s[si] == s[j] ||
+					(opType(pat[pi]) == opBackrefIgnoreCase &&
+						ascii.ToLower(s[si]) == ascii.ToLower(s[j]))) {
+					if j+1 >= int(c.cap[ref+1]) {
+						add = pi + 2
+					} else {
+						// continue matching the backreference
+						next = append(next, state{cap: c.cap, pi: pi, n: c.n + 1})
+					}
+				}
 			case opDoneSave1:
-				if cap == nil {
+				if !wantCap {
 					// if not capturing, any match will do
 					return true
 				}
@@ -201,10 +232,10 @@ func (pat Pattern) match(s string, start int, cap *Captures, fixed bool) bool {
 // addstate adds a state and, recursively, all of its children.
 // It processes all zero width instructions
 // so the states added will point to character matching instructions.
-func (pat Pattern) addstate(s string, si int, live *BitSet, states []state,
+func (pat Pattern) addstate(s string, si int, live *liveSet, states []state,
 	pi int16, cap *Captures) []state {
 	for {
-		if !live.AddNew(pi) {
+		if !live.addNew(pi, cap) {
 			return states
 		}
 		_ = t && trace.Println("addstate loop", pat.opstr1(pi))
@@ -236,6 +267,22 @@ func (pat Pattern) addstate(s string, si int, live *BitSet, states []state,
 			}
 			_ = t && trace.Println("YES")
 			pi++
+		case opBackref, opBackrefIgnoreCase:
+			ref := 2 * int(pat[pi+1])
+			if cap[ref] < 0 || cap[ref+1] < cap[ref] {
+				return states // group did not match
+			}
+			if cap[ref] < cap[ref+1] {
+				states = append(states, state{pi: pi, cap: *cap})
+				return states
+			}
+			pi += 2 // empty
+		case opLookahead, opNegLookahead, opLookbehind, opNegLookbehind:
+			n := int16(pat[pi+1])<<8 | int16(pat[pi+2])
+			if !lookaround(s, si, opType(pat[pi]), pat[pi+3:pi+3+n]) {
+				return states
+			}
+			pi += 3 + n
 		default:
 			st := state{pi: pi}
 			if cap != nil {
@@ -247,6 +294,38 @@ func (pat Pattern) addstate(s string, si int, live *BitSet, states []state,
 	}
 }
 
+// liveSet is the set of instructions that have been added at a position.
+// If the pattern has backreferences, the same instruction with different
+// captures can have different results, so they are included in caps.
+// Otherwise lower priority threads would be dropped.
+// The whole match (\0) is not included since it can't be referenced.
+type liveSet struct {
+	BitSet
+	caps map[liveKey]struct{}
+}
+
+type liveKey struct {
+	pi  int16
+	cap [len(Captures{}) - 2]int32
+}
+
+func (live *liveSet) addNew(pi int16, cap *Captures) bool {
+	if live.caps == nil {
+		return live.AddNew(pi)
+	}
+	key := liveKey{pi: pi, cap: [len(Captures{}) - 2]int32(cap[2:])}
+	if _, ok := live.caps[key]; ok {
+		return false
+	}
+	live.caps[key] = struct{}{}
+	return true
+}
+
+func (live *liveSet) Clear() {
+	live.BitSet.Clear()
+	clear(live.caps)
+}
+
 func boundary(s string, si int, op byte) bool {
 	switch opType(op) {
 	case opStrStart:
@@ -268,6 +347,20 @@ func boundary(s string, si int, op byte) bool {
 
 var wordSet = Pattern(word[:])
 
+// lookaround matches an embedded lookaround pattern at si.
+// Lookbehind patterns end with opStrEnd so they only match
+// if they end at si.
+func lookaround(s string, si int, op opType, sub Pattern) bool {
+	var ok bool
+	switch op {
+	case opLookahead, opNegLookahead:
+		ok = sub.match(s, si, nil, true)
+	case opLookbehind, opNegLookbehind:
+		ok = sub.match(s[:si], 0, nil, false)
+	}
+	return ok != (op == opNegLookahead || op == opNegLookbehind)
+}
+
 func dup(cap *Captures) *Captures {
 	if cap == nil {
 		return nil
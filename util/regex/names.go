@@ -0,0 +1,117 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package regex
+
+import (
+	"github.com/apmckinlay/gsuneido/util/ascii"
+)
+
+// Named groups are (?<name>...) or (?P<name>...)
+// They are numbered along with the other groups
+// so they are limited to the first nine groups.
+// The names are appended to the program after the final opDoneSave1:
+//	group len name ... (for each name) uint16 opNames
+
+type groupName struct {
+	name  string
+	group int
+}
+
+// groupName parses the name (after the <) up to and including the >
+func (co *compiler) groupName() string {
+	start := co.si
+	for co.si < co.sn && (co.src[co.si] == '_' || ascii.IsLetter(co.src[co.si]) ||
+		(co.si > start && ascii.IsDigit(co.src[co.si]))) {
+		co.si++
+	}
+	name := co.src[start:co.si]
+	if name == "" || !co.match(">") {
+		panic("regex: invalid group name")
+	}
+	return name
+}
+
+func (co *compiler) addName(name string, group int) {
+	if group >= 10 {
+		panic("regex: too many groups for named group: " + name)
+	}
+	for _, gn := range co.names {
+		if gn.name == name {
+			panic("regex: duplicate group name: " + name)
+		}
+	}
+	if !co.lookaround {
+		co.names = append(co.names, groupName{name: name, group: group})
+	}
+}
+
+// nameGroup returns the group number for a name, used by \k<name>
+func (co *compiler) nameGroup(name string) int {
+	for _, gn := range co.names {
+		if gn.name == name {
+			return gn.group
+		}
+	}
+	panic("regex: invalid group name: " + name)
+}
+
+func (co *compiler) appendNames(prog []byte) []byte {
+	if len(co.names) == 0 {
+		return prog
+	}
+	start := len(prog)
+	for _, gn := range co.names {
+		prog = append(prog, byte(gn.group), byte(len(gn.name)))
+		prog = append(prog, gn.name...)
+	}
+	n := len(prog) - start
+	return append(prog, byte(n>>8), byte(n), byte(opNames))
+}
+
+// names returns the encoded names, or "" if there are none
+func (pat Pattern) names() string {
+	n := len(pat)
+	switch opType(pat[0]) {
+	case opLiteralSubstr, opLiteralPrefix, opLiteralSuffix, opLiteralEqual:
+		return ""
+	}
+	if opType(pat[n-1]) != opNames {
+		return ""
+	}
+	nn := int(pat[n-3])<<8 | int(pat[n-2])
+	return string(pat[n-3-nn : n-3])
+}
+
+// Names returns the group names indexed by group number,
+// with "" for groups without names.
+// It returns nil if there are no named groups.
+func (pat Pattern) Names() []string {
+	names := pat.names()
+	if names == "" {
+		return nil
+	}
+	var list []string
+	for i := 0; i < len(names); {
+		group, n := int(names[i]), int(names[i+1])
+		for len(list) <= group {
+			list = append(list, "")
+		}
+		list[group] = names[i+2 : i+2+n]
+		i += 2 + n
+	}
+	return list
+}
+
+// Group returns the group number for a name, or -1 if not found
+func (pat Pattern) Group(name string) int {
+	names := pat.names()
+	for i := 0; i < len(names); {
+		group, n := int(names[i]), int(names[i+1])
+		if names[i+2:i+2+n] == name {
+			return group
+		}
+		i += 2 + n
+	}
+	return -1
+}
@@ -33,11 +33,19 @@ func _() {
 	_ = x[opLiteralSuffix-23]
 	_ = x[opLiteralEqual-24]
 	_ = x[opPrefix-25]
+	_ = x[opBackref-26]
+	_ = x[opBackrefIgnoreCase-27]
+	_ = x[opLookahead-28]
+	_ = x[opNegLookahead-29]
+	_ = x[opLookbehind-30]
+	_ = x[opNegLookbehind-31]
+	_ = x[opCaptures-32]
+	_ = x[opNames-33]
 }
 
-const _opType_name = "opCharopCharIgnoreCaseopJumpopSplitNextopSplitJumpopAnyNotNLopHalfSetopFullSetopListSetopWordStartopWordEndopLineStartopLineEndopStrStartopStrEndopSaveopDoneSave1opOnePassopBranchNextopBranchJumpopLiteralSubstropLiteralPrefixopLiteralSuffixopLiteralEqualopPrefix"
+const _opType_name = "opCharopCharIgnoreCaseopJumpopSplitNextopSplitJumpopAnyNotNLopHalfSetopFullSetopListSetopWordStartopWordEndopLineStartopLineEndopStrStartopStrEndopSaveopDoneSave1opOnePassopBranchNextopBranchJumpopLiteralSubstropLiteralPrefixopLiteralSuffixopLiteralEqualopPrefixopBackrefopBackrefIgnoreCaseopLookaheadopNegLookaheadopLookbehindopNegLookbehindopCapturesopNames"
 
-var _opType_index = [...]uint16{0, 6, 22, 28, 39, 50, 60, 69, 78, 87, 98, 107, 118, 127, 137, 145, 151, 162, 171, 183, 195, 210, 225, 240, 254, 262}
+var _opType_index = [...]uint16{0, 6, 22, 28, 39, 50, 60, 69, 78, 87, 98, 107, 118, 127, 137, 145, 151, 162, 171, 183, 195, 210, 225, 240, 254, 262, 271, 290, 301, 315, 327, 342, 352, 359}
 
 func (i opType) String() string {
 	i -= 1
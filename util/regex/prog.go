@@ -16,32 +16,40 @@ type Pattern string
 type opType byte
 
 const (
-	_                opType = iota
-	opChar                  // char
-	opCharIgnoreCase        // char
-	opJump                  // int16
-	opSplitNext             // int16
-	opSplitJump             // int16
-	opAnyNotNL              //
-	opHalfSet               // [16]byte
-	opFullSet               // [32]byte
-	opListSet               // uint8 []byte
-	opWordStart             //
-	opWordEnd               //
-	opLineStart             //
-	opLineEnd               //
-	opStrStart              //
-	opStrEnd                //
-	opSave                  // byte
-	opDoneSave1             //
-	opOnePass               //
-	opBranchNext            // int16
-	opBranchJump            // int16
-	opLiteralSubstr         // []byte (to end)
-	opLiteralPrefix         // []byte (to end)
-	opLiteralSuffix         // []byte (to end)
-	opLiteralEqual          // []byte (to end)
-	opPrefix                // uint8 []byte
+	_                   opType = iota
+	opChar                     // char
+	opCharIgnoreCase           // char
+	opJump                     // int16
+	opSplitNext                // int16
+	opSplitJump                // int16
+	opAnyNotNL                 //
+	opHalfSet                  // [16]byte
+	opFullSet                  // [32]byte
+	opListSet                  // uint8 []byte
+	opWordStart                //
+	opWordEnd                  //
+	opLineStart                //
+	opLineEnd                  //
+	opStrStart                 //
+	opStrEnd                   //
+	opSave                     // byte
+	opDoneSave1                //
+	opOnePass                  //
+	opBranchNext               // int16
+	opBranchJump               // int16
+	opLiteralSubstr            // []byte (to end)
+	opLiteralPrefix            // []byte (to end)
+	opLiteralSuffix            // []byte (to end)
+	opLiteralEqual             // []byte (to end)
+	opPrefix                   // uint8 []byte
+	opBackref                  // byte
+	opBackrefIgnoreCase        // byte
+	opLookahead                // uint16 Pattern
+	opNegLookahead             // uint16 Pattern
+	opLookbehind               // uint16 Pattern
+	opNegLookbehind            // uint16 Pattern
+	opCaptures                 //
+	opNames                    // (see names.go)
 )
 
 func (pat Pattern) String() string {
@@ -50,6 +58,12 @@ func (pat Pattern) String() string {
 	for pi < len(pat) {
 		inc, s := pat.opstr(pi)
 		fmt.Fprintf(&sb, "%d: %s\n", pi, s)
+		if opType(pat[pi]) == opDoneSave1 {
+			if names := pat.Names(); names != nil {
+				fmt.Fprintf(&sb, "Names %q\n", names)
+			}
+			break
+		}
 		pi += inc
 	}
 	return sb.String()
@@ -70,6 +84,13 @@ func (pat Pattern) opstr(pi int) (int, string) {
 		return 1 + 32, opstr
 	case opSave:
 		return 2, fmt.Sprintf("Save %d", int(pat[pi+1]))
+	case opBackref, opBackrefIgnoreCase:
+		return 2, fmt.Sprint(opstr, " ", int(pat[pi+1]))
+	case opLookahead, opNegLookahead, opLookbehind, opNegLookbehind:
+		n := int(pat[pi+1])<<8 | int(pat[pi+2])
+		sub := strings.TrimSpace(Pattern(pat[pi+3 : pi+3+n]).String())
+		return 3 + n, fmt.Sprintf("%s {%s}", opstr,
+			strings.ReplaceAll(sub, "\n", "; "))
 	case opListSet, opPrefix:
 		n := int(pat[pi+1])
 		return n + 2, fmt.Sprintf("%s %q", opstr, string(pat[pi+2:pi+2+n]))
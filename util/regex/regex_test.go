@@ -114,6 +114,150 @@ func TestCapture(t *testing.T) {
 	test("-ccc-", `(aaa)|(bbb)|(ccc)`, "ccc", "", "", "ccc")
 }
 
+func TestRepeat(t *testing.T) {
+	match := func(str string, pat string, expected bool) {
+		t.Helper()
+		assert.T(t).Msg(str, pat).This(Compile(pat).Match(str, nil)).Is(expected)
+	}
+	match("aaa", `\Aa{3}\Z`, true)
+	match("aa", `\Aa{3}\Z`, false)
+	match("aaaa", `\Aa{3}\Z`, false)
+	match("aa", `\Aa{2,}\Z`, true)
+	match("aaaaa", `\Aa{2,}\Z`, true)
+	match("a", `\Aa{2,}\Z`, false)
+	match("", `\Aa{0,2}\Z`, true)
+	match("aa", `\Aa{0,2}\Z`, true)
+	match("aaa", `\Aa{0,2}\Z`, false)
+	match("abab", `\A(ab){2}\Z`, true)
+	match("123-4567", `\A\d{3}-\d{4}\Z`, true)
+	match("12-4567", `\A\d{3}-\d{4}\Z`, false)
+	match("x{y}", `x{y}`, true) // not a repeat
+	match("x{", `x{`, true)
+	match("x{1", `x{1`, true)
+	match("x{1,a}", `\Ax{1,a}\Z`, true)
+
+	assert.T(t).This(rxType(Compile(`a{3}`))).Is('L')
+	assert.T(t).This(rxType(Compile(`\A\d{3}\Z`))).Is('1')
+
+	test := func(s, pat, expected string) {
+		t.Helper()
+		var cap Captures
+		assert.T(t).True(Compile(pat).Match(s, &cap))
+		assert.T(t).This(s[cap[0]:cap[1]]).Is(expected)
+	}
+	test("aaaa", `a{1,3}`, "aaa")
+	test("aaaa", `a{1,3}?`, "a")
+	test("aaaa", `a{2,}?`, "aa")
+	test("aaaa", `a{2,}`, "aaaa")
+
+	assert.T(t).This(func() { Compile(`a{3,2}`) }).Panics("invalid repeat")
+	assert.T(t).This(func() { Compile(`a{1001}`) }).Panics("too large")
+}
+
+func TestBackref(t *testing.T) {
+	test := func(s, pat string, expected ...string) {
+		t.Helper()
+		var cap Captures
+		if len(expected) == 0 {
+			assert.T(t).Msg(s, pat).False(Compile(pat).Match(s, &cap))
+			assert.T(t).Msg(s, pat).False(Compile(pat).Match(s, nil))
+			return
+		}
+		assert.T(t).Msg(s, pat).True(Compile(pat).Match(s, nil))
+		assert.T(t).Msg(s, pat).True(Compile(pat).Match(s, &cap))
+		for i, e := range expected {
+			assert.T(t).This(s[cap[2*i]:cap[2*i+1]]).Is(e)
+		}
+	}
+	test("abcabc", `(abc)\1`, "abcabc", "abc")
+	test("abcab", `(abc)\1`)
+	test("hello hello world", `\<(\w+) \1\>`, "hello hello", "hello")
+	test("the cat sat", `\<(\w+) \1\>`)
+	test("aa bb", `(\w)\1`, "aa", "a")
+	test("xyx", `\A(x)(y)\1\Z`, "xyx", "x", "y")
+	test("'quoted'", `(["'])(.*)\1`, "'quoted'", "'", "quoted")
+	test(`"mixed'`, `(["'])(.*)\1`)
+	test("AbcaBC", `(?i)(abc)\1`, "AbcaBC", "Abc")
+	test("x", `(a)?x\1`) // unmatched group fails
+	test("x", `(a?)x\1`, "x", "")
+	test("<b>bold</b>", `<(?<tag>\w+)>.*</\k<tag>>`, "<b>bold</b>", "b")
+	// needs a lower priority alternative with different captures
+	test("abab", `^(a|ab)(b*)\1$`, "abab", "ab", "")
+	test("aaba", `^(a|aa)(b?)\1$`)
+	test("aabaa", `(a|aa)b\1`, "aabaa", "aa")
+	assert.T(t).True(Compile(`^(a|ab)(b*)\1$`).Matches("abab"))
+
+	assert.T(t).This(rxType(Compile(`\A(a)\1`))).Is('M')
+	assert.T(t).This(func() { Compile(`\1(a)`) }).Panics("invalid backreference")
+	assert.T(t).This(func() { Compile(`(a)\0`) }).Panics("invalid backreference")
+	assert.T(t).This(func() { Compile(`\k<x>`) }).Panics("invalid group name")
+}
+
+func TestNamedGroups(t *testing.T) {
+	pat := Compile(`(?<year>\d+)-(\d+)-(?P<day>\d+)`)
+	assert.T(t).This(pat.Names()).Is([]string{"", "year", "", "day"})
+	assert.T(t).This(pat.Group("year")).Is(1)
+	assert.T(t).This(pat.Group("day")).Is(3)
+	assert.T(t).This(pat.Group("month")).Is(-1)
+	var cap Captures
+	assert.T(t).True(pat.Match("on 2024-05-17", &cap))
+	assert.T(t).This(cap[2:8]).Is([]int32{3, 7, 8, 10, 11, 13})
+	assert.T(t).True(pat.Match("2024-05-17", nil))
+
+	assert.T(t).This(Compile(`(a)(b)`).Names()).Is([]string(nil))
+	assert.T(t).This(Compile(`ab`).Names()).Is([]string(nil))
+	assert.T(t).This(Compile(`\A(?<x>a)`).Group("x")).Is(1) // one pass
+	assert.T(t).This(Compile(`(?:a)(?<x>b)`).Group("x")).Is(1)
+
+	assert.T(t).This(func() { Compile(`(?<x>a)(?<x>b)`) }).
+		Panics("duplicate group name")
+	assert.T(t).This(func() { Compile(`(?<>a)`) }).Panics("invalid group name")
+	assert.T(t).This(func() { Compile(`(?<1x>a)`) }).Panics("invalid group name")
+	assert.T(t).This(func() {
+		Compile(`(1)(2)(3)(4)(5)(6)(7)(8)(9)(?<ten>a)`)
+	}).Panics("too many groups")
+}
+
+func TestLookaround(t *testing.T) {
+	test := func(s, pat string, expected any) {
+		t.Helper()
+		var cap Captures
+		if expected == false {
+			assert.T(t).Msg(s, pat).False(Compile(pat).Match(s, &cap))
+			assert.T(t).Msg(s, pat).False(Compile(pat).Match(s, nil))
+			return
+		}
+		assert.T(t).Msg(s, pat).True(Compile(pat).Match(s, nil))
+		assert.T(t).Msg(s, pat).True(Compile(pat).Match(s, &cap))
+		assert.T(t).Msg(s, pat).This(s[cap[0]:cap[1]]).Is(expected)
+	}
+	test("foobar", `foo(?=bar)`, "foo")
+	test("foobaz", `foo(?=bar)`, false)
+	test("foobaz", `foo(?!bar)`, "foo")
+	test("foobar", `foo(?!bar)`, false)
+	test("price: $42", `(?<=\$)\d+`, "42")
+	test("price: 42", `(?<=\$)\d+`, false)
+	test("price: 42", `(?<!\$)\d+`, "42")
+	test("$42", `(?<!\$)\d+`, "2")
+	test("abc", `(?<=a|ab)c`, "c")
+	test("xbc", `(?<=a|ab)c`, false)
+	test("Password1", `\A(?=.*\d)(?=.*[a-z])(?=.*[A-Z]).{8,}\Z`, "Password1")
+	test("password1", `\A(?=.*\d)(?=.*[a-z])(?=.*[A-Z]).{8,}\Z`, false)
+	test("Pass1", `\A(?=.*\d)(?=.*[a-z])(?=.*[A-Z]).{8,}\Z`, false)
+	test("FOOBAR", `(?i)foo(?=bar)`, "FOO")
+	test("ab", `a(?=b(?<=ab))`, "a")
+	test("a", `a(?=)`, "a")
+
+	assert.T(t).This(rxType(Compile(`\Aa(?=b)`))).Is('M')
+	assert.T(t).This(func() { Compile(`(a)(?=\1)`) }).
+		Panics("not supported in lookaround")
+
+	// groups inside lookarounds are numbered but do not capture
+	var cap Captures
+	assert.T(t).True(Compile(`(?=(a))a(b)`).Match("ab", &cap))
+	assert.T(t).This(cap[2:6]).Is([]int32{-1, -1, 1, 2})
+}
+
 func TestMatch(t *testing.T) {
 	var rt rune
 	match := func(str string, pat string, expected bool) {
@@ -407,6 +551,28 @@ func TestCompile(t *testing.T) {
 		10: SplitJump 8
 		13: Char c
 		15: DoneSave1`)
+	test("a{2,3}b",
+		`0: Prefix "aa"
+		4: Char a
+		6: Char a
+		8: SplitNext 13
+		11: Char a
+		13: Char b
+		15: DoneSave1`)
+	test(`(a)\1b`,
+		`0: Captures
+		1: Prefix "a"
+		4: Save 2
+		6: Char a
+		8: Save 3
+		10: Backref 1
+		12: Char b
+		14: DoneSave1`)
+	test("x(?=ab)",
+		`0: Prefix "x"
+		3: Char x
+		5: Lookahead {0: LiteralSubstr "ab"}
+		11: DoneSave1`)
 }
 
 func TestRightAnchor(t *testing.T) {
@@ -681,7 +847,7 @@ func TestReplace(t *testing.T) {
 		rx := Compile(pat)
 		var cap Captures
 		assert.T(t).True(rx.Match(s, &cap))
-		r := Replacement(s, rep, &cap)
+		r := rx.Replacement(s, rep, &cap)
 		got := s[:cap[0]] + r + s[cap[1]:]
 		assert.T(t).This(got).Is(expected)
 	}
@@ -697,6 +863,10 @@ func TestReplace(t *testing.T) {
 	test("NOW IS THE TIME", "THE", `\l&`, "NOW IS tHE TIME")
 	test("now is the time", `(\w+) (\w+)`, "\\2-\\1", "is-now the time")
 	test("now is the time", ` (\w+) (\w+) `, "\\2\\1", "nowtheistime")
+	test("now is the time", `(?<first>\w+) (?<second>\w+)`,
+		`\k<second>-\k<first>`, "is-now the time")
+	test("now is the time", `(?<first>\w+)`, `\U\k<first>`, "NOW is the time")
+	test("now is the time", `(\w+)`, `\k<first>`, "k<first> is the time")
 }
 
 func BenchmarkMatch(b *testing.B) {
@@ -21,8 +21,9 @@ func LiteralRep(rep string) (string, bool) {
 	return "", false
 }
 
-// Replacement makes a single replacement, handling case conversion, &, and \#
-func Replacement(s, rep string, cap *Captures) string {
+// Replacement makes a single replacement,
+// handling case conversion, &, \#, and \k<name>
+func (pat Pattern) Replacement(s, rep string, cap *Captures) string {
 	if r, ok := LiteralRep(rep); ok {
 		return r
 	}
@@ -61,6 +62,15 @@ func Replacement(s, rep string, cap *Captures) string {
 			switch rep[i] {
 			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 				tr = add(int(c - '0'))
+			case 'k':
+				if name, ok := repName(rep[i+1:]); ok {
+					if g := pat.Group(name); g != -1 {
+						tr = add(g)
+						i += len(name) + 2
+						break
+					}
+				}
+				buf.WriteByte(c)
 			case 'n':
 				buf.WriteByte('\n')
 			case 't':
@@ -80,3 +90,15 @@ func Replacement(s, rep string, cap *Captures) string {
 	}
 	return buf.String()
 }
+
+// repName returns the name from <name> at the start of s
+func repName(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '<' {
+		return "", false
+	}
+	i := strings.IndexByte(s, '>')
+	if i == -1 {
+		return "", false
+	}
+	return s[1:i], true
+}
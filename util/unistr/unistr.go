@@ -0,0 +1,251 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+// Package unistr has Unicode aware functions for UTF-8 strings.
+// The byte oriented (ascii) functions are in str.
+//
+// Characters are (an approximation of) Unicode extended grapheme clusters
+// i.e. what a user would see as a character,
+// including combining marks, emoji sequences, and flags.
+// Invalid UTF-8 bytes are treated as single characters and left unchanged.
+//
+// "\r\n" is a single character, like the other grapheme clusters.
+//
+// Each function has a fast path for pure ascii strings
+// (without "\r\n" where it matters).
+package unistr
+
+import (
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/apmckinlay/gsuneido/util/str"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// IsASCII returns whether the string contains only ascii (< 0x80) bytes
+func IsASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isSimple returns whether each byte of s is a character
+// i.e. it is ascii without "\r\n"
+func isSimple(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf ||
+			(s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n') {
+			return false
+		}
+	}
+	return true
+}
+
+// Next returns the length in bytes of the first character in s
+func Next(s string) int {
+	if len(s) == 0 {
+		return 0
+	}
+	if s[0] < utf8.RuneSelf && (len(s) == 1 || s[1] < utf8.RuneSelf) {
+		if s[0] == '\r' && len(s) > 1 && s[1] == '\n' {
+			return 2
+		}
+		return 1 // fast path for ascii
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	if r == '\r' || (r != utf8.RuneError && unicode.IsControl(r)) {
+		if r == '\r' && len(s) > 1 && s[1] == '\n' {
+			return 2
+		}
+		return 1
+	}
+	if isRegional(r) {
+		if r2, n2 := utf8.DecodeRuneInString(s[n:]); isRegional(r2) {
+			n += n2 // flag is a pair of regional indicators
+		}
+	}
+	for n < len(s) {
+		r2, n2 := utf8.DecodeRuneInString(s[n:])
+		if r2 == zwj {
+			n += n2
+			if r3, n3 := utf8.DecodeRuneInString(s[n:]); n3 > 0 &&
+				!unicode.IsControl(r3) && !unicode.IsSpace(r3) {
+				n += n3 // joined emoji sequence
+			}
+		} else if isExtend(r2) {
+			n += n2
+		} else {
+			break
+		}
+	}
+	return n
+}
+
+const zwj = 0x200d // zero width joiner
+
+func isRegional(r rune) bool {
+	return 0x1f1e6 <= r && r <= 0x1f1ff
+}
+
+// isExtend returns whether r combines with the previous character
+func isExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		(0x1f3fb <= r && r <= 0x1f3ff) || // emoji skin tone modifiers
+		(0xe0020 <= r && r <= 0xe007f) || // tags
+		(0x1160 <= r && r <= 0x11ff) || // Hangul jamo vowels and finals
+		(0xd7b0 <= r && r <= 0xd7ff)
+}
+
+// Size returns the number of characters in s
+func Size(s string) int {
+	if isSimple(s) {
+		return len(s)
+	}
+	n := 0
+	for i := 0; i < len(s); i += Next(s[i:]) {
+		n++
+	}
+	return n
+}
+
+// Substr returns up to n characters starting at character i.
+// Like str.Subn, i and n may exceed the string.
+func Substr(s string, i, n int) string {
+	if isSimple(s) {
+		if i >= len(s) || n <= 0 {
+			return ""
+		}
+		return s[i : i+min(n, len(s)-i)]
+	}
+	start := skip(s, 0, i)
+	end := skip(s, start, n)
+	return s[start:end]
+}
+
+// skip returns the byte position after n characters starting at i
+func skip(s string, i, n int) int {
+	for ; n > 0 && i < len(s); n-- {
+		i += Next(s[i:])
+	}
+	return i
+}
+
+// Reverse returns the characters in s in reverse order.
+// Combining marks etc. stay with their character.
+func Reverse(s string) string {
+	b := make([]byte, len(s))
+	j := len(s)
+	if isSimple(s) {
+		for i := 0; i < len(s); i++ {
+			j--
+			b[j] = s[i]
+		}
+		return string(b)
+	}
+	for i := 0; i < len(s); {
+		n := Next(s[i:])
+		j -= n
+		copy(b[j:], s[i:i+n])
+		i += n
+	}
+	return string(b)
+}
+
+// Upper returns s with Unicode upper case mapping e.g. ß => SS
+func Upper(s string) string {
+	if IsASCII(s) {
+		return str.ToUpper(s)
+	}
+	return cases.Upper(language.Und).String(s)
+}
+
+// Lower returns s with Unicode lower case mapping
+func Lower(s string) string {
+	if IsASCII(s) {
+		return str.ToLower(s)
+	}
+	return cases.Lower(language.Und).String(s)
+}
+
+// Normalize returns s in the Unicode normalization form
+// "NFC", "NFD", "NFKC", or "NFKD". It panics for other forms.
+func Normalize(s string, form string) string {
+	var f norm.Form
+	switch form {
+	case "NFC":
+		f = norm.NFC
+	case "NFD":
+		f = norm.NFD
+	case "NFKC":
+		f = norm.NFKC
+	case "NFKD":
+		f = norm.NFKD
+	default:
+		panic("invalid normalization form: " + form)
+	}
+	if IsASCII(s) {
+		return s // ascii is the same in all forms
+	}
+	return f.String(s)
+}
+
+//-------------------------------------------------------------------
+
+// collator is a collate.Collator with a lock
+// because a Collator is not safe for concurrent use
+type collator struct {
+	lock sync.Mutex
+	c    *collate.Collator
+	buf  collate.Buffer
+}
+
+var collators = map[string]*collator{}
+var collatorsLock sync.Mutex
+
+// getCollator returns the collator for a BCP 47 language tag e.g. "fr".
+// "" gives the default (root) Unicode collation.
+func getCollator(lang string) *collator {
+	collatorsLock.Lock()
+	defer collatorsLock.Unlock()
+	if c, ok := collators[lang]; ok {
+		return c
+	}
+	tag := language.Und
+	if lang != "" {
+		var err error
+		if tag, err = language.Parse(lang); err != nil {
+			panic("invalid language: " + lang)
+		}
+	}
+	c := &collator{c: collate.New(tag)}
+	collators[lang] = c
+	return c
+}
+
+// Compare compares two strings with Unicode collation for the language,
+// returning -1, 0, or +1 similar to strings.Compare
+func Compare(s, t string, lang string) int {
+	c := getCollator(lang)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.c.CompareString(s, t)
+}
+
+// SortKey returns a binary string such that comparing the keys as bytes
+// gives the same order as Compare
+func SortKey(s string, lang string) string {
+	c := getCollator(lang)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := string(c.c.KeyFromString(&c.buf, s))
+	c.buf.Reset()
+	return key
+}
@@ -0,0 +1,122 @@
+// Copyright Suneido Software Corp. All rights reserved.
+// Governed by the MIT license found in the LICENSE file.
+
+package unistr
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/apmckinlay/gsuneido/util/assert"
+)
+
+const (
+	eAcute   = "\u00e9"               // precomposed é
+	eCombine = "e\u0301"              // e + combining acute
+	flag     = "\U0001F1E8\U0001F1E6" // Canadian flag, regional indicators
+	family   = "\U0001F468\u200d\U0001F469\u200d\U0001F467"
+	thumb    = "\U0001F44D\U0001F3FD" // thumbs up with skin tone
+)
+
+func TestNext(t *testing.T) {
+	assert := assert.T(t).This
+	test := func(s string, expected string) {
+		t.Helper()
+		assert(s[:Next(s)]).Is(expected)
+	}
+	assert(Next("")).Is(0)
+	test("abc", "a")
+	test("\r\nx", "\r\n")
+	test("\n\u0301", "\n")
+	test(eAcute+"x", eAcute)
+	test(eCombine+"x", eCombine)
+	test(flag+flag, flag)
+	test(family+"x", family)
+	test(thumb+"x", thumb)
+	test("\xffabc", "\xff")
+	test("\u1100\u1161\u11a8x", "\u1100\u1161\u11a8") // Hangul jamo
+}
+
+func TestSize(t *testing.T) {
+	assert := assert.T(t).This
+	assert(Size("")).Is(0)
+	assert(Size("hello")).Is(5)
+	assert(Size("Jos" + eAcute)).Is(4)
+	assert(Size("Jos" + eCombine)).Is(4)
+	assert(Size(flag + family + thumb)).Is(3)
+	assert(Size("a\xffb")).Is(3)
+	// "\r\n" is one character with or without non-ascii
+	assert(Size("a\r\nb")).Is(3)
+	assert(Size(eAcute + "\r\nb")).Is(3)
+	assert(Size("a\n\rb")).Is(4)
+	assert(Size("a\r")).Is(2)
+}
+
+func TestSubstr(t *testing.T) {
+	assert := assert.T(t).This
+	assert(Substr("hello", 1, 3)).Is("ell")
+	assert(Substr("hello", 3, 10)).Is("lo")
+	assert(Substr("hello", 10, 1)).Is("")
+	assert(Substr("hello", 2, math.MaxInt)).Is("llo")
+	s := "Ren" + eCombine + " " + flag + "!"
+	assert(Substr(s, 3, 1)).Is(eCombine)
+	assert(Substr(s, 2, 2)).Is("n" + eCombine)
+	assert(Substr(s, 5, 5)).Is(flag + "!")
+	assert(Substr(s, 0, 0)).Is("")
+	assert(Substr(s, 7, 1)).Is("")
+	assert(Substr("a\r\nb", 1, 1)).Is("\r\n")
+	assert(Substr(eAcute+"\r\nb", 1, 1)).Is("\r\n")
+	assert(Substr("a\r\nb", 2, 1)).Is("b")
+	assert(Substr(eAcute+"\r\nb", 2, 1)).Is("b")
+}
+
+func TestReverse(t *testing.T) {
+	assert := assert.T(t).This
+	assert(Reverse("")).Is("")
+	assert(Reverse("abc")).Is("cba")
+	assert(Reverse("a" + eCombine + flag)).Is(flag + eCombine + "a")
+	assert(Reverse("x" + family)).Is(family + "x")
+	assert(Reverse("a\r\nb")).Is("b\r\na")
+	assert(Reverse(eAcute + "\r\nb")).Is("b\r\n" + eAcute)
+}
+
+func TestCase(t *testing.T) {
+	assert := assert.T(t).This
+	assert(Upper("hello")).Is("HELLO")
+	assert(Lower("HeLLo")).Is("hello")
+	assert(Upper("straße")).Is("STRASSE")
+	assert(Upper("jos" + eAcute)).Is("JOSÉ")
+	assert(Lower("ÉCOLE")).Is(eAcute + "cole")
+	assert(Lower("ΑΒΓ")).Is("αβγ")
+	assert(Upper("\xe9x")).Is("\xe9X") // invalid is unchanged
+}
+
+func TestNormalize(t *testing.T) {
+	assert := assert.T(t).This
+	assert(Normalize("abc", "NFD")).Is("abc")
+	assert(Normalize(eCombine, "NFC")).Is(eAcute)
+	assert(Normalize(eAcute, "NFD")).Is(eCombine)
+	assert(Normalize("ﬁ", "NFC")).Is("ﬁ") // fi ligature
+	assert(Normalize("ﬁ", "NFKC")).Is("fi")
+	assert(func() { Normalize("", "XYZ") }).
+		Panics("invalid normalization form: XYZ")
+}
+
+func TestCollate(t *testing.T) {
+	assert := assert.T(t).This
+	assert(Compare("a", "b", "")).Is(-1)
+	assert(Compare(eAcute, "f", "")).Is(-1) // bytes would be +1
+	assert(Compare(eAcute, eCombine, "")).Is(0)
+	assert(Compare("a", "B", "")).Is(-1)
+	list := []string{"Zoe", "Émile", "eve", "adam", "zed"}
+	slices.SortFunc(list, func(x, y string) int { return Compare(x, y, "") })
+	assert(list).Is([]string{"adam", "Émile", "eve", "zed", "Zoe"})
+	// in Swedish, å comes after z
+	assert(Compare("å", "z", "")).Is(-1)
+	assert(Compare("å", "z", "sv")).Is(+1)
+	for i := range len(list) - 1 {
+		assert(SortKey(list[i], "") < SortKey(list[i+1], "")).Is(true)
+	}
+	assert(func() { Compare("a", "b", "???") }).Panics("invalid language")
+}